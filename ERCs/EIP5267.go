@@ -0,0 +1,229 @@
+// Code generated - DO NOT EDIT.
+// This file is a generated binding and any manual changes will be lost.
+
+package ERCs
+
+import (
+	"math/big"
+	"strings"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var (
+	_ = big.NewInt
+	_ = strings.NewReader
+	_ = ethereum.NotFound
+	_ = abi.U256
+	_ = bind.Bind
+	_ = common.Big1
+	_ = types.BloomLookup
+	_ = event.NewSubscription
+)
+
+// EIP5267ABI is the input ABI used to generate the binding from.
+const EIP5267ABI = "[{\"constant\":true,\"inputs\":[],\"name\":\"eip712Domain\",\"outputs\":[{\"name\":\"fields\",\"type\":\"bytes1\"},{\"name\":\"name\",\"type\":\"string\"},{\"name\":\"version\",\"type\":\"string\"},{\"name\":\"chainId\",\"type\":\"uint256\"},{\"name\":\"verifyingContract\",\"type\":\"address\"},{\"name\":\"salt\",\"type\":\"bytes32\"},{\"name\":\"extensions\",\"type\":\"uint256[]\"}],\"payable\":false,\"stateMutability\":\"view\",\"type\":\"function\"}]"
+
+// EIP5267 is an auto generated Go binding around an Ethereum contract.
+type EIP5267 struct {
+	EIP5267Caller     // Read-only binding to the contract
+	EIP5267Transactor // Write-only binding to the contract
+	EIP5267Filterer   // Log filterer for contract events
+}
+
+// EIP5267Caller is an auto generated read-only Go binding around an Ethereum contract.
+type EIP5267Caller struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// EIP5267Transactor is an auto generated write-only Go binding around an Ethereum contract.
+type EIP5267Transactor struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// EIP5267Filterer is an auto generated log filtering Go binding around an Ethereum contract events.
+type EIP5267Filterer struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// EIP5267Session is an auto generated Go binding around an Ethereum contract,
+// with pre-set call and transact options.
+type EIP5267Session struct {
+	Contract     *EIP5267          // Generic contract binding to set the session for
+	CallOpts     bind.CallOpts     // Call options to use throughout this session
+	TransactOpts bind.TransactOpts // Transaction auth options to use throughout this session
+}
+
+// EIP5267CallerSession is an auto generated read-only Go binding around an Ethereum contract,
+// with pre-set call options.
+type EIP5267CallerSession struct {
+	Contract *EIP5267Caller // Generic contract caller binding to set the session for
+	CallOpts bind.CallOpts  // Call options to use throughout this session
+}
+
+// EIP5267TransactorSession is an auto generated write-only Go binding around an Ethereum contract,
+// with pre-set transact options.
+type EIP5267TransactorSession struct {
+	Contract     *EIP5267Transactor // Generic contract transactor binding to set the session for
+	TransactOpts bind.TransactOpts  // Transaction auth options to use throughout this session
+}
+
+// EIP5267Raw is an auto generated low-level Go binding around an Ethereum contract.
+type EIP5267Raw struct {
+	Contract *EIP5267 // Generic contract binding to access the raw methods on
+}
+
+// EIP5267CallerRaw is an auto generated low-level read-only Go binding around an Ethereum contract.
+type EIP5267CallerRaw struct {
+	Contract *EIP5267Caller // Generic read-only contract binding to access the raw methods on
+}
+
+// EIP5267TransactorRaw is an auto generated low-level write-only Go binding around an Ethereum contract.
+type EIP5267TransactorRaw struct {
+	Contract *EIP5267Transactor // Generic write-only contract binding to access the raw methods on
+}
+
+// NewEIP5267 creates a new instance of EIP5267, bound to a specific deployed contract.
+func NewEIP5267(address common.Address, backend bind.ContractBackend) (*EIP5267, error) {
+	contract, err := bindEIP5267(address, backend, backend, backend)
+	if err != nil {
+		return nil, err
+	}
+	return &EIP5267{EIP5267Caller: EIP5267Caller{contract: contract}, EIP5267Transactor: EIP5267Transactor{contract: contract}, EIP5267Filterer: EIP5267Filterer{contract: contract}}, nil
+}
+
+// NewEIP5267Caller creates a new read-only instance of EIP5267, bound to a specific deployed contract.
+func NewEIP5267Caller(address common.Address, caller bind.ContractCaller) (*EIP5267Caller, error) {
+	contract, err := bindEIP5267(address, caller, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &EIP5267Caller{contract: contract}, nil
+}
+
+// NewEIP5267Transactor creates a new write-only instance of EIP5267, bound to a specific deployed contract.
+func NewEIP5267Transactor(address common.Address, transactor bind.ContractTransactor) (*EIP5267Transactor, error) {
+	contract, err := bindEIP5267(address, nil, transactor, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &EIP5267Transactor{contract: contract}, nil
+}
+
+// NewEIP5267Filterer creates a new log filterer instance of EIP5267, bound to a specific deployed contract.
+func NewEIP5267Filterer(address common.Address, filterer bind.ContractFilterer) (*EIP5267Filterer, error) {
+	contract, err := bindEIP5267(address, nil, nil, filterer)
+	if err != nil {
+		return nil, err
+	}
+	return &EIP5267Filterer{contract: contract}, nil
+}
+
+// bindEIP5267 binds a generic wrapper to an already deployed contract.
+func bindEIP5267(address common.Address, caller bind.ContractCaller, transactor bind.ContractTransactor, filterer bind.ContractFilterer) (*bind.BoundContract, error) {
+	parsed, err := abi.JSON(strings.NewReader(EIP5267ABI))
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewBoundContract(address, parsed, caller, transactor, filterer), nil
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_EIP5267 *EIP5267Raw) Call(opts *bind.CallOpts, result interface{}, method string, params ...interface{}) error {
+	return _EIP5267.Contract.EIP5267Caller.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_EIP5267 *EIP5267Raw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _EIP5267.Contract.EIP5267Transactor.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_EIP5267 *EIP5267Raw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _EIP5267.Contract.EIP5267Transactor.contract.Transact(opts, method, params...)
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_EIP5267 *EIP5267CallerRaw) Call(opts *bind.CallOpts, result interface{}, method string, params ...interface{}) error {
+	return _EIP5267.Contract.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_EIP5267 *EIP5267TransactorRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _EIP5267.Contract.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_EIP5267 *EIP5267TransactorRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _EIP5267.Contract.contract.Transact(opts, method, params...)
+}
+
+// Eip712Domain is a free data retrieval call binding the contract method 0x84b0196e.
+//
+// Solidity: function eip712Domain() constant returns(bytes1 fields, string name, string version, uint256 chainId, address verifyingContract, bytes32 salt, uint256[] extensions)
+func (_EIP5267 *EIP5267Caller) Eip712Domain(opts *bind.CallOpts) (struct {
+	Fields            [1]byte
+	Name              string
+	Version           string
+	ChainId           *big.Int
+	VerifyingContract common.Address
+	Salt              [32]byte
+	Extensions        []*big.Int
+}, error) {
+	ret := new(struct {
+		Fields            [1]byte
+		Name              string
+		Version           string
+		ChainId           *big.Int
+		VerifyingContract common.Address
+		Salt              [32]byte
+		Extensions        []*big.Int
+	})
+	out := ret
+	err := _EIP5267.contract.Call(opts, out, "eip712Domain")
+	return *ret, err
+}
+
+// Eip712Domain is a free data retrieval call binding the contract method 0x84b0196e.
+//
+// Solidity: function eip712Domain() constant returns(bytes1 fields, string name, string version, uint256 chainId, address verifyingContract, bytes32 salt, uint256[] extensions)
+func (_EIP5267 *EIP5267Session) Eip712Domain() (struct {
+	Fields            [1]byte
+	Name              string
+	Version           string
+	ChainId           *big.Int
+	VerifyingContract common.Address
+	Salt              [32]byte
+	Extensions        []*big.Int
+}, error) {
+	return _EIP5267.Contract.Eip712Domain(&_EIP5267.CallOpts)
+}
+
+// Eip712Domain is a free data retrieval call binding the contract method 0x84b0196e.
+//
+// Solidity: function eip712Domain() constant returns(bytes1 fields, string name, string version, uint256 chainId, address verifyingContract, bytes32 salt, uint256[] extensions)
+func (_EIP5267 *EIP5267CallerSession) Eip712Domain() (struct {
+	Fields            [1]byte
+	Name              string
+	Version           string
+	ChainId           *big.Int
+	VerifyingContract common.Address
+	Salt              [32]byte
+	Extensions        []*big.Int
+}, error) {
+	return _EIP5267.Contract.Eip712Domain(&_EIP5267.CallOpts)
+}
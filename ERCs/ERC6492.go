@@ -0,0 +1,199 @@
+// Code generated - DO NOT EDIT.
+// This file is a generated binding and any manual changes will be lost.
+
+package ERCs
+
+import (
+	"math/big"
+	"strings"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var (
+	_ = big.NewInt
+	_ = strings.NewReader
+	_ = ethereum.NotFound
+	_ = abi.U256
+	_ = bind.Bind
+	_ = common.Big1
+	_ = types.BloomLookup
+	_ = event.NewSubscription
+)
+
+// ERC6492ABI is the input ABI used to generate the binding from.
+const ERC6492ABI = "[{\"constant\":true,\"inputs\":[{\"name\":\"_signer\",\"type\":\"address\"},{\"name\":\"_hash\",\"type\":\"bytes32\"},{\"name\":\"_signature\",\"type\":\"bytes\"}],\"name\":\"isValidSig\",\"outputs\":[{\"name\":\"\",\"type\":\"bool\"}],\"payable\":false,\"stateMutability\":\"view\",\"type\":\"function\"}]"
+
+// ERC6492 is an auto generated Go binding around an Ethereum contract.
+type ERC6492 struct {
+	ERC6492Caller     // Read-only binding to the contract
+	ERC6492Transactor // Write-only binding to the contract
+	ERC6492Filterer   // Log filterer for contract events
+}
+
+// ERC6492Caller is an auto generated read-only Go binding around an Ethereum contract.
+type ERC6492Caller struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// ERC6492Transactor is an auto generated write-only Go binding around an Ethereum contract.
+type ERC6492Transactor struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// ERC6492Filterer is an auto generated log filtering Go binding around an Ethereum contract events.
+type ERC6492Filterer struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// ERC6492Session is an auto generated Go binding around an Ethereum contract,
+// with pre-set call and transact options.
+type ERC6492Session struct {
+	Contract     *ERC6492          // Generic contract binding to set the session for
+	CallOpts     bind.CallOpts     // Call options to use throughout this session
+	TransactOpts bind.TransactOpts // Transaction auth options to use throughout this session
+}
+
+// ERC6492CallerSession is an auto generated read-only Go binding around an Ethereum contract,
+// with pre-set call options.
+type ERC6492CallerSession struct {
+	Contract *ERC6492Caller // Generic contract caller binding to set the session for
+	CallOpts bind.CallOpts  // Call options to use throughout this session
+}
+
+// ERC6492TransactorSession is an auto generated write-only Go binding around an Ethereum contract,
+// with pre-set transact options.
+type ERC6492TransactorSession struct {
+	Contract     *ERC6492Transactor // Generic contract transactor binding to set the session for
+	TransactOpts bind.TransactOpts  // Transaction auth options to use throughout this session
+}
+
+// ERC6492Raw is an auto generated low-level Go binding around an Ethereum contract.
+type ERC6492Raw struct {
+	Contract *ERC6492 // Generic contract binding to access the raw methods on
+}
+
+// ERC6492CallerRaw is an auto generated low-level read-only Go binding around an Ethereum contract.
+type ERC6492CallerRaw struct {
+	Contract *ERC6492Caller // Generic read-only contract binding to access the raw methods on
+}
+
+// ERC6492TransactorRaw is an auto generated low-level write-only Go binding around an Ethereum contract.
+type ERC6492TransactorRaw struct {
+	Contract *ERC6492Transactor // Generic write-only contract binding to access the raw methods on
+}
+
+// NewERC6492 creates a new instance of ERC6492, bound to a specific deployed contract.
+func NewERC6492(address common.Address, backend bind.ContractBackend) (*ERC6492, error) {
+	contract, err := bindERC6492(address, backend, backend, backend)
+	if err != nil {
+		return nil, err
+	}
+	return &ERC6492{ERC6492Caller: ERC6492Caller{contract: contract}, ERC6492Transactor: ERC6492Transactor{contract: contract}, ERC6492Filterer: ERC6492Filterer{contract: contract}}, nil
+}
+
+// NewERC6492Caller creates a new read-only instance of ERC6492, bound to a specific deployed contract.
+func NewERC6492Caller(address common.Address, caller bind.ContractCaller) (*ERC6492Caller, error) {
+	contract, err := bindERC6492(address, caller, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &ERC6492Caller{contract: contract}, nil
+}
+
+// NewERC6492Transactor creates a new write-only instance of ERC6492, bound to a specific deployed contract.
+func NewERC6492Transactor(address common.Address, transactor bind.ContractTransactor) (*ERC6492Transactor, error) {
+	contract, err := bindERC6492(address, nil, transactor, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &ERC6492Transactor{contract: contract}, nil
+}
+
+// NewERC6492Filterer creates a new log filterer instance of ERC6492, bound to a specific deployed contract.
+func NewERC6492Filterer(address common.Address, filterer bind.ContractFilterer) (*ERC6492Filterer, error) {
+	contract, err := bindERC6492(address, nil, nil, filterer)
+	if err != nil {
+		return nil, err
+	}
+	return &ERC6492Filterer{contract: contract}, nil
+}
+
+// bindERC6492 binds a generic wrapper to an already deployed contract.
+func bindERC6492(address common.Address, caller bind.ContractCaller, transactor bind.ContractTransactor, filterer bind.ContractFilterer) (*bind.BoundContract, error) {
+	parsed, err := abi.JSON(strings.NewReader(ERC6492ABI))
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewBoundContract(address, parsed, caller, transactor, filterer), nil
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_ERC6492 *ERC6492Raw) Call(opts *bind.CallOpts, result interface{}, method string, params ...interface{}) error {
+	return _ERC6492.Contract.ERC6492Caller.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_ERC6492 *ERC6492Raw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _ERC6492.Contract.ERC6492Transactor.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_ERC6492 *ERC6492Raw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _ERC6492.Contract.ERC6492Transactor.contract.Transact(opts, method, params...)
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_ERC6492 *ERC6492CallerRaw) Call(opts *bind.CallOpts, result interface{}, method string, params ...interface{}) error {
+	return _ERC6492.Contract.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_ERC6492 *ERC6492TransactorRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _ERC6492.Contract.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_ERC6492 *ERC6492TransactorRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _ERC6492.Contract.contract.Transact(opts, method, params...)
+}
+
+// IsValidSig is a free data retrieval call binding the contract method 0x98ef1ed8.
+//
+// Solidity: function isValidSig(address _signer, bytes32 _hash, bytes _signature) constant returns(bool)
+func (_ERC6492 *ERC6492Caller) IsValidSig(opts *bind.CallOpts, _signer common.Address, _hash [32]byte, _signature []byte) (bool, error) {
+	var (
+		ret0 = new(bool)
+	)
+	out := ret0
+	err := _ERC6492.contract.Call(opts, out, "isValidSig", _signer, _hash, _signature)
+	return *ret0, err
+}
+
+// IsValidSig is a free data retrieval call binding the contract method 0x98ef1ed8.
+//
+// Solidity: function isValidSig(address _signer, bytes32 _hash, bytes _signature) constant returns(bool)
+func (_ERC6492 *ERC6492Session) IsValidSig(_signer common.Address, _hash [32]byte, _signature []byte) (bool, error) {
+	return _ERC6492.Contract.IsValidSig(&_ERC6492.CallOpts, _signer, _hash, _signature)
+}
+
+// IsValidSig is a free data retrieval call binding the contract method 0x98ef1ed8.
+//
+// Solidity: function isValidSig(address _signer, bytes32 _hash, bytes _signature) constant returns(bool)
+func (_ERC6492 *ERC6492CallerSession) IsValidSig(_signer common.Address, _hash [32]byte, _signature []byte) (bool, error) {
+	return _ERC6492.Contract.IsValidSig(&_ERC6492.CallOpts, _signer, _hash, _signature)
+}
@@ -0,0 +1,102 @@
+package dappauth
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// EthService serves the eth namespace methods used by the authenticator from the mock (exported, as the rpc server
+// only registers exported services)
+type EthService struct {
+	mock *mockContract
+}
+
+// CallArgs are the arguments of eth_call (exported, as are the argument types of registered methods)
+type CallArgs struct {
+	From common.Address  `json:"from"`
+	To   *common.Address `json:"to"`
+	Data hexutil.Bytes   `json:"data"`
+}
+
+func (s *EthService) Call(ctx context.Context, args CallArgs, blockNumber string) (hexutil.Bytes, error) {
+	return s.mock.CallContract(ctx, ethereum.CallMsg{From: args.From, To: args.To, Data: args.Data}, nil)
+}
+
+func (s *EthService) GetCode(ctx context.Context, addr common.Address, blockNumber string) (hexutil.Bytes, error) {
+	return s.mock.CodeAt(ctx, addr, nil)
+}
+
+// dialWebsocket serves the mock over a WebSocket JSON-RPC endpoint, returning a client of it.
+func dialWebsocket(mock *mockContract, t *testing.T) (*ethclient.Client, func()) {
+	server := rpc.NewServer()
+	checkError(server.RegisterName("eth", &EthService{mock: mock}), t)
+
+	httpServer := httptest.NewServer(server.WebsocketHandler([]string{"*"}))
+	client, err := rpc.DialWebsocket(context.Background(), "ws"+strings.TrimPrefix(httpServer.URL, "http"), "")
+	checkError(err, t)
+
+	return ethclient.NewClient(client), func() {
+		client.Close()
+		httpServer.Close()
+		server.Stop()
+	}
+}
+
+func TestWebsocketBackend(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyC, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrC := ethCrypto.PubkeyToAddress(keyC.PublicKey)
+
+	// the wallet at A, owned by B, only implements the legacy interface
+	mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, code: []byte{1}, revertSelectors: []string{"1626ba7e"}}
+	backend, closeBackend := dialWebsocket(mock, t)
+	defer closeBackend()
+
+	// no network context, as with NewAuthenticator(nil, ...)
+	authenticator := NewAuthenticator(nil, backend)
+	sigB := signERC1654PersonalMessage("foo", keyB, addrA, t)
+
+	websocketTests := []struct {
+		title     string
+		signature string
+		address   common.Address
+		expected  bool
+	}{
+		{"Smart-contract wallets should be authorized signers over the websocket", sigB, addrA, true},
+		{"Smart-contract wallets should NOT be authorized signers for other keys over the websocket", signERC1654PersonalMessage("foo", keyC, addrA, t), addrA, false},
+		{"External wallets should be authorized signers regardless of the backend", signEOAPersonalMessage("foo", keyC, t), addrC, true},
+	}
+
+	for _, test := range websocketTests {
+		t.Run(test.title, func(t *testing.T) {
+			isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", test.signature, test.address.Hex())
+			checkError(err, t)
+			expectBool(isAuthorizedSigner, test.expected, t)
+		})
+	}
+
+	t.Run("Reverts should be detected over the websocket", func(t *testing.T) {
+		noWallet, closeNoWallet := dialWebsocket(&mockContract{revertSelectors: []string{"1626ba7e", "20c13b0b"}}, t)
+		defer closeNoWallet()
+
+		_, err := NewAuthenticator(nil, noWallet).IsAuthorizedSigner("foo", sigB, addrA.Hex())
+		expectBool(errors.Is(err, ErrNoContractCode), true, t)
+	})
+}
@@ -0,0 +1,157 @@
+package dappauth
+
+import (
+	"context"
+	"encoding/binary"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// BatchResult is the result of an entry of a batch verification.
+type BatchResult struct {
+	Result *Result
+	Err    error
+}
+
+// VerifyBatch verifies the entries of the batch concurrently (bounded by the contract parallelism), returning the
+// results in input order. Identical contract calls within the batch, e.g. entries sharing the same contract wallet,
+// digest and signature, are coalesced into a single call whose result is shared.
+func (a *Authenticator) VerifyBatch(entries []VerifyRequest) []BatchResult {
+
+	batch := *a
+	batch.callCoalescer = newCallCoalescer()
+
+	results := make([]BatchResult, len(entries))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, a.contractParallelism)
+	for i, entry := range entries {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, entry VerifyRequest) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i].Result, results[i].Err = batch.Verify(entry.Challenge, entry.Signature, entry.Address)
+		}(i, entry)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// AuthRequest is an entry of a stream verification. ID is opaque to the verification, for correlating results.
+type AuthRequest struct {
+	ID string
+	VerifyRequest
+}
+
+// AuthResult is the result of an entry of a stream verification.
+type AuthResult struct {
+	Request AuthRequest
+	Result  *Result
+	Err     error
+}
+
+// VerifyStream verifies the entries received from reqs concurrently (bounded by the contract parallelism), sending
+// each result as soon as its verification completes, i.e. not in input order. The returned channel is closed once reqs
+// is closed and drained and all results were sent, or once ctx is done, which also cancels verifications in flight
+// (whose results are then dropped).
+func (a *Authenticator) VerifyStream(ctx context.Context, reqs <-chan AuthRequest) <-chan AuthResult {
+
+	stream := *a
+	stream.ctx = ctx
+
+	results := make(chan AuthResult)
+	go func() {
+		var wg sync.WaitGroup
+		defer func() {
+			wg.Wait()
+			close(results)
+		}()
+
+		sem := make(chan struct{}, a.contractParallelism)
+		for ctx.Err() == nil {
+			var req AuthRequest
+			select {
+			case r, ok := <-reqs:
+				if !ok {
+					return
+				}
+				req = r
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			wg.Add(1)
+			go func(req AuthRequest) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+
+				result, err := stream.Verify(req.Challenge, req.Signature, req.Address)
+				select {
+				case results <- AuthResult{Request: req, Result: result, Err: err}:
+				case <-ctx.Done():
+				}
+			}(req)
+		}
+	}()
+	return results
+}
+
+type coalescedCall struct {
+	once    sync.Once
+	isValid bool
+	gasUsed uint64
+	err     error
+}
+
+// callCoalescer makes each distinct contract call once, sharing its result with identical calls.
+type callCoalescer struct {
+	mu    sync.Mutex
+	calls map[common.Hash]*coalescedCall
+}
+
+func newCallCoalescer() *callCoalescer {
+	return &callCoalescer{calls: make(map[common.Hash]*coalescedCall)}
+}
+
+func (c *callCoalescer) do(key common.Hash, call func() (bool, uint64, error)) (bool, uint64, error) {
+	c.mu.Lock()
+	coalesced, ok := c.calls[key]
+	if !ok {
+		coalesced = &coalescedCall{}
+		c.calls[key] = coalesced
+	}
+	c.mu.Unlock()
+
+	coalesced.once.Do(func() {
+		coalesced.isValid, coalesced.gasUsed, coalesced.err = call()
+	})
+	return coalesced.isValid, coalesced.gasUsed, coalesced.err
+}
+
+// callKey binds the coalesced call to everything determining its result.
+func callKey(addr common.Address, hash [32]byte, message, sig []byte, blockNumber *big.Int) common.Hash {
+	var messageLen [8]byte
+	binary.BigEndian.PutUint64(messageLen[:], uint64(len(message)))
+	// calls at different blocks don't share results (flag ‖ block number, the flag telling a pinned block from latest)
+	var block [33]byte
+	if blockNumber != nil {
+		block[0] = 1
+		copy(block[1:], common.LeftPadBytes(blockNumber.Bytes(), 32))
+	}
+	return ethCrypto.Keccak256Hash(addr.Bytes(), hash[:], block[:], messageLen[:], message, sig)
+}
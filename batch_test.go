@@ -0,0 +1,112 @@
+package dappauth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestVerifyBatch(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyC, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrC := ethCrypto.PubkeyToAddress(keyC.PublicKey)
+	contractSig := signERC1654PersonalMessage("foo", keyB, addrA, t)
+	eoaSig := signEOAPersonalMessage("foo", keyC, t)
+
+	// the shared wallet at A is owned by B
+	mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey}
+	authenticator := NewAuthenticator(nil, mock)
+
+	results := authenticator.VerifyBatch([]VerifyRequest{
+		{Challenge: "foo", Signature: contractSig, Address: addrA.Hex()},
+		{Challenge: "foo", Signature: eoaSig, Address: addrC.Hex()},
+		{Challenge: "foo", Signature: contractSig, Address: addrA.Hex()},
+		{Challenge: "foo", Signature: contractSig, Address: addrA.Hex()},
+	})
+
+	t.Run("Batch results should be in input order", func(t *testing.T) {
+		expectBool(len(results) == 4, true, t)
+		for i, result := range results {
+			checkError(result.Err, t)
+			expectBool(result.Result.Authorized, true, t)
+			expectBool(result.Result.Path == PathEOA, i == 1, t)
+		}
+	})
+
+	t.Run("Duplicate entries should make a single contract call", func(t *testing.T) {
+		expectBool(mock.calls == 1, true, t)
+	})
+
+	t.Run("Separate batches should NOT share contract calls", func(t *testing.T) {
+		authenticator.VerifyBatch([]VerifyRequest{{Challenge: "foo", Signature: contractSig, Address: addrA.Hex()}})
+		expectBool(mock.calls == 2, true, t)
+	})
+}
+
+func TestVerifyStream(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyC, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrC := ethCrypto.PubkeyToAddress(keyC.PublicKey)
+	contractSig := signERC1654PersonalMessage("foo", keyB, addrA, t)
+	eoaSig := signEOAPersonalMessage("foo", keyC, t)
+
+	// the wallet at A is owned by B
+	mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey}
+
+	t.Run("Stream results should be sent for every request until the input is drained", func(t *testing.T) {
+		reqs := make(chan AuthRequest, 4)
+		reqs <- AuthRequest{ID: "1", VerifyRequest: VerifyRequest{Challenge: "foo", Signature: contractSig, Address: addrA.Hex()}}
+		reqs <- AuthRequest{ID: "2", VerifyRequest: VerifyRequest{Challenge: "foo", Signature: eoaSig, Address: addrC.Hex()}}
+		reqs <- AuthRequest{ID: "3", VerifyRequest: VerifyRequest{Challenge: "bar", Signature: eoaSig, Address: addrC.Hex()}}
+		reqs <- AuthRequest{ID: "4", VerifyRequest: VerifyRequest{Challenge: "foo", Signature: "0x00", Address: addrC.Hex()}}
+		close(reqs)
+
+		authorized := map[string]bool{}
+		errored := map[string]bool{}
+		for result := range NewAuthenticator(nil, mock).VerifyStream(context.Background(), reqs) {
+			errored[result.Request.ID] = result.Err != nil
+			authorized[result.Request.ID] = result.Err == nil && result.Result.Authorized
+		}
+
+		expectBool(len(authorized) == 4, true, t)
+		expectBool(authorized["1"] && authorized["2"], true, t)
+		expectBool(authorized["3"] || authorized["4"], false, t)
+		expectBool(errored["4"], true, t)
+	})
+
+	t.Run("Cancelling the context should stop the stream", func(t *testing.T) {
+		// the input is never closed, nor drained before the cancellation as each call takes a second
+		reqs := make(chan AuthRequest, 2)
+		reqs <- AuthRequest{ID: "1", VerifyRequest: VerifyRequest{Challenge: "foo", Signature: contractSig, Address: addrA.Hex()}}
+		reqs <- AuthRequest{ID: "2", VerifyRequest: VerifyRequest{Challenge: "foo", Signature: contractSig, Address: addrA.Hex()}}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		authenticator := NewAuthenticator(nil, &slowBackend{mockContract: mock, delay: time.Second})
+		results := authenticator.VerifyStream(ctx, reqs)
+
+		time.Sleep(20 * time.Millisecond)
+		start := time.Now()
+		cancel()
+
+		for result := range results {
+			expectBool(result.Err != nil, true, t)
+		}
+		expectBool(time.Since(start) < 500*time.Millisecond, true, t)
+	})
+}
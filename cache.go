@@ -0,0 +1,371 @@
+package dappauth
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+const defaultResultCacheSize = 10000
+
+// ErrNoInterfaceCache is returned when warming the cache of an authenticator without interface cache.
+var ErrNoInterfaceCache = errors.New("dappauth: interface cache not enabled")
+
+// _warmSignature is the dummy signature the wallet interfaces are probed with, a valid signature (of the zero hash by
+// the private key 1) so that wallets which recover it return rather than revert.
+var _warmSignature = func() []byte {
+	key, err := ethCrypto.ToECDSA(common.LeftPadBytes([]byte{1}, 32))
+	if err != nil {
+		panic(err)
+	}
+	sig, err := ethCrypto.Sign(make([]byte, 32), key)
+	if err != nil {
+		panic(err)
+	}
+	sig[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	return sig
+}()
+
+// HeaderReader is implemented by backends able to read block headers (e.g. ethclient.Client).
+type HeaderReader interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// WithResultCache enables caching of verification results. Results of external wallets (EOA path) are cached
+// indefinitely, while results of smart-contract wallets, whose authorization can change, expire once the chain
+// advanced more than staleBlocks past the block they were cached at (0 = expire on the next block).
+// Caching smart-contract wallet results requires the backend to implement HeaderReader. Errors are never cached.
+func WithResultCache(staleBlocks uint64) Option {
+	return func(a *Authenticator) {
+		a.resultCache = newResultCache(staleBlocks, defaultResultCacheSize)
+	}
+}
+
+type resultCacheEntry struct {
+	addr        common.Address
+	result      Result
+	blockNumber uint64 // the latest block when the result was cached
+}
+
+type resultCache struct {
+	staleBlocks uint64
+	maxEntries  int
+
+	mu      sync.Mutex
+	entries map[common.Hash]resultCacheEntry
+}
+
+func newResultCache(staleBlocks uint64, maxEntries int) *resultCache {
+	return &resultCache{
+		staleBlocks: staleBlocks,
+		maxEntries:  maxEntries,
+		entries:     make(map[common.Hash]resultCacheEntry),
+	}
+}
+
+// resultCacheKey binds the cached result to everything determining it.
+func resultCacheKey(challenge string, sig []byte, addr common.Address) common.Hash {
+	var challengeLen [8]byte
+	binary.BigEndian.PutUint64(challengeLen[:], uint64(len(challenge)))
+	return ethCrypto.Keccak256Hash(challengeLen[:], []byte(challenge), addr.Bytes(), sig)
+}
+
+// get returns the cached result if fresh, calling latestBlock only for results which can expire.
+func (c *resultCache) get(key common.Hash, latestBlock func() (uint64, bool)) (*Result, bool) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	if entry.result.Path != PathEOA {
+		blockNumber, knownBlock := latestBlock()
+		if !knownBlock || blockNumber > entry.blockNumber+c.staleBlocks {
+			c.mu.Lock()
+			delete(c.entries, key)
+			c.mu.Unlock()
+			return nil, false
+		}
+	}
+
+	result := entry.result
+	return &result, true
+}
+
+func (c *resultCache) put(key common.Hash, addr common.Address, result *Result, blockNumber uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		// evict an arbitrary entry
+		for k := range c.entries {
+			delete(c.entries, k)
+			break
+		}
+	}
+
+	c.entries[key] = resultCacheEntry{addr: addr, result: *result, blockNumber: blockNumber}
+}
+
+// invalidate deletes the cached results of the address. A nil cache is a no-op.
+func (c *resultCache) invalidate(addr common.Address) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, entry := range c.entries {
+		if entry.addr == addr {
+			delete(c.entries, key)
+		}
+	}
+}
+
+func (c *resultCache) clear() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[common.Hash]resultCacheEntry)
+}
+
+// latestBlockNumber returns the number of the latest block, if the backend is able to read it.
+func (a *Authenticator) latestBlockNumber() (uint64, bool) {
+	headerReader, ok := a.cc.(HeaderReader)
+	if !ok {
+		return 0, false
+	}
+
+	header, err := headerReader.HeaderByNumber(a.context(), nil)
+	if err != nil || header == nil || header.Number == nil {
+		return 0, false
+	}
+	return header.Number.Uint64(), true
+}
+
+// callBlockNumber returns the latest block, if the backend is able to read it (nil otherwise, or when calls resolve
+// against the pending state), for the calls to be pinned to (see atBlock).
+func (a *Authenticator) callBlockNumber() *big.Int {
+	if a.pendingState {
+		return nil
+	}
+
+	blockNumber, ok := a.latestBlockNumber()
+	if !ok {
+		return nil
+	}
+	return new(big.Int).SetUint64(blockNumber)
+}
+
+// atBlock returns the authenticator with its contract calls resolving against the block, so that a result reflects the
+// state of the block it reports, even if a block lands meanwhile (nil = latest, i.e. the authenticator as is).
+func (a *Authenticator) atBlock(blockNumber *big.Int) *Authenticator {
+	if blockNumber == nil {
+		return a
+	}
+
+	pinned := *a
+	pinned.callBlock = blockNumber
+	return &pinned
+}
+
+// verifyCached serves the verification from the result cache if enabled and fresh, caching the result otherwise.
+func (a *Authenticator) verifyCached(challenge string, sig []byte, addr common.Address) (*Result, error) {
+	if a.resultCache == nil {
+		return a.verifyUncached(challenge, sig, addr)
+	}
+
+	key := resultCacheKey(challenge, sig, addr)
+	if result, ok := a.resultCache.get(key, a.latestBlockNumber); ok {
+		return result, nil
+	}
+
+	// read before verifying, so that the cached block never postdates the state the result reflects
+	blockNumber, knownBlock := a.latestBlockNumber()
+
+	result, err := a.verifyUncached(challenge, sig, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Path == PathEOA || knownBlock {
+		a.resultCache.put(key, addr, result, blockNumber)
+	}
+	return result, nil
+}
+
+// WithInterfaceCache caches the wallet interface each smart-contract wallet was found to implement for ttl, so that
+// subsequent verifications go straight to it rather than probing interfaces which revert.
+func WithInterfaceCache(ttl time.Duration) Option {
+	return func(a *Authenticator) {
+		a.interfaceCache = newInterfaceCache(ttl, defaultResultCacheSize)
+	}
+}
+
+type interfaceCacheEntry struct {
+	walletInterface WalletInterface
+	expiry          time.Time
+}
+
+type interfaceCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[common.Address]interfaceCacheEntry
+}
+
+func newInterfaceCache(ttl time.Duration, maxEntries int) *interfaceCache {
+	return &interfaceCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[common.Address]interfaceCacheEntry),
+	}
+}
+
+// get returns the cached interface of the wallet if fresh. A nil cache never hits.
+func (c *interfaceCache) get(addr common.Address, now time.Time) (WalletInterface, bool) {
+	if c == nil {
+		return 0, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[addr]
+	if !ok {
+		return 0, false
+	}
+	if now.After(entry.expiry) {
+		delete(c.entries, addr)
+		return 0, false
+	}
+	return entry.walletInterface, true
+}
+
+func (c *interfaceCache) put(addr common.Address, walletInterface WalletInterface, now time.Time) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[addr]; !ok && len(c.entries) >= c.maxEntries {
+		// evict an arbitrary entry
+		for k := range c.entries {
+			delete(c.entries, k)
+			break
+		}
+	}
+
+	c.entries[addr] = interfaceCacheEntry{walletInterface: walletInterface, expiry: now.Add(c.ttl)}
+}
+
+func (c *interfaceCache) delete(addr common.Address) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, addr)
+}
+
+func (c *interfaceCache) clear() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[common.Address]interfaceCacheEntry)
+}
+
+// Invalidate deletes the cached results and wallet interface of the address, e.g. once a change of the owners of the
+// smart-contract wallet was detected, so that its next verification reflects the chain.
+func (a *Authenticator) Invalidate(addrHex string) error {
+	addr, err := a.normalizeAddress(addrHex)
+	if err != nil {
+		return err
+	}
+
+	a.resultCache.invalidate(addr)
+	a.interfaceCache.delete(addr)
+	return nil
+}
+
+// InvalidateAll deletes all cached results and wallet interfaces.
+func (a *Authenticator) InvalidateAll() {
+	a.resultCache.clear()
+	a.interfaceCache.clear()
+}
+
+// WarmCache probes concurrently (bounded by the contract parallelism) the wallet interface of each smart-contract wallet
+// of the addresses, caching it so that subsequent verifications skip probing. Addresses without code (external
+// wallets) or which implement none of the interfaces are skipped. Requires WithInterfaceCache.
+// Returns the first error of the addresses, in the given order, once all were probed.
+func (a *Authenticator) WarmCache(ctx context.Context, addrs []string) error {
+	if a.interfaceCache == nil {
+		return ErrNoInterfaceCache
+	}
+	if a.cc == nil {
+		return ErrNoBackend
+	}
+
+	warm := *a
+	warm.ctx = ctx
+
+	errs := make([]error, len(addrs))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, a.contractParallelism)
+	for i, addrHex := range addrs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, addrHex string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			errs[i] = warm.warmInterface(addrHex)
+		}(i, addrHex)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *Authenticator) warmInterface(addrHex string) error {
+	addr, err := a.normalizeAddress(addrHex)
+	if err != nil {
+		return err
+	}
+
+	// the result doesn't matter, only which interface returned
+	_, _, err = a.probeIsValidSignature(addr, [32]byte{}, nil, _warmSignature)
+	if err != nil && !errors.Is(err, ErrNoContractCode) && err != ErrUnsupportedWalletInterface {
+		return wrapError("warming", addr, err)
+	}
+	return nil
+}
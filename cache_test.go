@@ -0,0 +1,215 @@
+package dappauth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestResultCache(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+
+	verify := func(authenticator *Authenticator, sig string) {
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	}
+
+	t.Run("Smart-contract wallet results should be cached within the staleness window", func(t *testing.T) {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, blockNumber: 100}
+		authenticator := NewAuthenticator(nil, mock, WithResultCache(2))
+		sig := signERC1654PersonalMessage("foo", keyB, addrA, t)
+
+		verify(authenticator, sig)
+		expectBool(mock.calls == 1, true, t)
+
+		mock.blockNumber = 102
+		verify(authenticator, sig)
+		expectBool(mock.calls == 1, true, t)
+	})
+
+	t.Run("Smart-contract wallet results should expire after the block advances past the staleness window", func(t *testing.T) {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, blockNumber: 100}
+		authenticator := NewAuthenticator(nil, mock, WithResultCache(2))
+		sig := signERC1654PersonalMessage("foo", keyB, addrA, t)
+
+		verify(authenticator, sig)
+		expectBool(mock.calls == 1, true, t)
+
+		mock.blockNumber = 103
+		verify(authenticator, sig)
+		expectBool(mock.calls == 2, true, t)
+	})
+
+	t.Run("External wallet results should be cached indefinitely", func(t *testing.T) {
+		mock := &mockContract{blockNumber: 100}
+		authenticator := NewAuthenticator(nil, mock, WithResultCache(0))
+		sig := signEOAPersonalMessage("foo", keyA, t)
+
+		verify(authenticator, sig)
+		mock.blockNumber = 1000
+		verify(authenticator, sig)
+
+		key := resultCacheKey("foo", common.FromHex(sig), addrA)
+		_, ok := authenticator.resultCache.get(key, func() (uint64, bool) { return 0, false })
+		expectBool(ok, true, t)
+	})
+
+	t.Run("Results of other challenges should NOT be served from the cache", func(t *testing.T) {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, blockNumber: 100}
+		authenticator := NewAuthenticator(nil, mock, WithResultCache(2))
+
+		verify(authenticator, signERC1654PersonalMessage("foo", keyB, addrA, t))
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("bar", signERC1654PersonalMessage("foo", keyB, addrA, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, false, t)
+		expectBool(mock.calls == 2, true, t)
+	})
+}
+
+func TestInterfaceCache(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	sig := signERC1654PersonalMessage("foo", keyB, addrA, t)
+
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	// the wallet implements only the legacy interface, so the first (default) interface is probed and reverts
+	mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, revertSelectors: []string{"1626ba7e"}}
+	authenticator := NewAuthenticator(nil, mock, WithInterfaceCache(time.Minute), WithClock(clock))
+
+	verify := func(expectedCalls int) {
+		mock.calls = 0
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+		expectBool(mock.calls == expectedCalls, true, t)
+	}
+
+	t.Run("The first verification should probe the interfaces", func(t *testing.T) {
+		verify(2)
+	})
+
+	t.Run("Subsequent verifications should use the cached interface", func(t *testing.T) {
+		verify(1)
+	})
+
+	t.Run("Verifications after the TTL should probe the interfaces again", func(t *testing.T) {
+		now = now.Add(2 * time.Minute)
+		verify(2)
+	})
+
+	t.Run("Verifications should probe the interfaces again if the cached interface reverts", func(t *testing.T) {
+		mock.revertSelectors = []string{"20c13b0b"}
+		verify(2)
+		verify(1)
+	})
+}
+
+func TestWarmCache(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyC, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrC := ethCrypto.PubkeyToAddress(keyC.PublicKey)
+
+	// the wallet at A only implements the legacy interface
+	mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, code: []byte{1}, revertSelectors: []string{"1626ba7e"}}
+	authenticator := NewAuthenticator(nil, mock, WithInterfaceCache(time.Hour))
+
+	t.Run("Warming should require the interface cache", func(t *testing.T) {
+		err := NewAuthenticator(nil, mock).WarmCache(context.Background(), []string{addrA.Hex()})
+		expectBool(err == ErrNoInterfaceCache, true, t)
+	})
+
+	t.Run("Warming should skip external wallets", func(t *testing.T) {
+		checkError(authenticator.WarmCache(context.Background(), []string{addrA.Hex(), addrC.Hex()}), t)
+	})
+
+	t.Run("Warmed wallets should be verified without probing", func(t *testing.T) {
+		mock.calls = 0
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", signERC1654PersonalMessage("foo", keyB, addrA, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+		expectBool(mock.calls == 1, true, t)
+	})
+}
+
+func TestInvalidate(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyC, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrC := ethCrypto.PubkeyToAddress(keyC.PublicKey)
+	sig := signERC1654PersonalMessage("foo", keyB, addrA, t)
+
+	invalidateTests := []struct {
+		title      string
+		invalidate func(a *Authenticator)
+		expected   bool
+	}{
+		{"Cached results should be served until invalidated", func(a *Authenticator) {}, true},
+		{"Results should NOT be served from the cache after Invalidate", func(a *Authenticator) { checkError(a.Invalidate(addrA.Hex()), t) }, false},
+		{"Results should NOT be served from the cache after InvalidateAll", func(a *Authenticator) { a.InvalidateAll() }, false},
+		{"Results should be served from the cache after invalidating another address", func(a *Authenticator) { checkError(a.Invalidate(addrC.Hex()), t) }, true},
+	}
+
+	for _, test := range invalidateTests {
+		t.Run(test.title, func(t *testing.T) {
+			mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, blockNumber: 100}
+			authenticator := NewAuthenticator(nil, mock, WithResultCache(10), WithInterfaceCache(time.Hour))
+
+			isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+			checkError(err, t)
+			expectBool(isAuthorizedSigner, true, t)
+
+			// the owner of the wallet changes from B to C
+			mock.authorizedKey = &keyC.PublicKey
+			test.invalidate(authenticator)
+
+			isAuthorizedSigner, err = authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+			checkError(err, t)
+			expectBool(isAuthorizedSigner, test.expected, t)
+
+			_, cachedInterface := authenticator.interfaceCache.get(addrA, time.Now())
+			expectBool(cachedInterface, true, t)
+		})
+	}
+
+	t.Run("Invalidating an invalid address should error", func(t *testing.T) {
+		err := NewAuthenticator(nil, &mockContract{}, WithResultCache(10), WithStrictAddresses(true)).Invalidate("foo")
+		expectBool(err != nil, true, t)
+	})
+
+	t.Run("Invalidating without caches should be a no-op", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{})
+		checkError(authenticator.Invalidate(addrA.Hex()), t)
+		authenticator.InvalidateAll()
+	})
+}
@@ -0,0 +1,122 @@
+package dappauth
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+)
+
+var (
+	// ErrNoChainRegistry is returned when verifying on a chain without a chain registry.
+	ErrNoChainRegistry = errors.New("dappauth: no chain registry")
+	// ErrUnknownChain is returned when verifying on a chain which isn't registered in the chain registry.
+	ErrUnknownChain = errors.New("dappauth: chain not registered")
+)
+
+// ChainRegistry maps chain IDs to the contract backends of the chains. It is safe for concurrent use.
+type ChainRegistry struct {
+	mu       sync.RWMutex
+	backends map[string]bind.ContractCaller
+}
+
+// NewChainRegistry creates a new, empty ChainRegistry.
+func NewChainRegistry() *ChainRegistry {
+	return &ChainRegistry{backends: make(map[string]bind.ContractCaller)}
+}
+
+// Register sets the contract backend of the chain.
+func (r *ChainRegistry) Register(chainID *big.Int, backend bind.ContractCaller) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.backends[chainID.String()] = backend
+}
+
+// Backend returns the contract backend of the chain, if registered.
+func (r *ChainRegistry) Backend(chainID *big.Int) (bind.ContractCaller, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	backend, ok := r.backends[chainID.String()]
+	return backend, ok
+}
+
+// WithChainRegistry sets the registry of the backends of the chains verified on by IsAuthorizedSignerOnChain.
+func WithChainRegistry(registry *ChainRegistry) Option {
+	return func(a *Authenticator) {
+		a.chainRegistry = registry
+	}
+}
+
+// IsAuthorizedSignerOnChain is like IsAuthorizedSigner but verifies against the wallet on the chain, making contract
+// calls via the chain's backend in the chain registry. Signatures with an EIP-155 V (chainID * 2 + 35/36) are accepted
+// for the chain only (as far as it fits the V byte, i.e. for chain IDs up to 110). Results are not cached.
+func (a *Authenticator) IsAuthorizedSignerOnChain(chainID *big.Int, challenge, signature, addrHex string) (bool, error) {
+	if a.chainRegistry == nil {
+		return false, ErrNoChainRegistry
+	}
+	if chainID == nil {
+		return false, ErrUnknownChain
+	}
+
+	backend, ok := a.chainRegistry.Backend(chainID)
+	if !ok {
+		return false, ErrUnknownChain
+	}
+
+	addr, err := a.normalizeAddress(addrHex)
+	if err != nil {
+		return false, err
+	}
+
+	sigBytes, err := a.signatureBytes(signature)
+	if err != nil {
+		return false, err
+	}
+
+	onChain := *a
+	onChain.cc = backend
+	onChain.resultCache = nil
+	onChain.interfaceCache = nil
+
+	return onChain.isAuthorizedSigner(challenge, eip155SignatureToLegacy(sigBytes, chainID), addr)
+}
+
+// ExtractChainID returns the chain ID a 65 bytes signature was produced for if its V is in EIP-155 form
+// (chainID * 2 + 35/36), or nil if its V is plain (27/28, or 0/1). As V is a single byte, only chain IDs up to 110 can
+// be encoded. Fails with ErrInvalidSignatureLength or ErrInvalidRecoveryID for other signatures.
+func ExtractChainID(sig []byte) (*big.Int, error) {
+	if len(sig) != 65 {
+		return nil, ErrInvalidSignatureLength
+	}
+
+	switch v := sig[64]; {
+	case v == 0 || v == 1 || v == 27 || v == 28:
+		return nil, nil
+	case v >= 35:
+		return big.NewInt(int64(v-35) / 2), nil
+	default:
+		return nil, ErrInvalidRecoveryID
+	}
+}
+
+// eip155SignatureToLegacy converts the V of a 65 bytes signature from EIP-155 form for the chain to 27/28.
+// Signatures in any other form are returned as is.
+func eip155SignatureToLegacy(sig []byte, chainID *big.Int) []byte {
+	if len(sig) != 65 {
+		return sig
+	}
+
+	// v = chainID * 2 + 35 + parity
+	parity := new(big.Int).Sub(new(big.Int).SetUint64(uint64(sig[64])), new(big.Int).Add(new(big.Int).Lsh(chainID, 1), big.NewInt(35)))
+	if parity.Sign() < 0 || parity.Cmp(big.NewInt(1)) > 0 {
+		return sig
+	}
+
+	legacy := make([]byte, 65)
+	copy(legacy, sig)
+	legacy[64] = 27 + byte(parity.Uint64())
+	return legacy
+}
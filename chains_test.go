@@ -0,0 +1,123 @@
+package dappauth
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestIsAuthorizedSignerOnChain(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyC, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	mainnet, goerli := big.NewInt(1), big.NewInt(5)
+
+	// the wallet at A is owned by B on mainnet, and by C on goerli
+	registry := NewChainRegistry()
+	registry.Register(mainnet, &mockContract{address: addrA, authorizedKey: &keyB.PublicKey})
+	registry.Register(goerli, &mockContract{address: addrA, authorizedKey: &keyC.PublicKey})
+	authenticator := NewAuthenticator(nil, nil, WithChainRegistry(registry))
+
+	sigB := signERC1654PersonalMessage("foo", keyB, addrA, t)
+	sigC := signERC1654PersonalMessage("foo", keyC, addrA, t)
+
+	chainTests := []struct {
+		title     string
+		chainID   *big.Int
+		signature string
+		expected  bool
+	}{
+		{"Smart-contract wallets should be authorized signers on the chain they are owned on (mainnet)", mainnet, sigB, true},
+		{"Smart-contract wallets should be authorized signers on the chain they are owned on (goerli)", goerli, sigC, true},
+		{"Smart-contract wallets should NOT be authorized signers on another chain (mainnet)", mainnet, sigC, false},
+		{"Smart-contract wallets should NOT be authorized signers on another chain (goerli)", goerli, sigB, false},
+	}
+
+	for _, test := range chainTests {
+		t.Run(test.title, func(t *testing.T) {
+			isAuthorizedSigner, err := authenticator.IsAuthorizedSignerOnChain(test.chainID, "foo", test.signature, addrA.Hex())
+			checkError(err, t)
+			expectBool(isAuthorizedSigner, test.expected, t)
+		})
+	}
+
+	// the EOA signature with an EIP-155 V for mainnet (37/38)
+	eip155Sig, err := hex.DecodeString(signEOAPersonalMessage("foo", keyA, t))
+	checkError(err, t)
+	eip155Sig[64] += 10
+
+	t.Run("External wallets should be authorized signers with the EIP-155 V of the chain", func(t *testing.T) {
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSignerOnChain(mainnet, "foo", hex.EncodeToString(eip155Sig), addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	t.Run("External wallets should NOT be authorized signers with the EIP-155 V of another chain", func(t *testing.T) {
+		// falls through to the wallet, which may error on the unexpected V
+		isAuthorizedSigner, _ := authenticator.IsAuthorizedSignerOnChain(goerli, "foo", hex.EncodeToString(eip155Sig), addrA.Hex())
+		expectBool(isAuthorizedSigner, false, t)
+	})
+
+	t.Run("Unregistered chains should error", func(t *testing.T) {
+		_, err := authenticator.IsAuthorizedSignerOnChain(big.NewInt(10), "foo", sigB, addrA.Hex())
+		expectBool(err == ErrUnknownChain, true, t)
+	})
+}
+
+func TestExtractChainID(t *testing.T) {
+
+	key, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	sig, err := hex.DecodeString(signEOAPersonalMessage("foo", key, t))
+	checkError(err, t)
+	parity := sig[64] - 27
+
+	withV := func(v byte) []byte {
+		adjusted := append([]byte{}, sig...)
+		adjusted[64] = v
+		return adjusted
+	}
+
+	chainIDTests := []struct {
+		title    string
+		sig      []byte
+		expected *big.Int
+	}{
+		{"Plain 27/28 signatures should NOT have a chain ID", sig, nil},
+		{"Plain 0/1 signatures should NOT have a chain ID", withV(parity), nil},
+		{"EIP-155 signatures for mainnet should have chain ID 1", withV(1*2 + 35 + parity), big.NewInt(1)},
+		{"EIP-155 signatures for goerli should have chain ID 5", withV(5*2 + 35 + parity), big.NewInt(5)},
+		{"EIP-155 signatures for chain 0 should have chain ID 0", withV(35 + parity), big.NewInt(0)},
+		{"EIP-155 signatures for the largest chain fitting V should have chain ID 110", withV(110*2 + 35), big.NewInt(110)},
+	}
+
+	for _, test := range chainIDTests {
+		t.Run(test.title, func(t *testing.T) {
+			chainID, err := ExtractChainID(test.sig)
+			checkError(err, t)
+			expectBool(chainID == nil, test.expected == nil, t)
+			if chainID != nil && test.expected != nil {
+				expectBool(chainID.Cmp(test.expected) == 0, true, t)
+			}
+		})
+	}
+
+	t.Run("Signatures with an invalid V should error", func(t *testing.T) {
+		_, err := ExtractChainID(withV(30))
+		expectBool(err == ErrInvalidRecoveryID, true, t)
+	})
+
+	t.Run("Signatures which are not 65 bytes should error", func(t *testing.T) {
+		_, err := ExtractChainID(sig[:64])
+		expectBool(err == ErrInvalidSignatureLength, true, t)
+	})
+}
@@ -0,0 +1,333 @@
+package dappauth
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	// ErrInvalidChallengeDigest is returned when a pre-hashed challenge is not a hex encoded 32 bytes digest.
+	ErrInvalidChallengeDigest = errors.New("dappauth: pre-hashed challenge is not a 32 bytes digest")
+	// ErrInvalidChallengeEncoding is returned when the challenge can't be decoded per the configured ChallengeEncoding.
+	ErrInvalidChallengeEncoding = errors.New("dappauth: challenge is not validly encoded")
+	// ErrChallengeExpired is returned when the expiry extracted from the challenge has passed.
+	ErrChallengeExpired = errors.New("dappauth: challenge expired")
+	// ErrChallengeNotYetValid is returned when the not-before time extracted from the challenge hasn't come yet.
+	ErrChallengeNotYetValid = errors.New("dappauth: challenge not yet valid")
+	// ErrAddressNotBound is returned when the challenge isn't bound to the claimed address (see WithAddressBinding).
+	ErrAddressNotBound = errors.New("dappauth: challenge is not bound to the address")
+)
+
+// ChallengeExpiryExtractor extracts the expiry from the challenge message, e.g. an expiry field of a JSON challenge.
+type ChallengeExpiryExtractor func(message []byte) (time.Time, error)
+
+// WithChallengeExpiryExtractor enforces the expiry the extractor extracts from the challenge message (the exact bytes
+// signed), failing verifications with ErrChallengeExpired once it has passed.
+func WithChallengeExpiryExtractor(extractor ChallengeExpiryExtractor) Option {
+	return func(a *Authenticator) {
+		a.challengeExpiryExtractor = extractor
+	}
+}
+
+// ChallengeNotBeforeExtractor extracts the time from which the challenge is valid from the challenge message.
+type ChallengeNotBeforeExtractor func(message []byte) (time.Time, error)
+
+// WithChallengeNotBeforeExtractor enforces the not-before time the extractor extracts from the challenge message,
+// failing verifications with ErrChallengeNotYetValid until it has come.
+func WithChallengeNotBeforeExtractor(extractor ChallengeNotBeforeExtractor) Option {
+	return func(a *Authenticator) {
+		a.challengeNotBeforeExtractor = extractor
+	}
+}
+
+// ChallengeEncoding defines how the challenge argument encodes the message which was signed.
+type ChallengeEncoding int
+
+const (
+	// ChallengeEncodingRaw takes the challenge as is, i.e. the bytes of the string were signed.
+	ChallengeEncodingRaw ChallengeEncoding = iota
+	// ChallengeEncodingBase64 decodes the challenge from base64, either standard or URL-safe, with or without padding.
+	// Pre-hashed challenges are then the base64 of the 32 bytes digest.
+	ChallengeEncodingBase64
+	// ChallengeEncodingHex takes the challenge as the hex encoding of a binary challenge, which the wallet was shown and
+	// signed as is. The signed message is thus the hex string (as is, including any 0x prefix), so the length in the
+	// personal message prefix is that of the hex string rather than of the binary challenge.
+	ChallengeEncodingHex
+)
+
+// WithChallengeEncoding sets how the challenge argument is decoded to the signed message (default = ChallengeEncodingRaw).
+func WithChallengeEncoding(encoding ChallengeEncoding) Option {
+	return func(a *Authenticator) {
+		a.challengeEncoding = encoding
+	}
+}
+
+// PersonalMessageBytesMode defines how wallets sign binary challenges, which some show and sign as is while others
+// hex-encode them first.
+type PersonalMessageBytesMode int
+
+const (
+	// PersonalMessageRawBytes signs the challenge bytes as is, so the length in the personal message prefix is that of
+	// the binary challenge (default).
+	PersonalMessageRawBytes PersonalMessageBytesMode = iota
+	// PersonalMessageHexString signs the 0x prefixed lowercase hex string of the challenge bytes, so the length in the
+	// personal message prefix is that of the hex string, i.e. 2+2n for a challenge of n bytes.
+	PersonalMessageHexString
+)
+
+// WithPersonalMessageBytesMode sets how the wallets sign the challenge bytes (default = PersonalMessageRawBytes), to
+// match the wallet's behavior for binary challenges (e.g. ChallengeEncodingBase64). It has no effect on
+// ChallengeEncodingHex, whose hex string is always signed as is, nor on pre-hashed challenges.
+func WithPersonalMessageBytesMode(mode PersonalMessageBytesMode) Option {
+	return func(a *Authenticator) {
+		a.personalMessageBytesMode = mode
+	}
+}
+
+// WithChallengePreHashed indicates the challenge argument is the hex encoded keccak256 digest of the actual message,
+// and that the client personal_signed the 32 bytes of that digest (rather than the message itself).
+// The personal message prefix is still applied over the digest, so this is NOT the same as a raw digest
+// signed without any prefix. Smart-contract wallets receive the digest as the challenge hash.
+func WithChallengePreHashed(preHashed bool) Option {
+	return func(a *Authenticator) {
+		a.challengePreHashed = preHashed
+	}
+}
+
+// WithLegacyMetaMask enables a best-effort compatibility shim for signatures of legacy MetaMask builds,
+// which signed the hex string of the challenge rather than the challenge itself. The legacy interpretations are
+// only tried for external wallets, after the standard one failed.
+func WithLegacyMetaMask(legacy bool) Option {
+	return func(a *Authenticator) {
+		a.legacyMetaMask = legacy
+	}
+}
+
+// WithDomainBinding binds signatures to the domain of the verifier, to prevent them from being replayed on other dapps.
+// The signed message is then the lowercased domain, a newline and the challenge message, i.e. for the domain
+// example.com and the challenge "foo" the wallet signs "example.com\nfoo". Challenges must be built with the same domain.
+// Pre-hashed challenges are not bound, their digest is expected to commit to the domain itself.
+func WithDomainBinding(domain string) Option {
+	return func(a *Authenticator) {
+		a.domainBinding = strings.ToLower(domain)
+	}
+}
+
+// AddressBindingPrefix starts the line of the challenge message carrying the address it is bound to (see WithAddressBinding).
+const AddressBindingPrefix = "Address: "
+
+// WithAddressBinding requires the challenge to be bound to the claimed address, so that a signature can't be reused to
+// claim another identity, failing verifications with ErrAddressNotBound otherwise. The challenge message must have
+// exactly one line made of AddressBindingPrefix and the EIP-55 checksummed address, e.g.
+// "Sign in to example.com\nAddress: 0xAbC…\nNonce: 42", its line ending being "\n" or "\r\n". Pre-hashed
+// challenges can't be bound.
+func WithAddressBinding(bound bool) Option {
+	return func(a *Authenticator) {
+		a.addressBinding = bound
+	}
+}
+
+// checkAddressBinding enforces that the challenge contains the address, if configured.
+func (a *Authenticator) checkAddressBinding(challenge string, addr common.Address) error {
+	if !a.addressBinding {
+		return nil
+	}
+	if a.challengePreHashed {
+		return ErrAddressNotBound
+	}
+
+	msg, err := a.decodeChallenge(challenge)
+	if err != nil {
+		return err
+	}
+
+	// the one address line, so that a challenge can't be bound to several addresses
+	var bound string
+	for _, line := range strings.Split(string(msg), "\n") {
+		if strings.HasPrefix(line, AddressBindingPrefix) {
+			if bound != "" {
+				return ErrAddressNotBound
+			}
+			bound = strings.TrimSuffix(strings.TrimPrefix(line, AddressBindingPrefix), "\r")
+		}
+	}
+	if bound != addr.Hex() {
+		return ErrAddressNotBound
+	}
+	return nil
+}
+
+// challengeMessage returns the message the EOA signed via personal_sign for the challenge.
+func (a *Authenticator) challengeMessage(challenge string) ([]byte, error) {
+	if a.challengePreHashed {
+		return a.challengeDigest(challenge)
+	}
+
+	msg, err := a.signedChallengeBytes(challenge)
+	if err != nil {
+		return nil, err
+	}
+	return a.bindDomain(msg), nil
+}
+
+// signedChallengeBytes returns the challenge bytes in the form the wallet signed them, per the personal message bytes mode.
+func (a *Authenticator) signedChallengeBytes(challenge string) ([]byte, error) {
+	msg, err := a.decodeChallenge(challenge)
+	if err != nil || a.personalMessageBytesMode != PersonalMessageHexString || a.challengeEncoding == ChallengeEncodingHex {
+		return msg, err
+	}
+	return []byte("0x" + hex.EncodeToString(msg)), nil
+}
+
+// bindDomain prepends the domain the signature is bound to, if any, to the challenge message.
+func (a *Authenticator) bindDomain(msg []byte) []byte {
+	if a.domainBinding == "" {
+		return msg
+	}
+	bound := make([]byte, 0, len(a.domainBinding)+1+len(msg))
+	bound = append(append(bound, a.domainBinding...), '\n')
+	return append(bound, msg...)
+}
+
+// decodeChallenge returns the bytes the challenge argument encodes per the configured ChallengeEncoding.
+func (a *Authenticator) decodeChallenge(challenge string) ([]byte, error) {
+	switch a.challengeEncoding {
+	case ChallengeEncodingBase64:
+		for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+			if decoded, err := encoding.DecodeString(challenge); err == nil {
+				return decoded, nil
+			}
+		}
+		return nil, ErrInvalidChallengeEncoding
+	case ChallengeEncodingHex:
+		// validated, but not decoded as the hex string is what was signed
+		if _, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(challenge, "0x"), "0X")); err != nil {
+			return nil, ErrInvalidChallengeEncoding
+		}
+		return []byte(challenge), nil
+	default:
+		return []byte(challenge), nil
+	}
+}
+
+// personalChallengeHash returns the hash signed by an EOA over the challenge via personal_sign.
+func (a *Authenticator) personalChallengeHash(challenge string) ([]byte, error) {
+	msg, err := a.challengeMessage(challenge)
+	if err != nil {
+		return nil, err
+	}
+	return a.signedMessageHash(msg), nil
+}
+
+// personalChallengeHashes returns the hashes an EOA may have signed over the challenge via personal_sign:
+// the standard one, followed by the legacy MetaMask ones if enabled.
+func (a *Authenticator) personalChallengeHashes(challenge string) ([][]byte, error) {
+	msg, err := a.challengeMessage(challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := [][]byte{a.signedMessageHash(msg)}
+	if a.legacyMetaMask {
+		// legacy builds signed the hex string of the message, with or without its 0x prefix
+		hexMsg := hex.EncodeToString(msg)
+		hashes = append(hashes, a.signedMessageHash([]byte("0x"+hexMsg)), a.signedMessageHash([]byte(hexMsg)))
+	}
+	return hashes, nil
+}
+
+// contractChallengeHash returns the regular hash of the challenge, which smart-contract wallets hash ontop to an erc191 hash.
+func (a *Authenticator) contractChallengeHash(challenge string) ([32]byte, error) {
+	var challengeHash [32]byte
+
+	if a.challengePreHashed {
+		digest, err := a.challengeDigest(challenge)
+		if err != nil {
+			return challengeHash, err
+		}
+		copy(challengeHash[:], digest)
+		return challengeHash, nil
+	}
+
+	msg, err := a.signedChallengeBytes(challenge)
+	if err != nil {
+		return challengeHash, err
+	}
+
+	copy(challengeHash[:], ethCrypto.Keccak256(a.bindDomain(msg)))
+	return challengeHash, nil
+}
+
+func (a *Authenticator) challengeDigest(challenge string) ([]byte, error) {
+	digest := common.FromHex(challenge)
+	if a.challengeEncoding == ChallengeEncodingBase64 {
+		var err error
+		if digest, err = a.decodeChallenge(challenge); err != nil {
+			return nil, err
+		}
+	}
+	if len(digest) != 32 {
+		return nil, ErrInvalidChallengeDigest
+	}
+	return digest, nil
+}
+
+// ValidityWindow returns the window within which the challenge is valid, per the times extracted from the challenge
+// message by the configured extractors (see WithChallengeNotBeforeExtractor and WithChallengeExpiryExtractor).
+// A zero time means the window is unbounded on that side, e.g. both are zero for challenges without validity fields.
+// Verification results may be cached for at most the window.
+func (a *Authenticator) ValidityWindow(challenge string) (notBefore, notAfter time.Time, err error) {
+	if a.challengeNotBeforeExtractor == nil && a.challengeExpiryExtractor == nil {
+		return notBefore, notAfter, nil
+	}
+
+	msg, err := a.extractableMessage(challenge)
+	if err != nil {
+		return notBefore, notAfter, err
+	}
+
+	if a.challengeNotBeforeExtractor != nil {
+		if notBefore, err = a.challengeNotBeforeExtractor(msg); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("dappauth: extracting challenge not-before: %w", err)
+		}
+	}
+	if a.challengeExpiryExtractor != nil {
+		if notAfter, err = a.challengeExpiryExtractor(msg); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("dappauth: extracting challenge expiry: %w", err)
+		}
+	}
+	return notBefore, notAfter, nil
+}
+
+// extractableMessage returns the message which fields are extracted from, i.e. the challenge itself regardless of any
+// domain binding (or the digest of pre-hashed challenges).
+func (a *Authenticator) extractableMessage(challenge string) ([]byte, error) {
+	if a.challengePreHashed {
+		return a.challengeDigest(challenge)
+	}
+	return a.decodeChallenge(challenge)
+}
+
+// checkChallengeValidity enforces the validity window of the challenge, if any.
+func (a *Authenticator) checkChallengeValidity(challenge string) error {
+	notBefore, notAfter, err := a.ValidityWindow(challenge)
+	if err != nil {
+		return err
+	}
+
+	now := a.now()
+	if !notBefore.IsZero() && now.Before(notBefore) {
+		return ErrChallengeNotYetValid
+	}
+	if !notAfter.IsZero() && now.After(notAfter) {
+		return ErrChallengeExpired
+	}
+	return nil
+}
@@ -0,0 +1,515 @@
+package dappauth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestChallengePreHashed(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+
+	// the double-hash client hashes the challenge, then personal_signs the 32 bytes of the hash
+	digest := ethCrypto.Keccak256([]byte("foo"))
+	doubleHashSig := signEOAPersonalMessage(string(digest), keyA, t)
+
+	t.Run("Double-hash clients should be authorized signers over a pre-hashed challenge", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{}, WithChallengePreHashed(true))
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner(common.ToHex(digest), doubleHashSig, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	t.Run("Double-hash clients should NOT be authorized signers over a regular challenge", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{})
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", doubleHashSig, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, false, t)
+	})
+
+	t.Run("Smart-contract wallets should receive the digest as the challenge hash", func(t *testing.T) {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey}
+		authenticator := NewAuthenticator(nil, mock, WithChallengePreHashed(true))
+		sig := signERC1654PersonalMessage("foo", keyB, addrA, t)
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner(common.ToHex(digest), sig, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	t.Run("Pre-hashed challenges which are not a digest should error", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{}, WithChallengePreHashed(true))
+
+		_, err := authenticator.IsAuthorizedSigner("foo", doubleHashSig, addrA.Hex())
+		expectBool(err == ErrInvalidChallengeDigest, true, t)
+	})
+}
+
+func TestLegacyMetaMask(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+
+	legacyTests := []struct {
+		title     string
+		signature string
+	}{
+		{"Legacy MetaMask signatures over the 0x prefixed hex string should verify only under the flag", signEOAPersonalMessage("0x666f6f", keyA, t)},
+		{"Legacy MetaMask signatures over the hex string should verify only under the flag", signEOAPersonalMessage("666f6f", keyA, t)},
+	}
+
+	for _, test := range legacyTests {
+		t.Run(test.title, func(t *testing.T) {
+			isAuthorizedSigner, err := NewAuthenticator(nil, &mockContract{}).IsAuthorizedSigner("foo", test.signature, addrA.Hex())
+			checkError(err, t)
+			expectBool(isAuthorizedSigner, false, t)
+
+			isAuthorizedSigner, err = NewAuthenticator(nil, &mockContract{}, WithLegacyMetaMask(true)).IsAuthorizedSigner("foo", test.signature, addrA.Hex())
+			checkError(err, t)
+			expectBool(isAuthorizedSigner, true, t)
+		})
+	}
+
+	t.Run("Standard signatures should still verify under the flag", func(t *testing.T) {
+		isAuthorizedSigner, err := NewAuthenticator(nil, &mockContract{}, WithLegacyMetaMask(true)).IsAuthorizedSigner("foo", signEOAPersonalMessage("foo", keyA, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+}
+
+func TestChallengeEncodingBase64(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+
+	// binary challenge whose standard and URL-safe encodings differ, and which needs padding
+	challenge := []byte{0xfb, 0xff, 0xbf, 0x00, 0x01}
+	eoaSig := signEOAPersonalMessage(string(challenge), keyA, t)
+
+	encodingTests := []struct {
+		title   string
+		encoded string
+	}{
+		{"External wallets should be authorized signers over a standard base64 challenge", base64.StdEncoding.EncodeToString(challenge)},
+		{"External wallets should be authorized signers over an unpadded standard base64 challenge", base64.RawStdEncoding.EncodeToString(challenge)},
+		{"External wallets should be authorized signers over a URL-safe base64 challenge", base64.URLEncoding.EncodeToString(challenge)},
+		{"External wallets should be authorized signers over an unpadded URL-safe base64 challenge", base64.RawURLEncoding.EncodeToString(challenge)},
+	}
+
+	for _, test := range encodingTests {
+		t.Run(test.title, func(t *testing.T) {
+			authenticator := NewAuthenticator(nil, &mockContract{}, WithChallengeEncoding(ChallengeEncodingBase64))
+
+			isAuthorizedSigner, err := authenticator.IsAuthorizedSigner(test.encoded, eoaSig, addrA.Hex())
+			checkError(err, t)
+			expectBool(isAuthorizedSigner, true, t)
+		})
+	}
+
+	t.Run("External wallets should NOT be authorized signers over a base64 challenge without the encoding", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{})
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner(base64.StdEncoding.EncodeToString(challenge), eoaSig, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, false, t)
+	})
+
+	t.Run("Smart-contract wallets should be authorized signers over a base64 challenge", func(t *testing.T) {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey}
+		authenticator := NewAuthenticator(nil, mock, WithChallengeEncoding(ChallengeEncodingBase64))
+		sig := signERC1654PersonalMessage(string(challenge), keyB, addrA, t)
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner(base64.URLEncoding.EncodeToString(challenge), sig, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	t.Run("Challenges which are not base64 should error", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{}, WithChallengeEncoding(ChallengeEncodingBase64))
+
+		_, err := authenticator.IsAuthorizedSigner("not base64!", eoaSig, addrA.Hex())
+		expectBool(err == ErrInvalidChallengeEncoding, true, t)
+	})
+}
+
+func TestChallengeExpiryExtractor(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// extracts the RFC 3339 expiry of a JSON challenge
+	extractExpiry := func(message []byte) (time.Time, error) {
+		var challenge struct {
+			Expiry time.Time `json:"expiry"`
+		}
+		err := json.Unmarshal(message, &challenge)
+		return challenge.Expiry, err
+	}
+	authenticator := NewAuthenticator(nil, &mockContract{}, WithChallengeExpiryExtractor(extractExpiry), WithClock(func() time.Time { return now }))
+
+	expiryTests := []struct {
+		title       string
+		challenge   string
+		expected    bool
+		expectedErr error
+	}{
+		{"External wallets should be authorized signers over a fresh challenge", `{"nonce":"abc","expiry":"2020-01-01T12:05:00Z"}`, true, nil},
+		{"Stale challenges should error with ErrChallengeExpired", `{"nonce":"abc","expiry":"2020-01-01T11:55:00Z"}`, false, ErrChallengeExpired},
+	}
+
+	for _, test := range expiryTests {
+		t.Run(test.title, func(t *testing.T) {
+			isAuthorizedSigner, err := authenticator.IsAuthorizedSigner(test.challenge, signEOAPersonalMessage(test.challenge, keyA, t), addrA.Hex())
+			expectBool(err == test.expectedErr, true, t)
+			expectBool(isAuthorizedSigner, test.expected, t)
+		})
+	}
+
+	t.Run("Challenges without an extractable expiry should error", func(t *testing.T) {
+		_, err := authenticator.IsAuthorizedSigner("foo", signEOAPersonalMessage("foo", keyA, t), addrA.Hex())
+		expectBool(err != nil, true, t)
+	})
+}
+
+func TestValidityWindow(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// extracts the optional RFC 3339 validity fields of a JSON challenge
+	type jsonChallenge struct {
+		NotBefore time.Time `json:"notBefore"`
+		Expiry    time.Time `json:"expiry"`
+	}
+	extractNotBefore := func(message []byte) (time.Time, error) {
+		var challenge jsonChallenge
+		err := json.Unmarshal(message, &challenge)
+		return challenge.NotBefore, err
+	}
+	extractExpiry := func(message []byte) (time.Time, error) {
+		var challenge jsonChallenge
+		err := json.Unmarshal(message, &challenge)
+		return challenge.Expiry, err
+	}
+	authenticator := NewAuthenticator(nil, &mockContract{},
+		WithChallengeNotBeforeExtractor(extractNotBefore),
+		WithChallengeExpiryExtractor(extractExpiry),
+		WithClock(func() time.Time { return now }))
+
+	windowTests := []struct {
+		title             string
+		challenge         string
+		expectedNotBefore time.Time
+		expectedNotAfter  time.Time
+		expectedErr       error
+	}{
+		{
+			"Challenges with validity fields should have a bounded window",
+			`{"nonce":"abc","notBefore":"2020-01-01T11:55:00Z","expiry":"2020-01-01T12:05:00Z"}`,
+			time.Date(2020, 1, 1, 11, 55, 0, 0, time.UTC),
+			time.Date(2020, 1, 1, 12, 5, 0, 0, time.UTC),
+			nil,
+		},
+		{
+			"Challenges with only an expiry should have a window unbounded from below",
+			`{"nonce":"abc","expiry":"2020-01-01T12:05:00Z"}`,
+			time.Time{},
+			time.Date(2020, 1, 1, 12, 5, 0, 0, time.UTC),
+			nil,
+		},
+		{
+			"Challenges without validity fields should have an unbounded window",
+			`{"nonce":"abc"}`,
+			time.Time{},
+			time.Time{},
+			nil,
+		},
+		{
+			"Challenges not yet valid should error with ErrChallengeNotYetValid",
+			`{"nonce":"abc","notBefore":"2020-01-01T12:01:00Z"}`,
+			time.Date(2020, 1, 1, 12, 1, 0, 0, time.UTC),
+			time.Time{},
+			ErrChallengeNotYetValid,
+		},
+	}
+
+	for _, test := range windowTests {
+		t.Run(test.title, func(t *testing.T) {
+			notBefore, notAfter, err := authenticator.ValidityWindow(test.challenge)
+			checkError(err, t)
+			expectBool(notBefore.Equal(test.expectedNotBefore), true, t)
+			expectBool(notAfter.Equal(test.expectedNotAfter), true, t)
+
+			isAuthorizedSigner, err := authenticator.IsAuthorizedSigner(test.challenge, signEOAPersonalMessage(test.challenge, keyA, t), addrA.Hex())
+			expectBool(err == test.expectedErr, true, t)
+			expectBool(isAuthorizedSigner, test.expectedErr == nil, t)
+		})
+	}
+
+	t.Run("Challenges should have an unbounded window without extractors", func(t *testing.T) {
+		notBefore, notAfter, err := NewAuthenticator(nil, &mockContract{}).ValidityWindow("foo")
+		checkError(err, t)
+		expectBool(notBefore.IsZero() && notAfter.IsZero(), true, t)
+	})
+}
+
+func TestChallengeEncodingHex(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	authenticator := NewAuthenticator(nil, &mockContract{}, WithChallengeEncoding(ChallengeEncodingHex))
+
+	// the wallet is shown the hex string of the binary challenge, and signs it as is
+	challenge := []byte{0xde, 0xad, 0xbe, 0xef}
+	hexChallenge := common.ToHex(challenge)
+
+	t.Run("External wallets should be authorized signers over the hex string they were shown", func(t *testing.T) {
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner(hexChallenge, signEOAPersonalMessage(hexChallenge, keyA, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	t.Run("External wallets should NOT be authorized signers with the length of the binary challenge in the prefix", func(t *testing.T) {
+		// "\x19Ethereum Signed Message:\n4" rather than "...\n10" ahead of the hex string
+		mismatchedHash := ethCrypto.Keccak256([]byte(fmt.Sprintf("%s%d%s", PersonalMessagePrefix, len(challenge), hexChallenge)))
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner(hexChallenge, signRawHash(mismatchedHash, keyA, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, false, t)
+	})
+
+	t.Run("Challenges which are not hex should error", func(t *testing.T) {
+		_, err := authenticator.IsAuthorizedSigner("foo", signEOAPersonalMessage("foo", keyA, t), addrA.Hex())
+		expectBool(err == ErrInvalidChallengeEncoding, true, t)
+	})
+}
+
+func TestPersonalMessageBytesMode(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+
+	// binary challenge which isn't valid UTF-8, passed base64 encoded
+	challenge := []byte{0x00, 0xff, 0x80, 0x19, 0xfe}
+	encoded := base64.StdEncoding.EncodeToString(challenge)
+	hexChallenge := "0x00ff8019fe"
+
+	modeTests := []struct {
+		title    string
+		mode     PersonalMessageBytesMode
+		signed   string
+		expected bool
+	}{
+		{"External wallets signing the raw bytes should be authorized signers in raw bytes mode", PersonalMessageRawBytes, string(challenge), true},
+		{"External wallets signing the hex string should NOT be authorized signers in raw bytes mode", PersonalMessageRawBytes, hexChallenge, false},
+		{"External wallets signing the hex string should be authorized signers in hex string mode", PersonalMessageHexString, hexChallenge, true},
+		{"External wallets signing the raw bytes should NOT be authorized signers in hex string mode", PersonalMessageHexString, string(challenge), false},
+	}
+
+	for _, test := range modeTests {
+		t.Run(test.title, func(t *testing.T) {
+			authenticator := NewAuthenticator(nil, &mockContract{}, WithChallengeEncoding(ChallengeEncodingBase64), WithPersonalMessageBytesMode(test.mode))
+
+			isAuthorizedSigner, err := authenticator.IsAuthorizedSigner(encoded, signEOAPersonalMessage(test.signed, keyA, t), addrA.Hex())
+			checkError(err, t)
+			expectBool(isAuthorizedSigner, test.expected, t)
+		})
+	}
+
+	t.Run("The length in the prefix should be that of the hex string in hex string mode", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{}, WithChallengeEncoding(ChallengeEncodingBase64), WithPersonalMessageBytesMode(PersonalMessageHexString))
+		// "\x19Ethereum Signed Message:\n5" rather than "...\n12" ahead of the hex string
+		mismatchedHash := ethCrypto.Keccak256([]byte(fmt.Sprintf("%s%d%s", PersonalMessagePrefix, len(challenge), hexChallenge)))
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner(encoded, signRawHash(mismatchedHash, keyA, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, false, t)
+	})
+
+	t.Run("Smart-contract wallets should be authorized signers over the hex string in hex string mode", func(t *testing.T) {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey}
+		authenticator := NewAuthenticator(nil, mock, WithChallengeEncoding(ChallengeEncodingBase64), WithPersonalMessageBytesMode(PersonalMessageHexString))
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner(encoded, signERC1654PersonalMessage(hexChallenge, keyB, addrA, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	t.Run("Hex challenges should be signed as is in hex string mode", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{}, WithChallengeEncoding(ChallengeEncodingHex), WithPersonalMessageBytesMode(PersonalMessageHexString))
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner(hexChallenge, signEOAPersonalMessage(hexChallenge, keyA, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+}
+
+func TestDomainBinding(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyC, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrC := ethCrypto.PubkeyToAddress(keyC.PublicKey)
+	mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey}
+
+	exampleCom := NewAuthenticator(nil, mock, WithDomainBinding("Example.com"))
+	evilCom := NewAuthenticator(nil, mock, WithDomainBinding("evil.com"))
+
+	// the wallet signs the domain-bound message
+	eoaSig := signEOAPersonalMessage("example.com\nfoo", keyC, t)
+	contractSig := signERC1654PersonalMessage("example.com\nfoo", keyB, addrA, t)
+
+	domainTests := []struct {
+		title         string
+		authenticator *Authenticator
+		signature     string
+		address       common.Address
+		expected      bool
+	}{
+		{"External wallets should be authorized signers on the bound domain", exampleCom, eoaSig, addrC, true},
+		{"External wallets should NOT be authorized signers on another domain", evilCom, eoaSig, addrC, false},
+		{"External wallets should NOT be authorized signers without the binding", NewAuthenticator(nil, mock), eoaSig, addrC, false},
+		{"Smart-contract wallets should be authorized signers on the bound domain", exampleCom, contractSig, addrA, true},
+		{"Smart-contract wallets should NOT be authorized signers on another domain", evilCom, contractSig, addrA, false},
+	}
+
+	for _, test := range domainTests {
+		t.Run(test.title, func(t *testing.T) {
+			isAuthorizedSigner, err := test.authenticator.IsAuthorizedSigner("foo", test.signature, test.address.Hex())
+			checkError(err, t)
+			expectBool(isAuthorizedSigner, test.expected, t)
+		})
+	}
+
+	t.Run("Validity fields should be extracted from the unbound challenge", func(t *testing.T) {
+		var extracted string
+		authenticator := NewAuthenticator(nil, mock, WithDomainBinding("example.com"), WithChallengeExpiryExtractor(func(message []byte) (time.Time, error) {
+			extracted = string(message)
+			return time.Time{}, nil
+		}))
+		_, _, err := authenticator.ValidityWindow("foo")
+		checkError(err, t)
+		expectBool(extracted == "foo", true, t)
+	})
+}
+
+func TestAddressBinding(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrB := ethCrypto.PubkeyToAddress(keyB.PublicKey)
+	authenticator := NewAuthenticator(nil, &mockContract{}, WithAddressBinding(true))
+
+	bindingTests := []struct {
+		title       string
+		challenge   string
+		expectedErr error
+	}{
+		{"Challenges with the address line should be authorized", "Sign in\nAddress: " + addrA.Hex() + "\nNonce: 42", nil},
+		{"Challenges with the address line ending in CRLF should be authorized", "Sign in\r\nAddress: " + addrA.Hex() + "\r\nNonce: 42", nil},
+		{"Challenges with the address as the last line should be authorized", "Sign in\nAddress: " + addrA.Hex(), nil},
+		{"Challenges with the lowercased address should error with ErrAddressNotBound", "Sign in\nAddress: " + strings.ToLower(addrA.Hex()), ErrAddressNotBound},
+		{"Challenges with a longer hex string containing the address should error with ErrAddressNotBound", "Sign in\nAddress: " + addrA.Hex() + "ff", ErrAddressNotBound},
+		{"Challenges containing the address outside the address line should error with ErrAddressNotBound", "Sign in as " + addrA.Hex(), ErrAddressNotBound},
+		{"Challenges with another address should error with ErrAddressNotBound", "Sign in\nAddress: " + addrB.Hex(), ErrAddressNotBound},
+		{"Challenges with several address lines should error with ErrAddressNotBound", "Address: " + addrA.Hex() + "\nAddress: " + addrB.Hex(), ErrAddressNotBound},
+		{"Challenges without an address should error with ErrAddressNotBound", "Sign in (nonce 42)", ErrAddressNotBound},
+	}
+
+	for _, test := range bindingTests {
+		t.Run(test.title, func(t *testing.T) {
+			isAuthorizedSigner, err := authenticator.IsAuthorizedSigner(test.challenge, signEOAPersonalMessage(test.challenge, keyA, t), addrA.Hex())
+			expectBool(err == test.expectedErr, true, t)
+			expectBool(isAuthorizedSigner, test.expectedErr == nil, t)
+		})
+	}
+
+	// every entry point taking an address is bound, not only IsAuthorizedSigner
+	unbound := "Sign in\nAddress: " + addrB.Hex()
+	sig := signEOAPersonalMessage(unbound, keyA, t)
+	wallet := CounterfactualWallet{
+		Factory:         common.HexToAddress("0x4e59b44847b379578588920ca78fbf26c0b4956c"),
+		Salt:            [32]byte{1},
+		InitCode:        common.FromHex("0x6080604052"),
+		FactoryCalldata: common.FromHex("0xdeadbeef"),
+	}
+	bound := NewAuthenticator(nil, &mockContract{}, WithAddressBinding(true), WithOwnerResolver(&mockOwnerResolver{}))
+
+	entryPointTests := []struct {
+		title  string
+		verify func() error
+	}{
+		{"IsAuthorizedSignerTyped should error with ErrAddressNotBound", func() error {
+			_, err := bound.IsAuthorizedSignerTyped(unbound, sig, addrA.Hex(), WalletKindEOA)
+			return err
+		}},
+		{"IsAuthorizedByAnyContract should error with ErrAddressNotBound", func() error {
+			_, _, err := bound.IsAuthorizedByAnyContract(unbound, sig, []string{addrA.Hex()})
+			return err
+		}},
+		{"IsAuthorizedCounterfactualSigner should error with ErrAddressNotBound", func() error {
+			_, err := bound.IsAuthorizedCounterfactualSigner(unbound, sig, wallet, wallet.Address().Hex())
+			return err
+		}},
+		{"VerifySessionKey should error with ErrAddressNotBound", func() error {
+			_, err := bound.VerifySessionKey(unbound, sig, addrA.Hex())
+			return err
+		}},
+		{"CrossCheck should error with ErrAddressNotBound", func() error {
+			_, err := bound.CrossCheck(unbound, sig, addrA.Hex())
+			return err
+		}},
+		{"VerifyMultisigCount should error with ErrAddressNotBound", func() error {
+			_, _, err := bound.VerifyMultisigCount(unbound, sig, addrA.Hex())
+			return err
+		}},
+	}
+
+	for _, test := range entryPointTests {
+		t.Run(test.title, func(t *testing.T) {
+			expectBool(errors.Is(test.verify(), ErrAddressNotBound), true, t)
+		})
+	}
+}
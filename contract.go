@@ -0,0 +1,261 @@
+package dappauth
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+const defaultContractParallelism = 4
+
+// ContractHashScheme defines which hash of the challenge is passed to isValidSignature of smart-contract wallets,
+// and over which hash the wallet's signers are expected to have signed.
+type ContractHashScheme int
+
+const (
+	// HashSchemeERC191 passes keccak256(challenge); the wallet hashes ontop to an erc191 hash bound to its address,
+	// which its signers signed (default).
+	HashSchemeERC191 ContractHashScheme = iota
+	// HashSchemeRaw passes keccak256(challenge), which the wallet's signers signed directly.
+	HashSchemeRaw
+	// HashSchemePersonalPrefixed passes the personal message hash of the challenge, i.e. the wallet's signers
+	// signed the challenge via personal_sign.
+	HashSchemePersonalPrefixed
+	// HashSchemeCustom passes keccak256(challenge), over which the wallet's signers signed the digest built by the
+	// ContractDigestBuilder set by WithContractDigestBuilder, for wallets with a non-standard scheme.
+	HashSchemeCustom
+	// HashSchemeDoubleKeccak passes keccak256(personalHash), personalHash being the personal message hash of the
+	// challenge, i.e. keccak256(keccak256("\x19Ethereum Signed Message:\n" ‖ len(challenge) ‖ challenge)), which the
+	// wallet's signers signed directly, for wallets hashing the already prefixed message once more.
+	HashSchemeDoubleKeccak
+)
+
+// WithContractHashScheme sets the hash scheme expected by smart-contract wallets (default = HashSchemeERC191).
+func WithContractHashScheme(scheme ContractHashScheme) Option {
+	return func(a *Authenticator) {
+		a.contractHashScheme = scheme
+	}
+}
+
+// ContractDigestBuilder builds the digest signed by the signers of the smart-contract wallet at addr, given the hash
+// passed to its isValidSignature.
+type ContractDigestBuilder func(hash []byte, addr common.Address) []byte
+
+// WithContractDigestBuilder sets the custom hash scheme (HashSchemeCustom) of smart-contract wallets, whose signers
+// signed the digest the builder builds (e.g. DomainSeparatedDigest).
+func WithContractDigestBuilder(builder ContractDigestBuilder) Option {
+	return func(a *Authenticator) {
+		a.contractHashScheme = HashSchemeCustom
+		a.contractDigestBuilder = builder
+	}
+}
+
+// DomainSeparatedDigest is the ContractDigestBuilder of the custom scheme keccak256(domainSeparator ‖ hash), i.e.
+// keccak256(domainSeparator ‖ keccak256(message)), of wallets separating domains without the EIP-712 0x1901 prefix.
+func DomainSeparatedDigest(domainSeparator [32]byte) ContractDigestBuilder {
+	return func(hash []byte, addr common.Address) []byte {
+		return ethCrypto.Keccak256(domainSeparator[:], hash)
+	}
+}
+
+// WithContractDigestExtra mixes extra bytes (e.g. a wallet-managed nonce) into the hash passed to isValidSignature of
+// smart-contract wallets, which becomes keccak256(hash ‖ extra) with hash per the contract hash scheme. The extra bytes
+// must match the scheme of the wallet, otherwise no signature verifies. Interfaces taking the data rather than its
+// hash still receive the challenge message as is.
+func WithContractDigestExtra(extra []byte) Option {
+	return func(a *Authenticator) {
+		a.contractDigestExtra = extra
+	}
+}
+
+// IsAuthorizedSignerWithDigestExtra is like IsAuthorizedSigner but mixes the extra bytes into the hash passed to
+// smart-contract wallets, as WithContractDigestExtra, for this verification only.
+func (a *Authenticator) IsAuthorizedSignerWithDigestExtra(challenge, signature, addrHex string, extra []byte) (bool, error) {
+	augmented := *a
+	augmented.contractDigestExtra = extra
+	augmented.resultCache = nil
+
+	return augmented.IsAuthorizedSigner(challenge, signature, addrHex)
+}
+
+// ContractSignatureForm defines the form in which the signature is passed to isValidSignature of smart-contract wallets.
+type ContractSignatureForm int
+
+const (
+	// SignatureFormFull65 passes the signature as is, i.e. r ‖ s ‖ v for each signer (default).
+	SignatureFormFull65 ContractSignatureForm = iota
+	// SignatureFormRS64 strips the recovery byte, passing r ‖ s for each signer. Signatures which aren't
+	// (concatenated) 65 bytes signatures are passed as is.
+	SignatureFormRS64
+	// SignatureFormWithCompressedPubkey appends the compressed (33 bytes) public key of the signer, recovered from the
+	// signature, passing r ‖ s ‖ v ‖ pubkey for each signer. Signatures which aren't (concatenated) recoverable 65 bytes
+	// signatures are passed as is.
+	SignatureFormWithCompressedPubkey
+)
+
+// WithContractSignatureForm sets the form of the signature expected by smart-contract wallets (default = SignatureFormFull65).
+func WithContractSignatureForm(form ContractSignatureForm) Option {
+	return func(a *Authenticator) {
+		a.contractSignatureForm = form
+	}
+}
+
+// WithContractParallelism bounds the number of concurrent contract calls made when verifying against multiple
+// smart-contract wallets (default = 4).
+func WithContractParallelism(n int) Option {
+	return func(a *Authenticator) {
+		if n > 0 {
+			a.contractParallelism = n
+		}
+	}
+}
+
+// WithIgnoreContractErrors makes errors of individual wallets (e.g. RPC errors) not fail a verification against
+// multiple smart-contract wallets, so that such a wallet is treated as not authorizing the signature.
+func WithIgnoreContractErrors(ignore bool) Option {
+	return func(a *Authenticator) {
+		a.ignoreContractErrors = ignore
+	}
+}
+
+// IsAuthorizedByAnyContract checks concurrently if any of the smart-contract wallets authorizes the signature for the challenge,
+// returning the first of the addresses (in the given order) which does. If none does, the first error of the wallets is returned,
+// unless WithIgnoreContractErrors is set.
+func (a *Authenticator) IsAuthorizedByAnyContract(challenge, signature string, addrs []string) (matched string, ok bool, err error) {
+
+	if err := a.checkChallengeValidity(challenge); err != nil {
+		return "", false, err
+	}
+
+	sigBytes, err := a.signatureBytes(signature)
+	if err != nil {
+		return "", false, err
+	}
+
+	type result struct {
+		isAuthorizedSigner bool
+		err                error
+	}
+	results := make([]result, len(addrs))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, a.contractParallelism)
+	for i, addrHex := range addrs {
+		addr, err := a.normalizeAddress(addrHex)
+		if err == nil {
+			err = a.checkAddressBinding(challenge, addr)
+		}
+		if err != nil {
+			results[i].err = err
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, addr common.Address) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i].isAuthorizedSigner, results[i].err = a.isAuthorizedContractSigner(challenge, sigBytes, addr)
+		}(i, addr)
+	}
+	wg.Wait()
+
+	for i, r := range results {
+		if r.isAuthorizedSigner {
+			return addrs[i], true, nil
+		}
+	}
+
+	if !a.ignoreContractErrors {
+		for _, r := range results {
+			if r.err != nil {
+				return "", false, r.err
+			}
+		}
+	}
+
+	return "", false, nil
+}
+
+// contractHash returns the hash passed to isValidSignature of smart-contract wallets according to the hash scheme,
+// with the digest extra mixed in if any.
+func (a *Authenticator) contractHash(challenge string) ([32]byte, error) {
+	hash, err := a.schemeContractHash(challenge)
+	if err != nil || a.contractDigestExtra == nil {
+		return hash, err
+	}
+
+	copy(hash[:], ethCrypto.Keccak256(hash[:], a.contractDigestExtra))
+	return hash, nil
+}
+
+func (a *Authenticator) schemeContractHash(challenge string) ([32]byte, error) {
+	switch a.contractHashScheme {
+	case HashSchemePersonalPrefixed, HashSchemeDoubleKeccak:
+		var hash [32]byte
+		personalChallengeHash, err := a.personalChallengeHash(challenge)
+		if err != nil {
+			return hash, err
+		}
+		if a.contractHashScheme == HashSchemeDoubleKeccak {
+			personalChallengeHash = ethCrypto.Keccak256(personalChallengeHash)
+		}
+		copy(hash[:], personalChallengeHash)
+		return hash, nil
+	}
+
+	return a.contractChallengeHash(challenge)
+}
+
+// signedContractHash returns the hash signed by the signers of a smart-contract wallet, given the hash passed to the wallet.
+func (a *Authenticator) signedContractHash(hash []byte, addr common.Address) []byte {
+	switch a.contractHashScheme {
+	case HashSchemeERC191:
+		return erc191MessageHash(hash, addr)
+	case HashSchemeCustom:
+		if a.contractDigestBuilder != nil {
+			return a.contractDigestBuilder(hash, addr)
+		}
+	}
+	return hash
+}
+
+// contractSignature returns the signature in the form expected by smart-contract wallets. signedHash is the hash the
+// signers signed, which their public keys are recovered from.
+func (a *Authenticator) contractSignature(sig, signedHash []byte) []byte {
+	if len(sig) == 0 || len(sig)%65 != 0 {
+		return sig
+	}
+
+	switch a.contractSignatureForm {
+	case SignatureFormRS64:
+		rs := make([]byte, 0, len(sig)/65*64)
+		for _, chunk := range chunk65Bytes(sig) {
+			rs = append(rs, chunk[:64]...)
+		}
+		return rs
+	case SignatureFormWithCompressedPubkey:
+		withPubkeys := make([]byte, 0, len(sig)/65*(65+33))
+		for _, chunk := range chunk65Bytes(sig) {
+			pub, err := a.recoverPublicKey(signedHash, chunk[:])
+			if err != nil {
+				return sig
+			}
+			withPubkeys = append(append(withPubkeys, chunk[:]...), compressPubkey(pub)...)
+		}
+		return withPubkeys
+	default:
+		return sig
+	}
+}
+
+// compressPubkey compresses the uncompressed (65 bytes) public key to its 33 bytes form, i.e. the parity of Y then X.
+func compressPubkey(pub []byte) []byte {
+	compressed := make([]byte, 33)
+	compressed[0] = 0x02 | pub[64]&1
+	copy(compressed[1:], pub[1:33])
+	return compressed
+}
@@ -0,0 +1,285 @@
+package dappauth
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestContractHashScheme(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+
+	// the hash each scheme's wallet signers sign
+	signers := map[ContractHashScheme]func(msg string, key *ecdsa.PrivateKey) string{
+		HashSchemeERC191: func(msg string, key *ecdsa.PrivateKey) string {
+			return signERC1654PersonalMessage(msg, key, addrA, t)
+		},
+		HashSchemeRaw: func(msg string, key *ecdsa.PrivateKey) string {
+			return signRawHash(ethCrypto.Keccak256([]byte(msg)), key, t)
+		},
+		HashSchemePersonalPrefixed: func(msg string, key *ecdsa.PrivateKey) string {
+			return signEOAPersonalMessage(msg, key, t)
+		},
+		HashSchemeDoubleKeccak: func(msg string, key *ecdsa.PrivateKey) string {
+			return signRawHash(ethCrypto.Keccak256(personalMessageHash(msg)), key, t)
+		},
+	}
+
+	challengeHash := ethCrypto.Keccak256([]byte("foo"))
+	schemeTests := []struct {
+		title        string
+		scheme       ContractHashScheme
+		expectedHash []byte
+	}{
+		{"Smart-contract wallets should be authorized signers under the ERC191 hash scheme", HashSchemeERC191, challengeHash},
+		{"Smart-contract wallets should be authorized signers under the raw hash scheme", HashSchemeRaw, challengeHash},
+		{"Smart-contract wallets should be authorized signers under the personal-prefixed hash scheme", HashSchemePersonalPrefixed, personalMessageHash("foo")},
+		{"Smart-contract wallets should be authorized signers under the double-keccak hash scheme", HashSchemeDoubleKeccak, ethCrypto.Keccak256(personalMessageHash("foo"))},
+	}
+
+	for _, test := range schemeTests {
+		t.Run(test.title, func(t *testing.T) {
+			mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, hashScheme: test.scheme}
+			authenticator := NewAuthenticator(nil, mock, WithContractHashScheme(test.scheme))
+			sig := signers[test.scheme]("foo", keyB)
+
+			isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+			checkError(err, t)
+			expectBool(isAuthorizedSigner, true, t)
+			expectBool(bytes.Equal(mock.lastHash[:], test.expectedHash), true, t)
+		})
+	}
+
+	t.Run("Smart-contract wallets should NOT be authorized signers of the single personal hash under the double-keccak hash scheme", func(t *testing.T) {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, hashScheme: HashSchemeDoubleKeccak}
+		authenticator := NewAuthenticator(nil, mock, WithContractHashScheme(HashSchemeDoubleKeccak))
+		sig := signers[HashSchemePersonalPrefixed]("foo", keyB)
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, false, t)
+	})
+
+	t.Run("Smart-contract wallets should NOT be authorized signers under a different hash scheme", func(t *testing.T) {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, hashScheme: HashSchemePersonalPrefixed}
+		authenticator := NewAuthenticator(nil, mock)
+		sig := signers[HashSchemePersonalPrefixed]("foo", keyB)
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, false, t)
+	})
+}
+
+func signRawHash(hash []byte, key *ecdsa.PrivateKey, t *testing.T) string {
+	sig, err := ethCrypto.Sign(hash, key)
+	checkError(err, t)
+
+	sig[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	return hex.EncodeToString(sig)
+}
+
+func TestIsAuthorizedByAnyContract(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	otherAddrs := []common.Address{
+		common.HexToAddress("0x0000000000000000000000000000000000000001"),
+		common.HexToAddress("0x0000000000000000000000000000000000000002"),
+		common.HexToAddress("0x0000000000000000000000000000000000000003"),
+	}
+	addrs := []string{otherAddrs[0].Hex(), otherAddrs[1].Hex(), addrA.Hex(), otherAddrs[2].Hex()}
+	sig := signERC1654PersonalMessage("foo", keyB, addrA, t)
+
+	anyContractTests := []struct {
+		title           string
+		addrs           []string
+		errorAddresses  []common.Address
+		opts            []Option
+		expectedMatched string
+		expectedOk      bool
+		expectedError   bool
+	}{
+		{
+			title:           "The single matching smart-contract wallet should be returned",
+			addrs:           addrs,
+			expectedMatched: addrA.Hex(),
+			expectedOk:      true,
+		},
+		{
+			title:           "The single matching smart-contract wallet should be returned without parallelism",
+			addrs:           addrs,
+			opts:            []Option{WithContractParallelism(1)},
+			expectedMatched: addrA.Hex(),
+			expectedOk:      true,
+		},
+		{
+			title:           "The matching smart-contract wallet should be returned when another wallet errors",
+			addrs:           addrs,
+			errorAddresses:  otherAddrs[1:2],
+			expectedMatched: addrA.Hex(),
+			expectedOk:      true,
+		},
+		{
+			title:          "Errors should be returned when no smart-contract wallet matches",
+			addrs:          []string{otherAddrs[0].Hex(), otherAddrs[1].Hex()},
+			errorAddresses: otherAddrs[1:2],
+			expectedError:  true,
+		},
+		{
+			title:          "Errors should be ignored when configured",
+			addrs:          []string{otherAddrs[0].Hex(), otherAddrs[1].Hex()},
+			errorAddresses: otherAddrs[1:2],
+			opts:           []Option{WithIgnoreContractErrors(true)},
+		},
+	}
+
+	for _, test := range anyContractTests {
+		t.Run(test.title, func(t *testing.T) {
+			mock := &mockContract{authorizedKey: &keyB.PublicKey, errorAddresses: test.errorAddresses}
+			authenticator := NewAuthenticator(nil, mock, test.opts...)
+
+			matched, ok, err := authenticator.IsAuthorizedByAnyContract("foo", sig, test.addrs)
+			expectBool(err != nil, test.expectedError, t)
+			expectBool(ok, test.expectedOk, t)
+			expectBool(matched == test.expectedMatched, true, t)
+		})
+	}
+}
+
+func TestContractSignatureForm(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	sig := signERC1654PersonalMessage("foo", keyB, addrA, t)
+	sigBytes, err := hex.DecodeString(sig)
+	checkError(err, t)
+
+	t.Run("Smart-contract wallets should receive the 65 bytes signature by default", func(t *testing.T) {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey}
+		authenticator := NewAuthenticator(nil, mock)
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+		expectBool(bytes.Equal(mock.lastSignature, sigBytes), true, t)
+	})
+
+	t.Run("Smart-contract wallets should receive the 64 bytes signature under SignatureFormRS64", func(t *testing.T) {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, expectRS64: true}
+		authenticator := NewAuthenticator(nil, mock, WithContractSignatureForm(SignatureFormRS64))
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+		expectBool(bytes.Equal(mock.lastSignature, sigBytes[:64]), true, t)
+	})
+
+	t.Run("Smart-contract wallets should receive the signature with the compressed pubkey under SignatureFormWithCompressedPubkey", func(t *testing.T) {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, expectPubkey: true}
+		authenticator := NewAuthenticator(nil, mock, WithContractSignatureForm(SignatureFormWithCompressedPubkey))
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+		expectBool(bytes.Equal(mock.lastSignature, append(append([]byte{}, sigBytes...), ethCrypto.CompressPubkey(&keyB.PublicKey)...)), true, t)
+	})
+
+	t.Run("Smart-contract wallets expecting the 64 bytes signature should error on the 65 bytes signature", func(t *testing.T) {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, expectRS64: true}
+		authenticator := NewAuthenticator(nil, mock)
+
+		_, err := authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+		expectBool(err != nil, true, t)
+	})
+}
+
+func TestContractDigestExtra(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	nonce := common.LeftPadBytes([]byte{7}, 32)
+
+	// the signers of the wallet sign the digest augmented with the wallet's nonce
+	augmented := ethCrypto.Keccak256(ethCrypto.Keccak256([]byte("foo")), nonce)
+	sig := signRawHash(erc191MessageHash(augmented, addrA), keyB, t)
+
+	digestTests := []struct {
+		title    string
+		options  []Option
+		expected bool
+	}{
+		{"Signers should NOT be authorized without the extra", nil, false},
+		{"Signers should be authorized with the matching extra", []Option{WithContractDigestExtra(nonce)}, true},
+		{"Signers should NOT be authorized with another extra", []Option{WithContractDigestExtra([]byte{8})}, false},
+	}
+
+	for _, test := range digestTests {
+		t.Run(test.title, func(t *testing.T) {
+			mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey}
+			isAuthorizedSigner, err := NewAuthenticator(nil, mock, test.options...).IsAuthorizedSigner("foo", sig, addrA.Hex())
+			checkError(err, t)
+			expectBool(isAuthorizedSigner, test.expected, t)
+			expectBool(bytes.Equal(mock.lastHash[:], augmented), test.expected, t)
+		})
+	}
+
+	t.Run("Signers should be authorized with the extra per call", func(t *testing.T) {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey}
+		isAuthorizedSigner, err := NewAuthenticator(nil, mock).IsAuthorizedSignerWithDigestExtra("foo", sig, addrA.Hex(), nonce)
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+}
+
+func TestContractDigestBuilder(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	domainSeparator := ethCrypto.Keccak256Hash([]byte("wallet domain"))
+
+	// the wallet's signers sign keccak256(domainSeparator ‖ keccak256(message))
+	mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, digestBuilder: DomainSeparatedDigest(domainSeparator)}
+	sig := signRawHash(ethCrypto.Keccak256(domainSeparator[:], ethCrypto.Keccak256([]byte("foo"))), keyB, t)
+
+	t.Run("Smart-contract wallets should be authorized signers under the custom scheme", func(t *testing.T) {
+		result, err := NewAuthenticator(nil, mock, WithContractDigestBuilder(DomainSeparatedDigest(domainSeparator))).Verify("foo", sig, addrA.Hex())
+		checkError(err, t)
+		expectBool(result.Authorized, true, t)
+		expectBool(len(result.InnerSigners) == 1 && result.InnerSigners[0] == ethCrypto.PubkeyToAddress(keyB.PublicKey), true, t)
+	})
+
+	t.Run("Smart-contract wallets should NOT be authorized signers under another domain separator", func(t *testing.T) {
+		otherSig := signRawHash(ethCrypto.Keccak256(ethCrypto.Keccak256([]byte("other domain")), ethCrypto.Keccak256([]byte("foo"))), keyB, t)
+
+		isAuthorizedSigner, err := NewAuthenticator(nil, mock).IsAuthorizedSigner("foo", otherSig, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, false, t)
+	})
+}
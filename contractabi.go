@@ -0,0 +1,79 @@
+package dappauth
+
+import (
+	"bytes"
+	"errors"
+
+	ethAbi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrUnsupportedContractABI is returned when the verification method set by WithContractABI isn't of a supported form.
+var ErrUnsupportedContractABI = errors.New("dappauth: unsupported contract ABI verification method")
+
+// WithContractABI verifies smart-contract wallets via the method of their ABI rather than the standard wallet interfaces,
+// for wallets with a bespoke verification function. The method must take the challenge, as either its hash (bytes32)
+// or its message (bytes), then the signature (bytes), and return either a bool or a bytes4 magic value (the ERC-1654
+// magic value for hashes, the legacy ERC-1271 one for messages).
+func WithContractABI(abi ethAbi.ABI, method string) Option {
+	return func(a *Authenticator) {
+		a.contractABI = abi
+		a.contractABIMethod = method
+		a.walletInterfaces = []WalletInterface{InterfaceContractABI}
+	}
+}
+
+func (a *Authenticator) isValidSignatureViaContractABI(addr common.Address, hash [32]byte, message, sig []byte) (bool, uint64, error) {
+	method, ok := a.contractABI.Methods[a.contractABIMethod]
+	if !ok || len(method.Inputs) != 2 || len(method.Outputs) != 1 || method.Inputs[1].Type.T != ethAbi.BytesTy {
+		return false, 0, ErrUnsupportedContractABI
+	}
+
+	var (
+		challenge  interface{}
+		magicValue [4]byte
+	)
+	switch input := method.Inputs[0].Type; {
+	case input.T == ethAbi.FixedBytesTy && input.Size == 32:
+		challenge, magicValue = hash, _ERC1271MagicValue
+	case input.T == ethAbi.BytesTy:
+		if message == nil {
+			message = hash[:]
+		}
+		challenge, magicValue = message, _ERC1271LegacyMagicValue
+	default:
+		return false, 0, ErrUnsupportedContractABI
+	}
+
+	input, err := a.contractABI.Pack(a.contractABIMethod, challenge, sig)
+	if err != nil {
+		return false, 0, err
+	}
+
+	output, gasUsed, err := a.callContract(addr, input)
+	if err != nil {
+		if isRevertError(err) {
+			return false, 0, errReverted
+		}
+		return false, 0, contractCallFailed(err)
+	}
+	if len(output) == 0 {
+		return false, 0, errReverted
+	}
+
+	switch returned := method.Outputs[0].Type; {
+	case returned.T == ethAbi.BoolTy:
+		var isValid bool
+		if err := a.contractABI.Unpack(&isValid, a.contractABIMethod, output); err != nil {
+			return false, 0, err
+		}
+		return isValid, gasUsed, nil
+	case returned.T == ethAbi.FixedBytesTy && returned.Size == 4:
+		if len(output) < 4 {
+			return false, gasUsed, nil
+		}
+		return bytes.Equal(output[:4], magicValue[:]), gasUsed, nil
+	default:
+		return false, 0, ErrUnsupportedContractABI
+	}
+}
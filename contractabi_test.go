@@ -0,0 +1,95 @@
+package dappauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	ethAbi "github.com/ethereum/go-ethereum/accounts/abi"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+const _customWalletABI = `[
+	{ "name": "verifySig", "type": "function", "constant": true, "inputs": [{ "name": "digest", "type": "bytes32" }, { "name": "sig", "type": "bytes" }], "outputs": [{ "name": "", "type": "bool" }] },
+	{ "name": "checkSig", "type": "function", "constant": true, "inputs": [{ "name": "digest", "type": "bytes32" }, { "name": "sig", "type": "bytes" }], "outputs": [{ "name": "", "type": "bytes4" }] },
+	{ "name": "owner", "type": "function", "constant": true, "inputs": [], "outputs": [{ "name": "", "type": "address" }] }
+]`
+
+// customWalletBackend is a wallet with a bespoke verifySig(bytes32, bytes) returns (bool) and
+// checkSig(bytes32, bytes) returns (bytes4) verification methods.
+type customWalletBackend struct {
+	*mockContract
+	abi ethAbi.ABI
+}
+
+func (b *customWalletBackend) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	for _, name := range []string{"verifySig", "checkSig"} {
+		method := b.abi.Methods[name]
+		if !bytes.Equal(call.Data[:4], method.Id()) {
+			continue
+		}
+
+		var args struct {
+			Digest [32]byte
+			Sig    []byte
+		}
+		if err := method.Inputs.Unpack(&args, call.Data[4:]); err != nil {
+			return nil, err
+		}
+		isValid, err := b.isAuthorizedSignature(args.Digest, args.Sig, *call.To)
+		if err != nil {
+			return nil, err
+		}
+		if name == "checkSig" {
+			return isValid, nil
+		}
+		return method.Outputs.Pack(bytes.Equal(isValid[:4], _ERC1271MagicValue[:]))
+	}
+	return nil, errors.New("execution reverted")
+}
+
+func TestContractABI(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+
+	abi, err := ethAbi.JSON(strings.NewReader(_customWalletABI))
+	checkError(err, t)
+
+	backend := &customWalletBackend{mockContract: &mockContract{address: addrA, authorizedKey: &keyB.PublicKey}, abi: abi}
+
+	abiTests := []struct {
+		title       string
+		method      string
+		sigKey      *ecdsa.PrivateKey
+		expected    bool
+		expectedErr error
+	}{
+		{"Wallets returning a bool should be authorized signers", "verifySig", keyB, true, nil},
+		{"Wallets returning a bool should NOT be authorized signers for others' signatures", "verifySig", keyA, false, nil},
+		{"Wallets returning the magic value should be authorized signers", "checkSig", keyB, true, nil},
+		{"Wallets returning the magic value should NOT be authorized signers for others' signatures", "checkSig", keyA, false, nil},
+		{"Methods of an unsupported form should error with ErrUnsupportedContractABI", "owner", keyB, false, ErrUnsupportedContractABI},
+	}
+
+	for _, test := range abiTests {
+		t.Run(test.title, func(t *testing.T) {
+			authenticator := NewAuthenticator(nil, backend, WithContractABI(abi, test.method))
+
+			isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", signERC1654PersonalMessage("foo", test.sigKey, addrA, t), addrA.Hex())
+			if !errors.Is(err, test.expectedErr) {
+				t.Fatalf("expected error %v, got %v", test.expectedErr, err)
+			}
+			expectBool(isAuthorizedSigner, test.expected, t)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package dappauth
+
+// CrossCheckResult reports the results of the EOA and smart-contract wallet paths, each checked independently.
+type CrossCheckResult struct {
+	EOAAuthorized      bool  // the signature recovers to the address, as IsAuthorizedSigner's EOA path checks it
+	ContractAuthorized bool  // the contract at the address authorized the signature
+	HasCode            bool  // the address has contract code
+	EOAErr             error // the error of the EOA path (if any), e.g. ErrHighS or ErrAddressFiltered
+	ContractErr        error // the error of the contract path (if any)
+}
+
+// Disagree returns true if the EOA and smart-contract wallet paths disagree.
+func (r *CrossCheckResult) Disagree() bool {
+	return r.EOAAuthorized != r.ContractAuthorized
+}
+
+// Anomalous returns true if the signature recovers to the address while the address has contract code,
+// which is a potential spoofing setup.
+func (r *CrossCheckResult) Anomalous() bool {
+	return r.EOAAuthorized && r.HasCode
+}
+
+// CrossCheck runs both the EOA and the smart-contract wallet paths, without short-circuiting, and reports the result of each.
+// Unlike IsAuthorizedSigner, errors of either path are reported in the result rather than returned.
+// Intended for security monitoring rather than authentication.
+func (a *Authenticator) CrossCheck(challenge, signature, addrHex string) (*CrossCheckResult, error) {
+
+	if err := a.checkChallengeValidity(challenge); err != nil {
+		return nil, err
+	}
+
+	addr, err := a.normalizeAddress(addrHex)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.checkAddressBinding(challenge, addr); err != nil {
+		return nil, err
+	}
+
+	sigBytes, err := a.signatureBytes(signature)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.cc == nil {
+		return nil, ErrNoBackend
+	}
+
+	code, err := a.codeAt(addr)
+	if err != nil {
+		return nil, wrapError("code of", addr, contractCallFailed(err))
+	}
+
+	result := &CrossCheckResult{HasCode: len(code) > 0}
+
+	// the same EOA path as IsAuthorizedSigner, so that the paths only disagree when they would in verifications
+	eoa, err := a.verifyEOA(challenge, sigBytes, addr)
+	result.EOAAuthorized, result.EOAErr = eoa != nil && eoa.Authorized, err
+
+	result.ContractAuthorized, result.ContractErr = a.isAuthorizedContractSigner(challenge, sigBytes, addr)
+
+	return result, nil
+}
@@ -0,0 +1,86 @@
+package dappauth
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestCrossCheck(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	code := []byte{0x60, 0x80}
+
+	flippedSigBytes := common.FromHex(signEOAPersonalMessage("foo", keyA, t))
+	flippedSigBytes[64] ^= 27 ^ 28 // the wallet sends the wrong parity
+	flippedV := common.Bytes2Hex(flippedSigBytes)
+
+	crossCheckTests := []struct {
+		title                      string
+		sig                        string
+		mockContract               *mockContract
+		opts                       []Option
+		expectedEOAAuthorized      bool
+		expectedContractAuthorized bool
+		expectedDisagree           bool
+		expectedAnomalous          bool
+	}{
+		{
+			title:                 "External wallets should only be authorized via the EOA path",
+			sig:                   signEOAPersonalMessage("foo", keyA, t),
+			mockContract:          &mockContract{address: addrA},
+			expectedEOAAuthorized: true,
+			expectedDisagree:      true,
+		},
+		{
+			title:                      "Smart-contract wallets should only be authorized via the contract path",
+			sig:                        signERC1654PersonalMessage("foo", keyB, addrA, t),
+			mockContract:               &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, code: code},
+			expectedContractAuthorized: true,
+			expectedDisagree:           true,
+		},
+		{
+			title:                      "Addresses with code recovered from the signature should be authorized via both paths and be anomalous",
+			sig:                        signEOAPersonalMessage("foo", keyA, t),
+			mockContract:               &mockContract{address: addrA, authorizedKey: &keyA.PublicKey, code: code, hashScheme: HashSchemePersonalPrefixed},
+			opts:                       []Option{WithContractHashScheme(HashSchemePersonalPrefixed)},
+			expectedEOAAuthorized:      true,
+			expectedContractAuthorized: true,
+			expectedAnomalous:          true,
+		},
+		{
+			title:                 "External wallets signing with the wrong parity should be authorized via the EOA path as in verifications",
+			sig:                   flippedV,
+			mockContract:          &mockContract{address: addrA},
+			opts:                  []Option{WithTryBothParities(true)},
+			expectedEOAAuthorized: true,
+			expectedDisagree:      true,
+		},
+		{
+			title:        "Unauthorized signatures should be authorized via neither path",
+			sig:          signEOAPersonalMessage("foo", keyB, t),
+			mockContract: &mockContract{address: addrA, authorizedKey: &keyA.PublicKey, code: code},
+		},
+	}
+
+	for _, test := range crossCheckTests {
+		t.Run(test.title, func(t *testing.T) {
+			authenticator := NewAuthenticator(nil, test.mockContract, test.opts...)
+
+			result, err := authenticator.CrossCheck("foo", test.sig, addrA.Hex())
+			checkError(err, t)
+			checkError(result.EOAErr, t)
+			checkError(result.ContractErr, t)
+			expectBool(result.EOAAuthorized, test.expectedEOAAuthorized, t)
+			expectBool(result.ContractAuthorized, test.expectedContractAuthorized, t)
+			expectBool(result.Disagree(), test.expectedDisagree, t)
+			expectBool(result.Anomalous(), test.expectedAnomalous, t)
+		})
+	}
+}
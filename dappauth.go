@@ -5,85 +5,673 @@ package dappauth
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"errors"
 	"fmt"
+	"math/big"
+	"strings"
+	"time"
 
-	"github.com/dapperlabs/dappauth/ERCs"
+	ethAbi "github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	ethCrypto "github.com/ethereum/go-ethereum/crypto"
 )
 
+// PersonalMessagePrefix is the EIP-191 (version 0x45) prefix prepended to messages signed via personal_sign / eth_sign.
+const PersonalMessagePrefix = "\x19Ethereum Signed Message:\n"
+
 var (
 	_ERC1271MagicValue = [4]byte{22, 38, 186, 126} // 0x1626ba7e
+
+	// ErrAddressFiltered is returned when a valid signature involves an address rejected by the address filter.
+	ErrAddressFiltered = errors.New("dappauth: address rejected by filter")
+
+	// ErrTooManySignatures is returned when a concatenated multi-sig signature holds more signatures than allowed.
+	ErrTooManySignatures = errors.New("dappauth: too many signatures")
+
+	// ErrDeadlineExceeded is returned when a verification didn't complete within the overall deadline.
+	ErrDeadlineExceeded = errors.New("dappauth: verification deadline exceeded")
+
+	// ErrNoBackend is returned when verification needs a contract call but the authenticator has no contract backend.
+	// External wallets are still verified without a backend.
+	ErrNoBackend = errors.New("dappauth: no contract backend")
 )
 
+const defaultMaxMultisigSignatures = 32
+
 // Authenticator is the instance that holds the ethclient.Client .
 type Authenticator struct {
 	cc  bind.ContractCaller
 	ctx context.Context // Network context to support cancellation and timeouts (nil = no timeout)
+
+	decodeSignature             SignatureDecoder            // applied to the raw signature before parsing (default = identity)
+	erc6492Validator            common.Address              // ERC-6492 UniversalSigValidator (zero = counterfactual wallets unsupported)
+	callFrom                    common.Address              // msg.sender of contract calls (default = zero address)
+	addressFilter               AddressFilter               // addresses of a successful verification must pass it (nil = no filter)
+	challengePreHashed          bool                        // the challenge is the hex encoded digest of the actual message
+	contractHashScheme          ContractHashScheme          // the hash passed to smart-contract wallets (default = HashSchemeERC191)
+	contractParallelism         int                         // max concurrent contract calls when verifying against multiple wallets
+	ignoreContractErrors        bool                        // errors of individual wallets don't fail verifications against multiple wallets
+	now                         func() time.Time            // the clock deadlines and expiries are enforced against (default = time.Now)
+	maxMultisigSignatures       int                         // max 65 bytes signatures within a concatenated multi-sig signature
+	legacyMetaMask              bool                        // also try the legacy MetaMask hex string interpretation of the challenge
+	proxyResolution             bool                        // retry failed contract calls against the EIP-1967 implementation
+	resultCache                 *resultCache                // caches verification results (nil = no caching)
+	tryBothParities             bool                        // retry EOA recovery with the flipped V parity
+	walletInterfaces            []WalletInterface           // the interfaces attempted, in order, for smart-contract wallets
+	challengeEncoding           ChallengeEncoding           // how the challenge argument encodes the signed message
+	contractSignatureForm       ContractSignatureForm       // the form of the signature passed to smart-contract wallets
+	ownerResolver               OwnerResolver               // resolves the owners of smart-contract wallets instead of calling them
+	ownersBlockNumber           *big.Int                    // the block the owners are resolved at (nil = latest)
+	addressNormalizer           AddressNormalizer           // maps address arguments to EVM addresses (nil = parsed as hex)
+	eip191Version               EIP191Version               // the EIP-191 version of the message signed by external wallets
+	eip191VersionData           []byte                      // the EIP-191 version data (derived for EIP191VersionPersonalSign)
+	challengeExpiryExtractor    ChallengeExpiryExtractor    // extracts the expiry of challenges (nil = challenges don't expire)
+	interfaceCache              *interfaceCache             // caches the wallet interface of smart-contract wallets (nil = no caching)
+	challengeNotBeforeExtractor ChallengeNotBeforeExtractor // extracts the not-before time of challenges (nil = valid immediately)
+	recoverFunc                 RecoverFunc                 // recovers addresses from signatures (nil = go-ethereum's secp256k1)
+	chainRegistry               *ChainRegistry              // the backends of the chains verified on by IsAuthorizedSignerOnChain
+	callCoalescer               *callCoalescer              // shares the results of identical contract calls (within a batch)
+	stateOverride               StateOverride               // the state overrides of contract calls (nil = none)
+	domainBinding               string                      // the domain signatures are bound to (empty = unbound)
+	eip5267Domain               bool                        // verify typed data against the EIP-712 domain reported by its verifying contract
+	recoveryIDOffset            int                         // subtracted from V of external wallet signatures before recovery
+	contractDigestExtra         []byte                      // mixed into the hash passed to smart-contract wallets (nil = none)
+	strictAddresses             bool                        // reject address arguments which aren't 20 bytes hex addresses
+	overallDeadline             time.Duration               // bounds each verification as a whole, including all its calls (0 = unbounded)
+	sessionKeySelector          [4]byte                     // the selector of the session key view of smart accounts
+	checksumMismatchHook        ChecksumMismatchHook        // called for hex address arguments which aren't EIP-55 checksummed (nil = none)
+	concurrentInterfaceProbe    bool                        // attempt the wallet interfaces concurrently rather than in order
+	walletNonceSelector         [4]byte                     // the selector of the nonce view of smart-contract wallets
+	challengeNonceExtractor     ChallengeNonceExtractor     // extracts the wallet nonce of challenges (nil = not checked)
+	lenientMagicValue           bool                        // check only the first 4 bytes of the return of isValidSignature
+	revertAsUnauthorized        bool                        // wallets reverting on every interface are unauthorized rather than unsupported
+	contractABI                 ethAbi.ABI                  // the ABI of the wallets' verification method (see WithContractABI)
+	contractABIMethod           string                      // the name of the wallets' verification method
+	rawRecoveryID               bool                        // V of external wallet signatures is already 0/1
+	addressBinding              bool                        // challenges must contain the claimed address
+	contractDigestBuilder       ContractDigestBuilder       // builds the digest signed under HashSchemeCustom
+	validatorModule             common.Address              // the ERC-7579 validator module smart accounts are routed to (zero = none)
+	personalMessageBytesMode    PersonalMessageBytesMode    // whether binary challenges are signed as is or as their hex string
+	upgradeRetries              int                         // re-probes of wallets reverting on every interface (0 = none)
+	upgradeRetryDelay           time.Duration               // the delay before each re-probe
+	messagePrefix               string                      // the expanded message prefix template of external wallets (empty = per EIP-191)
+	lowSPaths                   []Path                      // the paths high-S signatures are rejected on
+	pendingState                bool                        // contract calls resolve against the pending state rather than the latest block
+	resolveIsContract           bool                        // resolve Result.IsContract via CodeAt when the path didn't learn it (see Verify)
+	callBlock                   *big.Int                    // the block contract calls resolve against (nil = latest, see atBlock)
 }
 
 // NewAuthenticator creates a new Authenticator .
-func NewAuthenticator(ctx context.Context, cc bind.ContractCaller) *Authenticator {
-	return &Authenticator{
-		ctx: ctx,
-		cc:  cc,
+func NewAuthenticator(ctx context.Context, cc bind.ContractCaller, opts ...Option) *Authenticator {
+	a := &Authenticator{
+		ctx:                   ctx,
+		cc:                    cc,
+		decodeSignature:       identitySignatureDecoder,
+		contractParallelism:   defaultContractParallelism,
+		now:                   time.Now,
+		maxMultisigSignatures: defaultMaxMultisigSignatures,
+		walletInterfaces:      defaultWalletInterfaces,
+		eip191Version:         EIP191VersionPersonalSign,
+		sessionKeySelector:    DefaultSessionKeySelector,
+	}
+	for _, opt := range opts {
+		opt(a)
 	}
+	return a
 }
 
 // IsAuthorizedSigner implements the logic to check if an address is an authorized signer for a signature and challenge.
 func (a *Authenticator) IsAuthorizedSigner(challenge, signature, addrHex string) (bool, error) {
+	addr, err := a.normalizeAddress(addrHex)
+	if err != nil {
+		return false, err
+	}
 
-	addr := common.HexToAddress(addrHex)
-	origSigBytes := common.FromHex(signature)
+	return a.IsAuthorizedSignerAddr(challenge, signature, addr)
+}
 
-	adjSigBytes := make([]byte, len(origSigBytes))
-	copy(adjSigBytes, origSigBytes)
-	adjSigBytes[64] -= 27 // Transform V from 27/28 to 0/1 according to the yellow paper
+// IsAuthorizedSignerAddr is like IsAuthorizedSigner but accepts the address in its typed form.
+func (a *Authenticator) IsAuthorizedSignerAddr(challenge, signature string, addr common.Address) (bool, error) {
 
-	// retrieve public key from signature
-	var personalChallengeHash []byte
-	personalChallengeHash = personalMessageHash(challenge)
+	origSigBytes, err := a.signatureBytes(signature)
+	if err != nil {
+		return false, err
+	}
 
-	// error is expected when multi sig ("invalid signature length")
-	recoveredKey, err := ethCrypto.SigToPub(personalChallengeHash, adjSigBytes)
+	return a.isAuthorizedSigner(challenge, origSigBytes, addr)
+}
 
-	// procced with EOA check if no error
-	if err == nil {
-		recoveredAddress := ethCrypto.PubkeyToAddress(*recoveredKey)
+// IsAuthorizedSignerRSV is like IsAuthorizedSigner but accepts the signature as its separate r, s and v components
+// (v being 27/28), assembling the canonical r||s||v signature internally.
+func (a *Authenticator) IsAuthorizedSignerRSV(challenge string, r, s [32]byte, v byte, addrHex string) (bool, error) {
 
-		// try direct-keyed wallet
-		if bytes.Compare(addr.Bytes(), recoveredAddress.Bytes()) == 0 {
-			return true, nil
-		}
+	addr, err := a.normalizeAddress(addrHex)
+	if err != nil {
+		return false, err
 	}
 
-	// try smart-contract wallet
-	_ERC1271Caller, err := ERCs.NewERC1271Caller(addr, a.cc)
+	sig := make([]byte, 0, 65)
+	sig = append(sig, r[:]...)
+	sig = append(sig, s[:]...)
+	sig = append(sig, v)
+
+	return a.isAuthorizedSigner(challenge, sig, addr)
+}
+
+// DeriveAddress recovers the address of the EOA (external wallet) which signed the challenge via personal_sign.
+// Unlike IsAuthorizedSigner it doesn't verify against a known address, so a signature always derives some address;
+// use its checksummed Hex() to store it as the identity of the signer.
+func (a *Authenticator) DeriveAddress(challenge, signature string) (common.Address, error) {
+
+	if err := a.checkChallengeValidity(challenge); err != nil {
+		return common.Address{}, err
+	}
+
+	sigBytes, err := a.signatureBytes(signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	personalChallengeHash, err := a.personalChallengeHash(challenge)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	return a.recoverAddress(personalChallengeHash, a.eoaSignatureCandidates(sigBytes)[0])
+}
+
+// RecoveredSigner details the EOA (external wallet) recovered from a signature, for diagnosing key encoding mismatches.
+type RecoveredSigner struct {
+	Address             common.Address
+	PublicKey           *ecdsa.PublicKey
+	CompressedPublicKey []byte // the 33 bytes compressed form of PublicKey
+}
+
+// DeriveSigner is like DeriveAddress but also returns the public key recovered from the signature, along with its
+// compressed form. The public key is always recovered via go-ethereum's secp256k1, regardless of WithRecoverFunc.
+func (a *Authenticator) DeriveSigner(challenge, signature string) (*RecoveredSigner, error) {
+
+	if err := a.checkChallengeValidity(challenge); err != nil {
+		return nil, err
+	}
+
+	sigBytes, err := a.signatureBytes(signature)
+	if err != nil {
+		return nil, err
+	}
+
+	personalChallengeHash, err := a.personalChallengeHash(challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	recoveredPub, err := a.recoverPublicKey(personalChallengeHash, a.eoaSignatureCandidates(sigBytes)[0])
+	if err != nil {
+		return nil, err
+	}
+
+	pubKey, err := ethCrypto.UnmarshalPubkey(recoveredPub)
+	if err != nil {
+		return nil, fmt.Errorf("dappauth: recovering signer: %w", err)
+	}
+
+	return &RecoveredSigner{
+		Address:             ethCrypto.PubkeyToAddress(*pubKey),
+		PublicKey:           pubKey,
+		CompressedPublicKey: compressPubkey(recoveredPub),
+	}, nil
+}
+
+// SameSigner checks if both signatures of the challenge were signed via personal_sign by the same EOA (external
+// wallet), without exposing the address of either signer.
+func (a *Authenticator) SameSigner(challenge, sigA, sigB string) (bool, error) {
+	signerA, err := a.DeriveAddress(challenge, sigA)
+	if err != nil {
+		return false, err
+	}
+
+	signerB, err := a.DeriveAddress(challenge, sigB)
 	if err != nil {
 		return false, err
 	}
 
-	_ERC1271CallerSession := ERCs.ERC1271CallerSession{
-		Contract: _ERC1271Caller,
-		CallOpts: bind.CallOpts{
-			Pending: false,
-			Context: a.ctx,
-		},
+	return signerA == signerB, nil
+}
+
+// IsSignerPublicKey checks if the public key signed the challenge via personal_sign, comparing the full uncompressed
+// public key recovered from the signature rather than its address.
+func (a *Authenticator) IsSignerPublicKey(challenge, signature string, pubKey *ecdsa.PublicKey) (bool, error) {
+	if pubKey == nil || pubKey.X == nil || pubKey.Y == nil {
+		return false, nil
+	}
+
+	if err := a.checkChallengeValidity(challenge); err != nil {
+		return false, err
 	}
 
-	// we send just a regular hash, which then the smart contract hashes ontop to an erc191 hash
-	var challengeHash [32]byte
-	copy(challengeHash[:], ethCrypto.Keccak256([]byte(challenge)))
-	magicValue, err := _ERC1271CallerSession.IsValidSignature(challengeHash, origSigBytes)
+	sigBytes, err := a.signatureBytes(signature)
 	if err != nil {
 		return false, err
 	}
 
-	return magicValue == _ERC1271MagicValue, nil
+	personalChallengeHashes, err := a.personalChallengeHashes(challenge)
+	if err != nil {
+		return false, err
+	}
+
+	expectedPub := ethCrypto.FromECDSAPub(pubKey)
+	for _, personalChallengeHash := range personalChallengeHashes {
+		for _, eoaSigBytes := range a.eoaSignatureCandidates(sigBytes) {
+			recoveredPub, err := a.recoverPublicKey(personalChallengeHash, eoaSigBytes)
+			if err == nil && bytes.Equal(recoveredPub, expectedPub) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func (a *Authenticator) isAuthorizedSigner(challenge string, origSigBytes []byte, addr common.Address) (bool, error) {
+	return authorized(a.verify(challenge, origSigBytes, addr))
+}
+
+func (a *Authenticator) verify(challenge string, origSigBytes []byte, addr common.Address) (*Result, error) {
+	// checked ahead of the cache, as cached results outlive the challenge
+	if err := a.checkChallengeValidity(challenge); err != nil {
+		return nil, err
+	}
+	if err := a.checkAddressBinding(challenge, addr); err != nil {
+		return nil, err
+	}
+
+	if a.overallDeadline <= 0 {
+		return a.verifyCached(challenge, origSigBytes, addr)
+	}
+
+	ctx, cancel := context.WithTimeout(a.context(), a.overallDeadline)
+	defer cancel()
+
+	budgeted := *a
+	budgeted.ctx = ctx
+
+	result, err := budgeted.verifyCached(challenge, origSigBytes, addr)
+	if err != nil && ctx.Err() == context.DeadlineExceeded && a.context().Err() == nil {
+		return nil, ErrDeadlineExceeded
+	}
+	return result, err
+}
+
+func (a *Authenticator) verifyUncached(challenge string, origSigBytes []byte, addr common.Address) (*Result, error) {
+	result, err := a.verifyPaths(challenge, origSigBytes, addr)
+	if err == nil && result.IsContract == nil && a.resolveIsContract {
+		result.IsContract = a.isContract(addr)
+	}
+	return result, err
+}
+
+func (a *Authenticator) verifyPaths(challenge string, origSigBytes []byte, addr common.Address) (*Result, error) {
+
+	// counterfactual smart-contract wallet
+	if IsERC6492Signature(origSigBytes) {
+		return a.verifyERC6492Signature(challenge, origSigBytes, addr)
+	}
+
+	// try direct-keyed wallet
+	if result, err := a.verifyEOA(challenge, origSigBytes, addr); result != nil || err != nil {
+		return result, err
+	}
+
+	// try smart-contract wallet
+	result, err := a.verifyContractSigner(challenge, origSigBytes, addr)
+	if err == nil && !result.Authorized {
+		// the EOA the signature recovers to, for debugging mismatches
+		if personalChallengeHash, err := a.personalChallengeHash(challenge); err == nil {
+			result.RecoveredAddress, _ = a.recoverAddress(personalChallengeHash, a.eoaSignatureCandidates(origSigBytes)[0])
+		}
+	}
+	return result, err
+}
+
+// verifyEOA verifies the signature of an external wallet, returning a nil result if it doesn't recover to the address.
+func (a *Authenticator) verifyEOA(challenge string, origSigBytes []byte, addr common.Address) (*Result, error) {
+
+	personalChallengeHashes, err := a.personalChallengeHashes(challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, personalChallengeHash := range personalChallengeHashes {
+		for _, eoaSigBytes := range a.eoaSignatureCandidates(origSigBytes) {
+			// error is expected when multi sig ("invalid signature length")
+			recoveredAddress, err := a.recoverAddress(personalChallengeHash, eoaSigBytes)
+
+			// procced with EOA check if no error
+			if err == nil && bytes.Compare(addr.Bytes(), recoveredAddress.Bytes()) == 0 {
+				if a.enforcesLowS(PathEOA) && hasHighS(eoaSigBytes) {
+					return nil, ErrHighS
+				}
+				return a.authorize(&Result{Path: PathEOA, RecoveredAddress: recoveredAddress, Digest: common.BytesToHash(personalChallengeHash)}, addr)
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+func (a *Authenticator) isAuthorizedContractSigner(challenge string, origSigBytes []byte, addr common.Address) (bool, error) {
+	return authorized(a.verifyContractSigner(challenge, origSigBytes, addr))
+}
+
+func (a *Authenticator) verifyContractSigner(challenge string, origSigBytes []byte, addr common.Address) (*Result, error) {
+
+	// by default we send just a regular hash, which then the smart contract hashes ontop to an erc191 hash
+	challengeHash, err := a.contractHash(challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := a.challengeMessage(challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.checkWalletNonce(challenge, addr); err != nil {
+		return nil, err
+	}
+
+	return a.verifyContractHash(challengeHash, message, origSigBytes, addr)
+}
+
+// verifyContractHash verifies the signature via isValidSignature of the smart-contract wallet. message is the
+// challenge message the hash was derived from (nil = the hash itself) for wallet interfaces taking the data.
+func (a *Authenticator) verifyContractHash(challengeHash [32]byte, message, origSigBytes []byte, addr common.Address) (*Result, error) {
+
+	if a.enforcesLowS(PathContract) && hasHighS(origSigBytes) {
+		return nil, ErrHighS
+	}
+
+	if a.ownerResolver != nil {
+		return a.verifyOwners(challengeHash, origSigBytes, addr)
+	}
+
+	blockNumber := a.callBlockNumber()
+	pinned := a.atBlock(blockNumber)
+
+	contractSigBytes := a.routeToValidatorModule(a.contractSignature(origSigBytes, a.signedContractHash(challengeHash[:], addr)))
+	isValid, gasUsed, err := pinned.isValidSignature(addr, challengeHash, message, contractSigBytes)
+	if err != nil && a.proxyResolution {
+		isValid, gasUsed, err = pinned.isValidSignatureAtImplementation(addr, challengeHash, message, contractSigBytes, err)
+	}
+	if err != nil {
+		return nil, wrapError("contract wallet", addr, err)
+	}
+
+	// the wallet answered (or was found to have code), so it has code
+	isContract := true
+	result := &Result{
+		Path:         PathContract,
+		InnerSigners: a.recoverInnerSigners(challengeHash[:], origSigBytes, addr),
+		GasUsed:      gasUsed,
+		Digest:       common.BytesToHash(a.signedContractHash(challengeHash[:], addr)),
+		BlockNumber:  blockNumber,
+		IsContract:   &isContract,
+	}
+
+	if !isValid {
+		return result, nil
+	}
+
+	return a.authorize(result, append([]common.Address{addr}, result.InnerSigners...)...)
+}
+
+// recoverAddress recovers the address of the EOA which signed the hash, V being 27/28.
+func (a *Authenticator) recoverAddress(hash, sig []byte) (common.Address, error) {
+	if a.recoverFunc != nil {
+		return a.recoverAddressWith(a.recoverFunc, hash, sig)
+	}
+
+	recoveredPub, err := a.recoverPublicKey(hash, sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	// derive the address from the uncompressed public key directly, rather than via an ecdsa.PublicKey
+	return common.BytesToAddress(ethCrypto.Keccak256(recoveredPub[1:])[12:]), nil
+}
+
+func (a *Authenticator) recoverAddressWith(recoverFn RecoverFunc, hash, sig []byte) (common.Address, error) {
+	if len(sig) != 65 {
+		return common.Address{}, ErrInvalidSignatureLength
+	}
+
+	var adjSigBytes [65]byte
+	copy(adjSigBytes[:], sig)
+	adjSigBytes[64] -= 27 // Transform V from 27/28 to 0/1 according to the yellow paper
+
+	if err := validateSignatureValues(sig[:32], sig[32:64]); err != nil {
+		return common.Address{}, err
+	}
+
+	recoveredAddress, err := recoverFn(hash, adjSigBytes[:])
+	if err != nil {
+		return common.Address{}, fmt.Errorf("dappauth: recovering signer: %w", err)
+	}
+	return recoveredAddress, nil
+}
+
+// recoverPublicKey recovers the uncompressed (65 bytes) public key of the EOA which signed the hash, V being 27/28.
+func (a *Authenticator) recoverPublicKey(hash, sig []byte) ([]byte, error) {
+	if len(sig) != 65 {
+		return nil, ErrInvalidSignatureLength
+	}
+
+	var adjSigBytes [65]byte
+	copy(adjSigBytes[:], sig)
+	adjSigBytes[64] -= 27 // Transform V from 27/28 to 0/1 according to the yellow paper
+
+	if err := validateSignatureValues(sig[:32], sig[32:64]); err != nil {
+		return nil, err
+	}
+
+	recoveredPub, err := ethCrypto.Ecrecover(hash, adjSigBytes[:])
+	if err != nil {
+		return nil, fmt.Errorf("dappauth: recovering signer: %w", err)
+	}
+	return recoveredPub, nil
+}
+
+// eoaSignatureCandidates returns the signatures to attempt EOA recovery with: the signature itself (with the recovery
+// id offset removed), followed by the signature with the flipped V parity if enabled.
+func (a *Authenticator) eoaSignatureCandidates(sig []byte) [][]byte {
+	offset := a.recoveryIDOffset
+	if a.rawRecoveryID {
+		offset -= 27 // V is already 0/1
+	}
+	if offset != 0 && len(sig) == 65 {
+		adjusted := make([]byte, len(sig))
+		copy(adjusted, sig)
+		adjusted[64] = byte(int(sig[64]) - offset)
+		sig = adjusted
+	}
+
+	candidates := [][]byte{sig}
+	if a.tryBothParities && len(sig) == 65 && (sig[64] == 27 || sig[64] == 28) {
+		flipped := make([]byte, len(sig))
+		copy(flipped, sig)
+		flipped[64] = 55 - sig[64] // 27 <-> 28
+		candidates = append(candidates, flipped)
+	}
+	return candidates
+}
+
+// recoverInnerSigners makes a best-effort recovery of the EOAs which signed on behalf of a smart-contract wallet,
+// assuming the wallet verifies each 65 bytes chunk of the signature according to the contract hash scheme.
+func (a *Authenticator) recoverInnerSigners(challengeHash, sig []byte, addr common.Address) []common.Address {
+	if len(sig)%65 != 0 {
+		return nil
+	}
+
+	signedHash := a.signedContractHash(challengeHash, addr)
+
+	var signers []common.Address
+	for _, chunk := range chunk65Bytes(sig) {
+		signer, err := a.recoverAddress(signedHash, chunk[:])
+		if err == nil {
+			signers = append(signers, signer)
+		}
+	}
+	return signers
+}
+
+// authorize applies the address filter to the addresses taking part in a successful verification,
+// marking the result as authorized if they all pass.
+func (a *Authenticator) authorize(result *Result, addrs ...common.Address) (*Result, error) {
+	if a.addressFilter != nil {
+		for _, addr := range addrs {
+			if !a.addressFilter(addr) {
+				return nil, wrapError("address", addr, ErrAddressFiltered)
+			}
+		}
+	}
+
+	result.Authorized = true
+	return result, nil
+}
+
+// Ping checks that the contract backend is reachable, by making a cheap call to it.
+func (a *Authenticator) Ping(ctx context.Context) error {
+	if a.cc == nil {
+		return ErrNoBackend
+	}
+	if _, err := a.cc.CodeAt(ctx, common.Address{}, nil); err != nil {
+		return fmt.Errorf("dappauth: backend unreachable: %w", err)
+	}
+	return nil
+}
+
+// normalizeAddress maps the address argument to an EVM address per the address normalizer.
+func (a *Authenticator) normalizeAddress(addr string) (common.Address, error) {
+	if a.addressNormalizer == nil {
+		if a.strictAddresses && !common.IsHexAddress(addr) {
+			if strings.Contains(addr, ".") {
+				return common.Address{}, fmt.Errorf("%w: %q", ErrUnresolvedName, addr)
+			}
+			return common.Address{}, fmt.Errorf("%w: %q", ErrInvalidAddress, addr)
+		}
+		parsed := hexToAddress(addr)
+		if a.checksumMismatchHook != nil && !isChecksummed(addr, parsed) {
+			a.checksumMismatchHook(addr, parsed)
+		}
+		return parsed, nil
+	}
+
+	normalized, err := a.addressNormalizer(addr)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("dappauth: normalizing address %q: %w", addr, err)
+	}
+	return normalized, nil
+}
+
+// isChecksummed returns true if the hex address argument is the EIP-55 checksummed hex of the address, 0x prefixed or not.
+func isChecksummed(addr string, parsed common.Address) bool {
+	return strings.TrimPrefix(addr, "0x") == parsed.Hex()[2:]
+}
+
+// hexToAddress is common.HexToAddress, decoding well-formed addresses without allocating.
+func hexToAddress(s string) common.Address {
+	hexAddr := s
+	if len(hexAddr) >= 2 && hexAddr[0] == '0' && (hexAddr[1] == 'x' || hexAddr[1] == 'X') {
+		hexAddr = hexAddr[2:]
+	}
+
+	var addr common.Address
+	if len(hexAddr) != 2*common.AddressLength {
+		return common.HexToAddress(s)
+	}
+	for i := range addr {
+		hi, okHi := fromHexChar(hexAddr[2*i])
+		lo, okLo := fromHexChar(hexAddr[2*i+1])
+		if !okHi || !okLo {
+			return common.HexToAddress(s)
+		}
+		addr[i] = hi<<4 | lo
+	}
+	return addr
+}
+
+func fromHexChar(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// signatureBytes decodes the hex encoded signature and applies the signature decoder.
+func (a *Authenticator) signatureBytes(signature string) ([]byte, error) {
+	sigBytes, err := a.decodeSignature(common.FromHex(signature))
+	if err != nil {
+		return nil, fmt.Errorf("dappauth: decoding signature: %w", err)
+	}
+
+	// bound the work on untrusted input before any recovery
+	countedSigBytes := sigBytes
+	if IsERC6492Signature(sigBytes) {
+		if _, _, originalSignature, err := unwrapERC6492Signature(sigBytes); err == nil {
+			countedSigBytes = originalSignature
+		}
+	}
+	if (len(countedSigBytes)+64)/65 > a.maxMultisigSignatures {
+		return nil, ErrTooManySignatures
+	}
+
+	return sigBytes, nil
+}
+
+// wrapError annotates err with the verification path and the address it occurred for.
+func wrapError(path string, addr common.Address, err error) error {
+	return fmt.Errorf("dappauth: %s %s: %w", path, addr.Hex(), err)
+}
+
+// callOpts returns the options of the calls of contract bindings made via boundCaller, which resolves the pending
+// state and the pinned block itself.
+func (a *Authenticator) callOpts() bind.CallOpts {
+	return bind.CallOpts{
+		From:    a.callFrom,
+		Context: a.context(),
+	}
 }
 
 func personalMessageHash(message string) []byte {
-	msg := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(message), message)
-	return ethCrypto.Keccak256([]byte(msg))
+	return eip191Hash(EIP191VersionPersonalSign, nil, []byte(message))
+}
+
+func erc191MessageHash(msg []byte, address common.Address) []byte {
+	return eip191Hash(EIP191VersionDataWithValidator, address.Bytes(), msg)
+}
+
+func chunk65Bytes(b []byte) [][65]byte {
+	chunkSize := 65
+	var chunks [][65]byte
+
+	for i := 0; i < len(b); i += chunkSize {
+		end := i + chunkSize
+
+		if end > len(b) {
+			end = len(b)
+		}
+
+		var chunk [65]byte
+		copy(chunk[:], b[i:end])
+
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks
 }
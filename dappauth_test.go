@@ -1,8 +1,13 @@
 package dappauth
 
 import (
+	"bytes"
+	"context"
 	"crypto/ecdsa"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -196,3 +201,394 @@ func expectBool(actual, expected bool, t *testing.T) {
 		t.Errorf("expected %v to be %v", actual, expected)
 	}
 }
+
+func TestDappAuthRSV(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrB := ethCrypto.PubkeyToAddress(keyB.PublicKey)
+
+	rsvTests := []struct {
+		title    string
+		isEOA    bool
+		key      *ecdsa.PrivateKey
+		authAddr common.Address
+		mock     *mockContract
+	}{
+		{
+			title:    "External wallets should agree with the concatenated signature",
+			isEOA:    true,
+			key:      keyA,
+			authAddr: addrA,
+			mock:     &mockContract{},
+		},
+		{
+			title:    "External wallets over OTHER addresses should agree with the concatenated signature",
+			isEOA:    true,
+			key:      keyA,
+			authAddr: addrB,
+			mock:     &mockContract{},
+		},
+		{
+			title:    "Smart-contract wallets should agree with the concatenated signature",
+			isEOA:    false,
+			key:      keyB,
+			authAddr: addrA,
+			mock:     &mockContract{address: addrA, authorizedKey: &keyB.PublicKey},
+		},
+	}
+
+	for _, test := range rsvTests {
+		t.Run(test.title, func(t *testing.T) {
+			authenticator := NewAuthenticator(nil, test.mock)
+
+			sig := generateSignature(test.isEOA, "foo", test.key, test.authAddr, t)
+			sigBytes := common.FromHex(sig)
+
+			var r, s [32]byte
+			copy(r[:], sigBytes[:32])
+			copy(s[:], sigBytes[32:64])
+
+			expected, err := authenticator.IsAuthorizedSigner("foo", sig, test.authAddr.Hex())
+			checkError(err, t)
+			actual, err := authenticator.IsAuthorizedSignerRSV("foo", r, s, sigBytes[64], test.authAddr.Hex())
+			checkError(err, t)
+
+			expectBool(actual, expected, t)
+		})
+	}
+}
+
+func TestPersonalMessagePrefix(t *testing.T) {
+	expected := ethCrypto.Keccak256([]byte("\x19Ethereum Signed Message:\n3foo"))
+	expectBool(bytes.Equal(personalMessageHash("foo"), expected), true, t)
+}
+
+func TestPing(t *testing.T) {
+	authenticator := NewAuthenticator(nil, &mockContract{})
+	checkError(authenticator.Ping(context.Background()), t)
+
+	authenticator = NewAuthenticator(nil, &mockContract{errorCodeAt: true})
+	expectBool(authenticator.Ping(context.Background()) != nil, true, t)
+}
+
+func TestDeriveAddress(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	authenticator := NewAuthenticator(nil, &mockContract{})
+
+	t.Run("The derived address should be the signing key's address", func(t *testing.T) {
+		addr, err := authenticator.DeriveAddress("foo", signEOAPersonalMessage("foo", keyA, t))
+		checkError(err, t)
+		expectBool(addr == ethCrypto.PubkeyToAddress(keyA.PublicKey), true, t)
+		expectBool(addr.Hex() == ethCrypto.PubkeyToAddress(keyA.PublicKey).Hex(), true, t)
+	})
+
+	t.Run("The derived address should NOT be the signing key's address for the wrong challenge", func(t *testing.T) {
+		addr, err := authenticator.DeriveAddress("foo", signEOAPersonalMessage("bar", keyB, t))
+		checkError(err, t)
+		expectBool(addr == ethCrypto.PubkeyToAddress(keyB.PublicKey), false, t)
+	})
+
+	t.Run("Deriving from a multi-sig signature should error", func(t *testing.T) {
+		_, err := authenticator.DeriveAddress("foo", signEOAPersonalMessage("foo", keyA, t)+signEOAPersonalMessage("foo", keyB, t))
+		expectBool(err != nil, true, t)
+	})
+
+	t.Run("The derived address should be the signing key's address for many keys", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			key, err := ethCrypto.GenerateKey()
+			checkError(err, t)
+
+			addr, err := authenticator.DeriveAddress("foo", signEOAPersonalMessage("foo", key, t))
+			checkError(err, t)
+			expectBool(addr == ethCrypto.PubkeyToAddress(key.PublicKey), true, t)
+		}
+	})
+
+	t.Run("The derived address should be the signing key's address for keys with leading-zero components", func(t *testing.T) {
+		for _, key := range leadingZeroKeys(t) {
+			addr, err := authenticator.DeriveAddress("foo", signEOAPersonalMessage("foo", key, t))
+			checkError(err, t)
+			expectBool(addr == ethCrypto.PubkeyToAddress(key.PublicKey), true, t)
+
+			isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", signEOAPersonalMessage("foo", key, t), addr.Hex())
+			checkError(err, t)
+			expectBool(isAuthorizedSigner, true, t)
+		}
+	})
+}
+
+func TestDeriveSigner(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	authenticator := NewAuthenticator(nil, &mockContract{})
+
+	t.Run("The derived public key should be the signing key's public key", func(t *testing.T) {
+		signer, err := authenticator.DeriveSigner("foo", signEOAPersonalMessage("foo", keyA, t))
+		checkError(err, t)
+		expectBool(signer.Address == ethCrypto.PubkeyToAddress(keyA.PublicKey), true, t)
+		expectBool(signer.PublicKey.X.Cmp(keyA.PublicKey.X) == 0 && signer.PublicKey.Y.Cmp(keyA.PublicKey.Y) == 0, true, t)
+		expectBool(bytes.Equal(signer.CompressedPublicKey, ethCrypto.CompressPubkey(&keyA.PublicKey)), true, t)
+	})
+
+	t.Run("The derived public key should be the signing key's public key for keys with leading-zero components", func(t *testing.T) {
+		for _, key := range leadingZeroKeys(t) {
+			signer, err := authenticator.DeriveSigner("foo", signEOAPersonalMessage("foo", key, t))
+			checkError(err, t)
+			expectBool(bytes.Equal(ethCrypto.FromECDSAPub(signer.PublicKey), ethCrypto.FromECDSAPub(&key.PublicKey)), true, t)
+			expectBool(bytes.Equal(signer.CompressedPublicKey, ethCrypto.CompressPubkey(&key.PublicKey)), true, t)
+		}
+	})
+
+	t.Run("The derived public key should NOT be the signing key's public key for the wrong challenge", func(t *testing.T) {
+		signer, err := authenticator.DeriveSigner("foo", signEOAPersonalMessage("bar", keyB, t))
+		checkError(err, t)
+		expectBool(signer.Address == ethCrypto.PubkeyToAddress(keyB.PublicKey), false, t)
+		expectBool(bytes.Equal(signer.CompressedPublicKey, ethCrypto.CompressPubkey(&keyB.PublicKey)), false, t)
+	})
+
+	t.Run("Deriving from a multi-sig signature should error", func(t *testing.T) {
+		_, err := authenticator.DeriveSigner("foo", signEOAPersonalMessage("foo", keyA, t)+signEOAPersonalMessage("foo", keyB, t))
+		expectBool(err != nil, true, t)
+	})
+}
+
+// leadingZeroKeys deterministically derives keys whose private key, public X and public Y (each) have a leading zero byte.
+func leadingZeroKeys(t *testing.T) []*ecdsa.PrivateKey {
+	var keys []*ecdsa.PrivateKey
+	var zeroD, zeroX, zeroY bool
+
+	seed := []byte("dappauth")
+	for i := 0; i < 100000 && !(zeroD && zeroX && zeroY); i++ {
+		seed = ethCrypto.Keccak256(seed)
+		key, err := ethCrypto.ToECDSA(seed)
+		checkError(err, t)
+
+		switch {
+		case !zeroD && seed[0] == 0:
+			zeroD = true
+		case !zeroX && len(key.PublicKey.X.Bytes()) < 32:
+			zeroX = true
+		case !zeroY && len(key.PublicKey.Y.Bytes()) < 32:
+			zeroY = true
+		default:
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	if !(zeroD && zeroX && zeroY) {
+		t.Fatal("no keys with leading-zero components")
+	}
+	return keys
+}
+
+func TestSameSigner(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	authenticator := NewAuthenticator(nil, &mockContract{})
+	sigA := signEOAPersonalMessage("foo", keyA, t)
+
+	signerTests := []struct {
+		title    string
+		sigB     string
+		expected bool
+	}{
+		{"Signatures of the same key should be of the same signer", signEOAPersonalMessage("foo", keyA, t), true},
+		{"Signatures of different keys should NOT be of the same signer", signEOAPersonalMessage("foo", keyB, t), false},
+		{"Signatures of the same key over another challenge should NOT be of the same signer", signEOAPersonalMessage("bar", keyA, t), false},
+	}
+
+	for _, test := range signerTests {
+		t.Run(test.title, func(t *testing.T) {
+			sameSigner, err := authenticator.SameSigner("foo", sigA, test.sigB)
+			checkError(err, t)
+			expectBool(sameSigner, test.expected, t)
+		})
+	}
+
+	t.Run("Unrecoverable signatures should error", func(t *testing.T) {
+		_, err := authenticator.SameSigner("foo", sigA, sigA+sigA)
+		expectBool(err != nil, true, t)
+	})
+}
+
+func TestDappAuthAddr(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrB := ethCrypto.PubkeyToAddress(keyB.PublicKey)
+	authenticator := NewAuthenticator(nil, &mockContract{})
+	sig := signEOAPersonalMessage("foo", keyA, t)
+
+	for _, addr := range []common.Address{addrA, addrB} {
+		expected, err := authenticator.IsAuthorizedSigner("foo", sig, addr.Hex())
+		checkError(err, t)
+		actual, err := authenticator.IsAuthorizedSignerAddr("foo", sig, addr)
+		checkError(err, t)
+
+		expectBool(actual, expected, t)
+		expectBool(actual, addr == addrA, t)
+	}
+}
+
+func TestErrorWrapping(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	errDecoder := errors.New("decoder error")
+
+	wrappingTests := []struct {
+		title         string
+		mockContract  *mockContract
+		opts          []Option
+		challenge     string
+		signature     string
+		expectedError error
+	}{
+		{
+			title:         "Smart-contract call errors should be reachable through wrapping",
+			mockContract:  &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, errorIsValidSignature: true},
+			challenge:     "foo",
+			signature:     signERC1654PersonalMessage("foo", keyB, addrA, t),
+			expectedError: errDummy,
+		},
+		{
+			title:         "Address filter errors should be reachable through wrapping",
+			mockContract:  &mockContract{address: addrA},
+			opts:          []Option{WithAddressFilter(func(common.Address) bool { return false })},
+			challenge:     "foo",
+			signature:     signEOAPersonalMessage("foo", keyA, t),
+			expectedError: ErrAddressFiltered,
+		},
+		{
+			title:         "Challenge errors should be reachable through wrapping",
+			mockContract:  &mockContract{address: addrA},
+			opts:          []Option{WithChallengePreHashed(true)},
+			challenge:     "foo",
+			signature:     signEOAPersonalMessage("foo", keyA, t),
+			expectedError: ErrInvalidChallengeDigest,
+		},
+		{
+			title:         "Signature decoder errors should be reachable through wrapping",
+			mockContract:  &mockContract{address: addrA},
+			opts:          []Option{WithSignatureDecoder(func([]byte) ([]byte, error) { return nil, errDecoder })},
+			challenge:     "foo",
+			signature:     signEOAPersonalMessage("foo", keyA, t),
+			expectedError: errDecoder,
+		},
+	}
+
+	for _, test := range wrappingTests {
+		t.Run(test.title, func(t *testing.T) {
+			authenticator := NewAuthenticator(nil, test.mockContract, test.opts...)
+
+			_, err := authenticator.IsAuthorizedSigner(test.challenge, test.signature, addrA.Hex())
+			err = fmt.Errorf("req %s: %w", "42", err)
+			expectBool(errors.Is(err, test.expectedError), true, t)
+		})
+	}
+
+	t.Run("Contract wallet errors should include the address", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{address: addrA, errorIsValidSignature: true})
+
+		_, err := authenticator.IsAuthorizedSigner("foo", signERC1654PersonalMessage("foo", keyB, addrA, t), addrA.Hex())
+		expectBool(strings.Contains(err.Error(), addrA.Hex()), true, t)
+	})
+}
+
+func TestNilBackend(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	authenticator := NewAuthenticator(nil, nil, WithProxyResolution(true), WithResultCache(0))
+
+	t.Run("External wallets should be authorized signers without a backend", func(t *testing.T) {
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", signEOAPersonalMessage("foo", keyA, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	t.Run("Signatures needing a contract call should error with ErrNoBackend", func(t *testing.T) {
+		_, err := authenticator.IsAuthorizedSigner("foo", signERC1654PersonalMessage("foo", keyB, addrA, t), addrA.Hex())
+		expectBool(errors.Is(err, ErrNoBackend), true, t)
+	})
+
+	t.Run("Ping should error with ErrNoBackend", func(t *testing.T) {
+		expectBool(authenticator.Ping(context.Background()) == ErrNoBackend, true, t)
+	})
+}
+
+func TestHexToAddress(t *testing.T) {
+	for _, s := range []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+		"0X5AAEB6053F3E94C9B9A09F33669435E7EF1BEAED",
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAe",
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAgd",
+		"0x",
+		"",
+	} {
+		expectBool(hexToAddress(s) == common.HexToAddress(s), true, t)
+	}
+}
+
+func TestIsSignerPublicKey(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	authenticator := NewAuthenticator(nil, &mockContract{})
+	sig := signEOAPersonalMessage("foo", keyA, t)
+
+	publicKeyTests := []struct {
+		title     string
+		challenge string
+		pubKey    *ecdsa.PublicKey
+		expected  bool
+	}{
+		{"The signing public key should be the signer", "foo", &keyA.PublicKey, true},
+		{"Another public key should NOT be the signer", "foo", &keyB.PublicKey, false},
+		{"The signing public key should NOT be the signer of another challenge", "bar", &keyA.PublicKey, false},
+		{"A nil public key should NOT be the signer", "foo", nil, false},
+	}
+
+	for _, test := range publicKeyTests {
+		t.Run(test.title, func(t *testing.T) {
+			isSigner, err := authenticator.IsSignerPublicKey(test.challenge, sig, test.pubKey)
+			checkError(err, t)
+			expectBool(isSigner, test.expected, t)
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package dappauth
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// EIP191Version is the version byte of EIP-191 signed data, following the 0x19 prefix.
+type EIP191Version byte
+
+const (
+	// EIP191VersionDataWithValidator (0x00) is data with an intended validator, the version data being the validator's address.
+	EIP191VersionDataWithValidator EIP191Version = 0x00
+	// EIP191VersionStructuredData (0x01) is EIP-712 structured data, the version data being the domain separator.
+	EIP191VersionStructuredData EIP191Version = 0x01
+	// EIP191VersionPersonalSign (0x45) is personal_sign, the version data being "thereum Signed Message:\n" followed by
+	// the length of the message.
+	EIP191VersionPersonalSign EIP191Version = 0x45
+)
+
+// the personal message prefix past 0x19 and the version byte ('E' = 0x45)
+var _personalSignVersionData = PersonalMessagePrefix[2:]
+
+// preallocated to keep hashing allocation-free, as it is on the hot path
+var (
+	_eip191Prefixes = func() (prefixes [256][2]byte) {
+		for version := range prefixes {
+			prefixes[version] = [2]byte{0x19, byte(version)}
+		}
+		return prefixes
+	}()
+	_personalSignVersionDataBytes = []byte(_personalSignVersionData)
+)
+
+// WithEIP191Version sets the EIP-191 version (and its version data) of the message signed by external wallets
+// (default = EIP191VersionPersonalSign). The version data of EIP191VersionPersonalSign is derived from the message.
+func WithEIP191Version(version EIP191Version, versionData []byte) Option {
+	return func(a *Authenticator) {
+		a.eip191Version = version
+		a.eip191VersionData = versionData
+	}
+}
+
+// Substitution tokens of message prefix templates (see WithMessagePrefix).
+const (
+	MessagePrefixTokenChainID   = "{chainId}"   // the decimal chain ID
+	MessagePrefixTokenChainName = "{chainName}" // the chain name
+	MessagePrefixTokenLength    = "{length}"    // the decimal length of the message, in bytes
+)
+
+// WithMessagePrefix replaces the personal message prefix of external wallets with the template, for wallets (e.g. of
+// app-chains) whose prefix differs from PersonalMessagePrefix, e.g. "\x19{chainName} Signed Message:\n{length}".
+// MessagePrefixTokenChainID and MessagePrefixTokenChainName are substituted with the chain ID (empty if nil) and name.
+// MessagePrefixTokenLength is substituted with the length of each message; templates without it are followed by the
+// length, as is PersonalMessagePrefix. The wallets sign keccak256(prefix ‖ message), regardless of WithEIP191Version.
+func WithMessagePrefix(template string, chainID *big.Int, chainName string) Option {
+	return func(a *Authenticator) {
+		id := ""
+		if chainID != nil {
+			id = chainID.String()
+		}
+		a.messagePrefix = strings.NewReplacer(MessagePrefixTokenChainID, id, MessagePrefixTokenChainName, chainName).Replace(template)
+		if !strings.Contains(a.messagePrefix, MessagePrefixTokenLength) {
+			a.messagePrefix += MessagePrefixTokenLength
+		}
+	}
+}
+
+// EIP191Hash returns the digest of the EIP-191 signed data: keccak256(0x19 ‖ version ‖ versionData ‖ message).
+// The version data of EIP191VersionPersonalSign is derived from the message (versionData is ignored).
+func EIP191Hash(version EIP191Version, versionData, message []byte) [32]byte {
+	var hash [32]byte
+	copy(hash[:], eip191Hash(version, versionData, message))
+	return hash
+}
+
+func eip191Hash(version EIP191Version, versionData, message []byte) []byte {
+	if version == EIP191VersionPersonalSign {
+		var length [20]byte
+		return ethCrypto.Keccak256(_eip191Prefixes[version][:], _personalSignVersionDataBytes, strconv.AppendInt(length[:0], int64(len(message)), 10), message)
+	}
+	return ethCrypto.Keccak256(_eip191Prefixes[version][:], versionData, message)
+}
+
+// eip191Message returns the EIP-191 signed data (the preimage of eip191Hash).
+func eip191Message(version EIP191Version, versionData, message []byte) []byte {
+	if version == EIP191VersionPersonalSign {
+		versionData = append([]byte(_personalSignVersionData), strconv.Itoa(len(message))...)
+	}
+
+	msg := append([]byte{0x19, byte(version)}, versionData...)
+	return append(msg, message...)
+}
+
+// signedMessageHash returns the digest an external wallet signs over the message, per the message prefix if set or
+// the EIP-191 version otherwise.
+func (a *Authenticator) signedMessageHash(message []byte) []byte {
+	if a.messagePrefix != "" {
+		return ethCrypto.Keccak256(a.expandMessagePrefix(message), message)
+	}
+	return eip191Hash(a.eip191Version, a.eip191VersionData, message)
+}
+
+// signedMessage returns the data an external wallet signs over the message (the preimage of signedMessageHash).
+func (a *Authenticator) signedMessage(message []byte) []byte {
+	if a.messagePrefix != "" {
+		return append(a.expandMessagePrefix(message), message...)
+	}
+	return eip191Message(a.eip191Version, a.eip191VersionData, message)
+}
+
+// expandMessagePrefix returns the message prefix with the length of the message in place of its length token.
+func (a *Authenticator) expandMessagePrefix(message []byte) []byte {
+	return []byte(strings.Replace(a.messagePrefix, MessagePrefixTokenLength, strconv.Itoa(len(message)), -1))
+}
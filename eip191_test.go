@@ -0,0 +1,135 @@
+package dappauth
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestEIP191Hash(t *testing.T) {
+
+	validator := common.HexToAddress("0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC")
+
+	vectorTests := []struct {
+		title       string
+		version     EIP191Version
+		versionData []byte
+		message     []byte
+		expected    string
+	}{
+		{
+			// keccak256(0x19 ‖ 0x00 ‖ validator ‖ "hello")
+			"The 0x00 digest should match the reference vector",
+			EIP191VersionDataWithValidator,
+			validator.Bytes(),
+			[]byte("hello"),
+			common.ToHex(ethCrypto.Keccak256(append(append([]byte{0x19, 0x00}, validator.Bytes()...), "hello"...))),
+		},
+		{
+			// the Mail example of EIP-712
+			"The 0x01 digest should match the reference vector",
+			EIP191VersionStructuredData,
+			common.FromHex("0xf2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f"),
+			common.FromHex("0xc52c0ee5d84264471806290a3f2c4cecfc5490626bf912d01f240d7a274b371e"),
+			"0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2",
+		},
+		{
+			// personal_sign("hello")
+			"The 0x45 digest should match the reference vector",
+			EIP191VersionPersonalSign,
+			nil,
+			[]byte("hello"),
+			"0x50b2c43fd39106bafbba0da34fc430e1f91e3c96ea2acee2bc34119f92b37750",
+		},
+	}
+
+	for _, test := range vectorTests {
+		t.Run(test.title, func(t *testing.T) {
+			hash := EIP191Hash(test.version, test.versionData, test.message)
+			expectBool(common.ToHex(hash[:]) == test.expected, true, t)
+			expectBool(hash == ethCrypto.Keccak256Hash(eip191Message(test.version, test.versionData, test.message)), true, t)
+		})
+	}
+}
+
+func TestEIP191Version(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	validator := common.HexToAddress("0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC")
+	hash := EIP191Hash(EIP191VersionDataWithValidator, validator.Bytes(), []byte("foo"))
+	sig := signRawHash(hash[:], keyA, t)
+
+	t.Run("External wallets should be authorized signers under the configured EIP-191 version", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{}, WithEIP191Version(EIP191VersionDataWithValidator, validator.Bytes()))
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	t.Run("External wallets should NOT be authorized signers under another EIP-191 version", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{})
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, false, t)
+	})
+}
+
+func TestMessagePrefix(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+
+	// "\x19Ronin Signed Message (2020):\n3foo"
+	roninSig := signRawHash(ethCrypto.Keccak256([]byte("\x19Ronin Signed Message (2020):\n3foo")), keyA, t)
+
+	prefixTests := []struct {
+		title     string
+		template  string
+		chainID   *big.Int
+		chainName string
+		sig       string
+		expected  bool
+	}{
+		{"External wallets should be authorized signers under a chain-name-bearing prefix", "\x19{chainName} Signed Message ({chainId}):\n{length}", big.NewInt(2020), "Ronin", roninSig, true},
+		{"The length should follow templates without the length token", "\x19{chainName} Signed Message ({chainId}):\n", big.NewInt(2020), "Ronin", roninSig, true},
+		{"External wallets should NOT be authorized signers under another chain name", "\x19{chainName} Signed Message ({chainId}):\n{length}", big.NewInt(2020), "Saigon", roninSig, false},
+		{"External wallets should NOT be authorized signers under another chain ID", "\x19{chainName} Signed Message ({chainId}):\n{length}", big.NewInt(2021), "Ronin", roninSig, false},
+		{"The standard prefix should verify standard signatures", PersonalMessagePrefix, nil, "", signEOAPersonalMessage("foo", keyA, t), true},
+		{"External wallets should NOT be authorized signers of standard signatures under a chain prefix", "\x19{chainName} Signed Message ({chainId}):\n{length}", big.NewInt(2020), "Ronin", signEOAPersonalMessage("foo", keyA, t), false},
+	}
+
+	for _, test := range prefixTests {
+		t.Run(test.title, func(t *testing.T) {
+			authenticator := NewAuthenticator(nil, &mockContract{}, WithMessagePrefix(test.template, test.chainID, test.chainName))
+
+			isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", test.sig, addrA.Hex())
+			checkError(err, t)
+			expectBool(isAuthorizedSigner, test.expected, t)
+		})
+	}
+
+	t.Run("External wallets should NOT be authorized signers of chain prefixed signatures without the prefix", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{})
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", roninSig, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, false, t)
+	})
+	t.Run("The signable message should be the preimage of the verified digest under a chain-name-bearing prefix", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{}, WithMessagePrefix("\x19{chainName} Signed Message ({chainId}):\n{length}", big.NewInt(2020), "Ronin"))
+
+		result, err := authenticator.Verify("foo", roninSig, addrA.Hex())
+		checkError(err, t)
+		expectBool(result.Authorized, true, t)
+		expectBool(common.BytesToHash(ethCrypto.Keccak256(authenticator.SignableMessage("foo"))) == result.Digest, true, t)
+	})
+}
@@ -0,0 +1,248 @@
+package dappauth
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/dapperlabs/dappauth/ERCs"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	_EIP712DomainTypeHash = ethCrypto.Keccak256([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)"))
+	_PermitTypeHash       = ethCrypto.Keccak256([]byte("Permit(address owner,address spender,uint256 value,uint256 nonce,uint256 deadline)"))
+
+	// ErrPermitExpired is returned when the deadline of a permit has passed.
+	ErrPermitExpired = errors.New("dappauth: permit deadline has passed")
+
+	// ErrUnsupportedDomainExtensions is returned when the EIP-5267 domain of a contract has extensions, which EIP-5267
+	// leaves to further EIPs to define.
+	ErrUnsupportedDomainExtensions = errors.New("dappauth: unsupported EIP-712 domain extensions")
+)
+
+// DomainFields is the set of fields an EIP-712 domain is made of, as the fields bitmap of EIP-5267.
+type DomainFields byte
+
+const (
+	// DomainFieldName is the string name of the domain.
+	DomainFieldName DomainFields = 1 << iota
+	// DomainFieldVersion is the string version of the domain.
+	DomainFieldVersion
+	// DomainFieldChainID is the uint256 chainId of the domain.
+	DomainFieldChainID
+	// DomainFieldVerifyingContract is the address verifyingContract of the domain.
+	DomainFieldVerifyingContract
+	// DomainFieldSalt is the bytes32 salt of the domain.
+	DomainFieldSalt
+)
+
+// TypedDataDomain is the domain of EIP-712 typed data. Domains omitting optional fields, e.g. made of name, version and
+// chainId only, must set Fields accordingly: absent fields are left out of the domain type and separator, whereas the
+// default fields encode a zero verifyingContract as the zero address.
+type TypedDataDomain struct {
+	Name              string
+	Version           string
+	ChainID           *big.Int
+	VerifyingContract common.Address
+	Salt              [32]byte
+	Fields            DomainFields // the fields the domain is made of (0 = name, version, chainId and verifyingContract)
+}
+
+// Separator returns the EIP-712 domain separator, over the fields the domain is made of.
+func (d *TypedDataDomain) Separator() [32]byte {
+	var separator [32]byte
+	if d.Fields == 0 {
+		copy(separator[:], ethCrypto.Keccak256(
+			_EIP712DomainTypeHash,
+			ethCrypto.Keccak256([]byte(d.Name)),
+			ethCrypto.Keccak256([]byte(d.Version)),
+			encodeUint256(d.ChainID),
+			encodeAddress(d.VerifyingContract),
+		))
+		return separator
+	}
+
+	var (
+		members []string
+		values  = [][]byte{nil} // the type hash goes first
+	)
+	if d.Fields&DomainFieldName != 0 {
+		members = append(members, "string name")
+		values = append(values, ethCrypto.Keccak256([]byte(d.Name)))
+	}
+	if d.Fields&DomainFieldVersion != 0 {
+		members = append(members, "string version")
+		values = append(values, ethCrypto.Keccak256([]byte(d.Version)))
+	}
+	if d.Fields&DomainFieldChainID != 0 {
+		members = append(members, "uint256 chainId")
+		values = append(values, encodeUint256(d.ChainID))
+	}
+	if d.Fields&DomainFieldVerifyingContract != 0 {
+		members = append(members, "address verifyingContract")
+		values = append(values, encodeAddress(d.VerifyingContract))
+	}
+	if d.Fields&DomainFieldSalt != 0 {
+		members = append(members, "bytes32 salt")
+		values = append(values, d.Salt[:])
+	}
+	values[0] = ethCrypto.Keccak256([]byte("EIP712Domain(" + strings.Join(members, ",") + ")"))
+
+	copy(separator[:], ethCrypto.Keccak256(values...))
+	return separator
+}
+
+// WithEIP5267Domain makes typed data be verified against the EIP-712 domain its verifying contract reports via EIP-5267
+// eip712Domain(), rather than against the domain given. A domain without verifying contract then stands for the
+// domain of the wallet itself, for wallets which sign typed data bound to their own domain. Costs an extra RPC.
+func WithEIP5267Domain(fetch bool) Option {
+	return func(a *Authenticator) {
+		a.eip5267Domain = fetch
+	}
+}
+
+// EIP712Domain returns the EIP-712 domain the contract reports via EIP-5267 eip712Domain().
+func (a *Authenticator) EIP712Domain(addr common.Address) (TypedDataDomain, error) {
+	if a.cc == nil {
+		return TypedDataDomain{}, ErrNoBackend
+	}
+
+	_EIP5267Caller, err := ERCs.NewEIP5267Caller(addr, boundCaller{a})
+	if err != nil {
+		return TypedDataDomain{}, wrapError("EIP-712 domain of", addr, err)
+	}
+
+	callOpts := a.callOpts()
+	reported, err := _EIP5267Caller.Eip712Domain(&callOpts)
+	if err != nil {
+		return TypedDataDomain{}, wrapError("EIP-712 domain of", addr, err)
+	}
+	if len(reported.Extensions) > 0 {
+		return TypedDataDomain{}, ErrUnsupportedDomainExtensions
+	}
+
+	return TypedDataDomain{
+		Name:              reported.Name,
+		Version:           reported.Version,
+		ChainID:           reported.ChainId,
+		VerifyingContract: reported.VerifyingContract,
+		Salt:              reported.Salt,
+		Fields:            DomainFields(reported.Fields[0]),
+	}, nil
+}
+
+// typedDataDomain returns the domain typed data signed by addr is verified against.
+func (a *Authenticator) typedDataDomain(domain TypedDataDomain, addr common.Address) (TypedDataDomain, error) {
+	if !a.eip5267Domain {
+		return domain, nil
+	}
+
+	verifyingContract := domain.VerifyingContract
+	if verifyingContract == (common.Address{}) {
+		verifyingContract = addr
+	}
+	return a.EIP712Domain(verifyingContract)
+}
+
+// Permit is an EIP-2612 permit message.
+type Permit struct {
+	Owner    common.Address
+	Spender  common.Address
+	Value    *big.Int
+	Nonce    *big.Int
+	Deadline *big.Int // unix timestamp (seconds)
+}
+
+// StructHash returns the EIP-712 struct hash of the permit.
+func (p *Permit) StructHash() [32]byte {
+	var hash [32]byte
+	copy(hash[:], ethCrypto.Keccak256(
+		_PermitTypeHash,
+		encodeAddress(p.Owner),
+		encodeAddress(p.Spender),
+		encodeUint256(p.Value),
+		encodeUint256(p.Nonce),
+		encodeUint256(p.Deadline),
+	))
+	return hash
+}
+
+// TypedDataHash returns the EIP-712 digest ("\x19\x01" ‖ domainSeparator ‖ structHash) to be signed.
+func TypedDataHash(domain TypedDataDomain, structHash [32]byte) [32]byte {
+	domainSeparator := domain.Separator()
+
+	var hash [32]byte
+	copy(hash[:], eip191Hash(EIP191VersionStructuredData, domainSeparator[:], structHash[:]))
+	return hash
+}
+
+// IsAuthorizedPermit checks if the owner of the permit is an authorized signer for the EIP-712 signature of the permit,
+// either as an external wallet or as a smart-contract wallet, and that the permit's deadline hasn't passed.
+func (a *Authenticator) IsAuthorizedPermit(domain TypedDataDomain, permit Permit, signature string) (bool, error) {
+
+	if permit.Deadline == nil || permit.Deadline.Cmp(big.NewInt(a.now().Unix())) < 0 {
+		return false, ErrPermitExpired
+	}
+
+	sigBytes, err := a.signatureBytes(signature)
+	if err != nil {
+		return false, err
+	}
+
+	if domain, err = a.typedDataDomain(domain, permit.Owner); err != nil {
+		return false, err
+	}
+
+	return authorized(a.verifyTypedData(TypedDataHash(domain, permit.StructHash()), sigBytes, permit.Owner))
+}
+
+// IsAuthorizedTypedData checks if the address is an authorized signer for the EIP-712 signature of the typed data,
+// given its struct hash, either as an external wallet or as a smart-contract wallet.
+func (a *Authenticator) IsAuthorizedTypedData(domain TypedDataDomain, structHash [32]byte, signature, addrHex string) (bool, error) {
+
+	sigBytes, err := a.signatureBytes(signature)
+	if err != nil {
+		return false, err
+	}
+
+	addr, err := a.normalizeAddress(addrHex)
+	if err != nil {
+		return false, err
+	}
+
+	if domain, err = a.typedDataDomain(domain, addr); err != nil {
+		return false, err
+	}
+
+	return authorized(a.verifyTypedData(TypedDataHash(domain, structHash), sigBytes, addr))
+}
+
+// verifyTypedData verifies the signature over the EIP-712 digest, signed without the personal message prefix.
+func (a *Authenticator) verifyTypedData(digest [32]byte, sigBytes []byte, addr common.Address) (*Result, error) {
+
+	// try direct-keyed wallet
+	recoveredAddress, err := a.recoverAddress(digest[:], sigBytes)
+	if err == nil && recoveredAddress == addr {
+		if a.enforcesLowS(PathEOA) && hasHighS(sigBytes) {
+			return nil, ErrHighS
+		}
+		return a.authorize(&Result{Path: PathEOA, RecoveredAddress: recoveredAddress, Digest: digest}, addr)
+	}
+
+	// try smart-contract wallet
+	return a.verifyContractHash(digest, nil, sigBytes, addr)
+}
+
+func encodeUint256(i *big.Int) []byte {
+	if i == nil {
+		return make([]byte, 32)
+	}
+	return math.PaddedBigBytes(math.U256(new(big.Int).Set(i)), 32)
+}
+
+func encodeAddress(addr common.Address) []byte {
+	return common.LeftPadBytes(addr.Bytes(), 32)
+}
@@ -0,0 +1,295 @@
+package dappauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestPermit(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	now := time.Unix(1700000000, 0)
+
+	domain := TypedDataDomain{
+		Name:              "Token",
+		Version:           "1",
+		ChainID:           big.NewInt(1),
+		VerifyingContract: common.HexToAddress("0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"),
+	}
+	permit := func(deadline int64) Permit {
+		return Permit{
+			Owner:    addrA,
+			Spender:  common.HexToAddress("0x000000000000000000000000000000000000bEEF"),
+			Value:    big.NewInt(1000),
+			Nonce:    big.NewInt(0),
+			Deadline: big.NewInt(deadline),
+		}
+	}
+
+	validPermit := permit(now.Unix() + 60)
+	expiredPermit := permit(now.Unix() - 60)
+	noExpiryPermit := permit(0)
+	noExpiryPermit.Deadline = math.MaxBig256 // the standard "no expiry" deadline
+
+	permitTests := []struct {
+		title                         string
+		permit                        Permit
+		signature                     string
+		mockContract                  *mockContract
+		expectedAuthorizedSignerError error
+		expectedAuthorizedSigner      bool
+	}{
+		{
+			title:                    "External wallets should be authorized signers over their valid permit",
+			permit:                   validPermit,
+			signature:                signPermit(domain, validPermit, keyA, t),
+			mockContract:             &mockContract{},
+			expectedAuthorizedSigner: true,
+		},
+		{
+			title:                         "External wallets should NOT be authorized signers over their expired permit",
+			permit:                        expiredPermit,
+			signature:                     signPermit(domain, expiredPermit, keyA, t),
+			mockContract:                  &mockContract{},
+			expectedAuthorizedSignerError: ErrPermitExpired,
+		},
+		{
+			title:                    "External wallets should be authorized signers over their permit without expiry",
+			permit:                   noExpiryPermit,
+			signature:                signPermit(domain, noExpiryPermit, keyA, t),
+			mockContract:             &mockContract{},
+			expectedAuthorizedSigner: true,
+		},
+		{
+			title:        "External wallets should NOT be authorized signers over a permit signed by another key",
+			permit:       validPermit,
+			signature:    signPermit(domain, validPermit, keyB, t),
+			mockContract: &mockContract{},
+		},
+		{
+			title:                    "Smart-contract wallets should be authorized signers over their valid permit",
+			permit:                   validPermit,
+			signature:                signRawHash(erc191MessageHash(typedDataHash(domain, validPermit), addrA), keyB, t),
+			mockContract:             &mockContract{address: addrA, authorizedKey: &keyB.PublicKey},
+			expectedAuthorizedSigner: true,
+		},
+	}
+
+	for _, test := range permitTests {
+		t.Run(test.title, func(t *testing.T) {
+			authenticator := NewAuthenticator(nil, test.mockContract, WithClock(func() time.Time { return now }))
+
+			isAuthorizedSigner, err := authenticator.IsAuthorizedPermit(domain, test.permit, test.signature)
+			if !errors.Is(err, test.expectedAuthorizedSignerError) {
+				t.Errorf("expected %v to be %v", err, test.expectedAuthorizedSignerError)
+			}
+			expectBool(isAuthorizedSigner, test.expectedAuthorizedSigner, t)
+		})
+	}
+}
+
+// computes the EIP-712 digest of a permit independently of TypedDataHash
+func typedDataHash(domain TypedDataDomain, permit Permit) []byte {
+	domainSeparator := ethCrypto.Keccak256(
+		ethCrypto.Keccak256([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)")),
+		ethCrypto.Keccak256([]byte(domain.Name)),
+		ethCrypto.Keccak256([]byte(domain.Version)),
+		common.LeftPadBytes(domain.ChainID.Bytes(), 32),
+		common.LeftPadBytes(domain.VerifyingContract.Bytes(), 32),
+	)
+	structHash := ethCrypto.Keccak256(
+		ethCrypto.Keccak256([]byte("Permit(address owner,address spender,uint256 value,uint256 nonce,uint256 deadline)")),
+		common.LeftPadBytes(permit.Owner.Bytes(), 32),
+		common.LeftPadBytes(permit.Spender.Bytes(), 32),
+		common.LeftPadBytes(permit.Value.Bytes(), 32),
+		common.LeftPadBytes(permit.Nonce.Bytes(), 32),
+		common.LeftPadBytes(permit.Deadline.Bytes(), 32),
+	)
+	return ethCrypto.Keccak256([]byte{0x19, 0x01}, domainSeparator, structHash)
+}
+
+func signPermit(domain TypedDataDomain, permit Permit, key *ecdsa.PrivateKey, t *testing.T) string {
+	digest := TypedDataHash(domain, permit.StructHash())
+	expectBool(bytes.Equal(digest[:], typedDataHash(domain, permit)), true, t)
+	return signRawHash(digest[:], key, t)
+}
+
+func TestEIP5267Domain(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+
+	// the wallet at A, owned by B, reports its own domain
+	walletDomain := TypedDataDomain{
+		Name:              "Wallet",
+		Version:           "1",
+		ChainID:           big.NewInt(1),
+		VerifyingContract: addrA,
+		Salt:              common.HexToHash("0x01"),
+		Fields:            DomainFieldName | DomainFieldVersion | DomainFieldChainID | DomainFieldVerifyingContract | DomainFieldSalt,
+	}
+	mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, hashScheme: HashSchemeRaw, eip5267Domain: &walletDomain}
+
+	var structHash [32]byte
+	copy(structHash[:], ethCrypto.Keccak256([]byte("contents")))
+	digest := TypedDataHash(walletDomain, structHash)
+	sigB := signRawHash(digest[:], keyB, t)
+
+	t.Run("Domains should be fetched from the contract", func(t *testing.T) {
+		domain, err := NewAuthenticator(nil, mock).EIP712Domain(addrA)
+		checkError(err, t)
+		expectBool(domain.Separator() == walletDomain.Separator(), true, t)
+	})
+
+	t.Run("Signers should be authorized over the wallet's own domain", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, mock, WithEIP5267Domain(true))
+		isAuthorizedSigner, err := authenticator.IsAuthorizedTypedData(TypedDataDomain{}, structHash, sigB, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	t.Run("Signers should NOT be authorized over the given domain without fetching", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, mock)
+		isAuthorizedSigner, err := authenticator.IsAuthorizedTypedData(TypedDataDomain{}, structHash, sigB, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, false, t)
+	})
+
+	t.Run("Domains should be fetched per the state override", func(t *testing.T) {
+		upgraded := walletDomain
+		upgraded.Version = "2"
+		backend := &domainOverrideBackend{mockContract: mock, overridden: &mockContract{address: addrA, eip5267Domain: &upgraded}}
+		override := StateOverride{addrA: OverrideAccount{Code: []byte{1}}}
+
+		domain, err := NewAuthenticator(nil, backend, WithStateOverride(override)).EIP712Domain(addrA)
+		checkError(err, t)
+		expectBool(domain.Separator() == upgraded.Separator(), true, t)
+	})
+
+	t.Run("Contracts without eip712Domain() should error", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{address: addrA, authorizedKey: &keyB.PublicKey}, WithEIP5267Domain(true))
+		_, err := authenticator.IsAuthorizedTypedData(TypedDataDomain{}, structHash, sigB, addrA.Hex())
+		expectBool(err != nil, true, t)
+	})
+}
+
+// answers the calls to overridden accounts from the overridden mock
+type domainOverrideBackend struct {
+	*mockContract
+	overridden *mockContract
+}
+
+func (b *domainOverrideBackend) CallContractWithStateOverride(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int, override StateOverride) ([]byte, error) {
+	if _, ok := override[*call.To]; ok {
+		return b.overridden.CallContract(ctx, call, blockNumber)
+	}
+	return b.CallContract(ctx, call, blockNumber)
+}
+
+func TestDomainSeparatorFields(t *testing.T) {
+
+	domain := TypedDataDomain{
+		Name:              "Token",
+		Version:           "1",
+		ChainID:           big.NewInt(1),
+		VerifyingContract: common.HexToAddress("0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"),
+	}
+	legacy := domain.Separator()
+
+	t.Run("Domains of the default fields should have the same separator", func(t *testing.T) {
+		domain.Fields = DomainFieldName | DomainFieldVersion | DomainFieldChainID | DomainFieldVerifyingContract
+		expectBool(domain.Separator() == legacy, true, t)
+	})
+
+	t.Run("Separators should be computed over the present fields only", func(t *testing.T) {
+		domain.Fields = DomainFieldName | DomainFieldChainID
+		expected := ethCrypto.Keccak256(
+			ethCrypto.Keccak256([]byte("EIP712Domain(string name,uint256 chainId)")),
+			ethCrypto.Keccak256([]byte("Token")),
+			common.LeftPadBytes([]byte{1}, 32),
+		)
+		separator := domain.Separator()
+		expectBool(bytes.Equal(separator[:], expected), true, t)
+	})
+}
+
+func TestMinimalDomain(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+
+	// name, version and chainId only, without verifyingContract
+	domain := TypedDataDomain{
+		Name:    "Token",
+		Version: "1",
+		ChainID: big.NewInt(1),
+		Fields:  DomainFieldName | DomainFieldVersion | DomainFieldChainID,
+	}
+	structHash := ethCrypto.Keccak256Hash([]byte("Mail"))
+
+	// computed independently of Separator
+	separator := ethCrypto.Keccak256(
+		ethCrypto.Keccak256([]byte("EIP712Domain(string name,string version,uint256 chainId)")),
+		ethCrypto.Keccak256([]byte("Token")),
+		ethCrypto.Keccak256([]byte("1")),
+		common.LeftPadBytes([]byte{1}, 32),
+	)
+	digest := ethCrypto.Keccak256([]byte{0x19, 0x01}, separator, structHash[:])
+
+	t.Run("The separator should be computed over name, version and chainId only", func(t *testing.T) {
+		domainSeparator := domain.Separator()
+		expectBool(bytes.Equal(domainSeparator[:], separator), true, t)
+
+		typedDataHash := TypedDataHash(domain, structHash)
+		expectBool(bytes.Equal(typedDataHash[:], digest), true, t)
+	})
+
+	t.Run("External wallets should be authorized signers over typed data of a minimal domain", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{})
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedTypedData(domain, structHash, signRawHash(digest, keyA, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	t.Run("Smart-contract wallets should be authorized signers over typed data of a minimal domain", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{address: addrA, authorizedKey: &keyB.PublicKey})
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedTypedData(domain, structHash, signRawHash(erc191MessageHash(digest, addrA), keyB, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	t.Run("External wallets should NOT be authorized signers of a minimal domain under the default fields", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{})
+		defaultDomain := domain
+		defaultDomain.Fields = 0
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedTypedData(defaultDomain, structHash, signRawHash(digest, keyA, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, false, t)
+	})
+}
@@ -0,0 +1,189 @@
+package dappauth
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dapperlabs/dappauth/ERCs"
+	ethAbi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	// ERC6492MagicSuffix is appended to ERC-6492 wrapped signatures of counterfactual (not yet deployed) wallets.
+	ERC6492MagicSuffix = common.FromHex("0x6492649264926492649264926492649264926492649264926492649264926492")
+
+	// ErrNoERC6492Validator is returned when an ERC-6492 signature is verified without a configured validator.
+	ErrNoERC6492Validator = errors.New("dappauth: no ERC-6492 validator configured")
+
+	// ErrCounterfactualAddressMismatch is returned when the CREATE2 predicted wallet address is not the claimed address.
+	ErrCounterfactualAddressMismatch = errors.New("dappauth: counterfactual wallet address mismatch")
+)
+
+// the ERC-6492 wrapper is abi.encode(address factory, bytes factoryCalldata, bytes originalSignature)
+var _ERC6492WrapperArgs = mustArguments(`[
+	{ "name": "factory", "type": "address" },
+	{ "name": "factoryCalldata", "type": "bytes" },
+	{ "name": "originalSignature", "type": "bytes" }]`)
+
+// CounterfactualWallet describes a contract wallet that is not deployed yet, by the CREATE2 parameters of its deployment.
+type CounterfactualWallet struct {
+	Factory         common.Address // the CREATE2 deployer
+	Salt            [32]byte       // the CREATE2 salt
+	InitCode        []byte         // the wallet's creation code (its keccak256 is used to predict the address)
+	FactoryCalldata []byte         // the calldata sent to Factory to deploy the wallet
+}
+
+// Address returns the CREATE2 predicted address of the wallet.
+func (w *CounterfactualWallet) Address() common.Address {
+	return ethCrypto.CreateAddress2(w.Factory, w.Salt, ethCrypto.Keccak256(w.InitCode))
+}
+
+// WithERC6492Validator sets the address of the deployed ERC-6492 UniversalSigValidator used to verify
+// signatures of counterfactual wallets (the validator is only ever invoked via eth_call).
+func WithERC6492Validator(validator common.Address) Option {
+	return func(a *Authenticator) {
+		a.erc6492Validator = validator
+	}
+}
+
+// WrapERC6492Signature wraps the signature of a counterfactual wallet according to ERC-6492.
+func WrapERC6492Signature(factory common.Address, factoryCalldata, signature []byte) ([]byte, error) {
+	wrapped, err := _ERC6492WrapperArgs.Pack(factory, factoryCalldata, signature)
+	if err != nil {
+		return nil, err
+	}
+	return append(wrapped, ERC6492MagicSuffix...), nil
+}
+
+// IsERC6492Signature returns true if the signature is ERC-6492 wrapped.
+func IsERC6492Signature(signature []byte) bool {
+	return len(signature) > len(ERC6492MagicSuffix) && bytes.HasSuffix(signature, ERC6492MagicSuffix)
+}
+
+func unwrapERC6492Signature(signature []byte) (factory common.Address, factoryCalldata, originalSignature []byte, err error) {
+	if !IsERC6492Signature(signature) {
+		return common.Address{}, nil, nil, errors.New("dappauth: not an ERC-6492 signature")
+	}
+
+	wrapper := []interface{}{&factory, &factoryCalldata, &originalSignature}
+	err = _ERC6492WrapperArgs.Unpack(&wrapper, signature[:len(signature)-len(ERC6492MagicSuffix)])
+	return factory, factoryCalldata, originalSignature, err
+}
+
+// IsAuthorizedCounterfactualSigner checks if a counterfactual (not yet deployed) wallet would authorize the signature for the challenge.
+// The CREATE2 predicted address of the wallet must equal the claimed address. Signatures that are not ERC-6492 wrapped yet are wrapped
+// with the wallet's factory parameters, and those already wrapped must carry the wallet's factory and factory calldata.
+func (a *Authenticator) IsAuthorizedCounterfactualSigner(challenge, signature string, wallet CounterfactualWallet, addrHex string) (bool, error) {
+
+	if err := a.checkChallengeValidity(challenge); err != nil {
+		return false, err
+	}
+
+	addr, err := a.normalizeAddress(addrHex)
+	if err != nil {
+		return false, err
+	}
+	if wallet.Address() != addr {
+		return false, ErrCounterfactualAddressMismatch
+	}
+	if err := a.checkAddressBinding(challenge, addr); err != nil {
+		return false, err
+	}
+
+	sigBytes, err := a.signatureBytes(signature)
+	if err != nil {
+		return false, err
+	}
+
+	if IsERC6492Signature(sigBytes) {
+		factory, factoryCalldata, _, err := unwrapERC6492Signature(sigBytes)
+		if err != nil {
+			return false, fmt.Errorf("dappauth: unwrapping ERC-6492 signature: %w", err)
+		}
+		// the wrapper would deploy another wallet than the one described
+		if factory != wallet.Factory || !bytes.Equal(factoryCalldata, wallet.FactoryCalldata) {
+			return false, ErrCounterfactualAddressMismatch
+		}
+	} else {
+		sigBytes, err = WrapERC6492Signature(wallet.Factory, wallet.FactoryCalldata, sigBytes)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return authorized(a.verifyERC6492Signature(challenge, sigBytes, addr))
+}
+
+func (a *Authenticator) verifyERC6492Signature(challenge string, signature []byte, addr common.Address) (*Result, error) {
+
+	// accounts already deployed verify the inner signature directly, without simulating the deployment
+	if a.cc != nil {
+		code, err := a.codeAt(addr)
+		if err != nil {
+			return nil, wrapError("code of", addr, contractCallFailed(err))
+		}
+		if len(code) > 0 {
+			_, _, innerSig, err := unwrapERC6492Signature(signature)
+			if err != nil {
+				return nil, fmt.Errorf("dappauth: unwrapping ERC-6492 signature: %w", err)
+			}
+			return a.verifyContractSigner(challenge, innerSig, addr)
+		}
+	}
+
+	if a.erc6492Validator == (common.Address{}) {
+		return nil, ErrNoERC6492Validator
+	}
+	if a.cc == nil {
+		return nil, ErrNoBackend
+	}
+
+	blockNumber := a.callBlockNumber()
+	pinned := a.atBlock(blockNumber)
+
+	_ERC6492Caller, err := ERCs.NewERC6492Caller(a.erc6492Validator, boundCaller{pinned})
+	if err != nil {
+		return nil, wrapError("ERC-6492 validator for", addr, err)
+	}
+
+	_ERC6492CallerSession := ERCs.ERC6492CallerSession{
+		Contract: _ERC6492Caller,
+		CallOpts: pinned.callOpts(),
+	}
+
+	// same as for ERC-1271, the validator receives the hash according to the contract hash scheme
+	challengeHash, err := a.contractHash(challenge)
+	if err != nil {
+		return nil, err
+	}
+	isValid, err := _ERC6492CallerSession.IsValidSig(addr, challengeHash, signature)
+	if err != nil {
+		return nil, wrapError("ERC-6492 validator for", addr, err)
+	}
+
+	result := &Result{Path: PathERC6492, Digest: common.BytesToHash(a.signedContractHash(challengeHash[:], addr)), BlockNumber: blockNumber}
+	isContract := false // the deployed wallet would have been verified directly
+	result.IsContract = &isContract
+	if _, _, originalSignature, err := unwrapERC6492Signature(signature); err == nil {
+		result.InnerSigners = a.recoverInnerSigners(challengeHash[:], originalSignature, addr)
+	}
+
+	if !isValid {
+		return result, nil
+	}
+
+	return a.authorize(result, append([]common.Address{addr}, result.InnerSigners...)...)
+}
+
+func mustArguments(definition string) ethAbi.Arguments {
+	var args ethAbi.Arguments
+	if err := json.NewDecoder(strings.NewReader(definition)).Decode(&args); err != nil {
+		panic(err)
+	}
+	return args
+}
@@ -0,0 +1,114 @@
+package dappauth
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestCounterfactualWallet(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	validator := common.HexToAddress("0x000000000000000000000000000000000000dEaD")
+	wallet := CounterfactualWallet{
+		Factory:         common.HexToAddress("0x4e59b44847b379578588920ca78fbf26c0b4956c"),
+		Salt:            [32]byte{1},
+		InitCode:        common.FromHex("0x6080604052"),
+		FactoryCalldata: common.FromHex("0xdeadbeef"),
+	}
+	walletAddr := wallet.Address()
+
+	// CREATE2 address = keccak256(0xff ++ factory ++ salt ++ keccak256(initCode))[12:]
+	expectedAddr := common.BytesToAddress(ethCrypto.Keccak256(
+		[]byte{0xff}, wallet.Factory.Bytes(), wallet.Salt[:], ethCrypto.Keccak256(wallet.InitCode))[12:])
+	if walletAddr != expectedAddr {
+		t.Fatalf("expected CREATE2 address %v to be %v", walletAddr.Hex(), expectedAddr.Hex())
+	}
+
+	t.Run("Counterfactual wallets should be authorized signers over their predicted address", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{authorizedKey: &keyB.PublicKey}, WithERC6492Validator(validator))
+		sig := signERC1654PersonalMessage("foo", keyB, walletAddr, t)
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedCounterfactualSigner("foo", sig, wallet, walletAddr.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	t.Run("Counterfactual wallets should NOT be authorized with an incorrect internal key", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{authorizedKey: &keyA.PublicKey}, WithERC6492Validator(validator))
+		sig := signERC1654PersonalMessage("foo", keyB, walletAddr, t)
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedCounterfactualSigner("foo", sig, wallet, walletAddr.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, false, t)
+	})
+
+	t.Run("ERC-6492 wrapped signatures should be verified by IsAuthorizedSigner", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{authorizedKey: &keyB.PublicKey}, WithERC6492Validator(validator))
+		sig := common.FromHex(signERC1654PersonalMessage("foo", keyB, walletAddr, t))
+		wrapped, err := WrapERC6492Signature(wallet.Factory, wallet.FactoryCalldata, sig)
+		checkError(err, t)
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", common.Bytes2Hex(wrapped), walletAddr.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	t.Run("Counterfactual wallets should error when the predicted address isn't the claimed address", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{authorizedKey: &keyB.PublicKey}, WithERC6492Validator(validator))
+		otherAddr := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+		sig := signERC1654PersonalMessage("foo", keyB, otherAddr, t)
+
+		_, err := authenticator.IsAuthorizedCounterfactualSigner("foo", sig, wallet, otherAddr.Hex())
+		expectBool(err == ErrCounterfactualAddressMismatch, true, t)
+	})
+
+	t.Run("Wrapped signatures deploying with other factory calldata should error with ErrCounterfactualAddressMismatch", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{authorizedKey: &keyB.PublicKey}, WithERC6492Validator(validator))
+		sig := common.FromHex(signERC1654PersonalMessage("foo", keyB, walletAddr, t))
+		wrapped, err := WrapERC6492Signature(wallet.Factory, common.FromHex("0xcafebabe"), sig)
+		checkError(err, t)
+
+		_, err = authenticator.IsAuthorizedCounterfactualSigner("foo", common.Bytes2Hex(wrapped), wallet, walletAddr.Hex())
+		expectBool(err == ErrCounterfactualAddressMismatch, true, t)
+	})
+
+	t.Run("Wrapped signatures deploying with the wallet's factory calldata should be authorized", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{authorizedKey: &keyB.PublicKey}, WithERC6492Validator(validator))
+		sig := common.FromHex(signERC1654PersonalMessage("foo", keyB, walletAddr, t))
+		wrapped, err := WrapERC6492Signature(wallet.Factory, wallet.FactoryCalldata, sig)
+		checkError(err, t)
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedCounterfactualSigner("foo", common.Bytes2Hex(wrapped), wallet, walletAddr.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	t.Run("Counterfactual wallets should error without a validator", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{authorizedKey: &keyB.PublicKey})
+		sig := signERC1654PersonalMessage("foo", keyB, walletAddr, t)
+
+		_, err := authenticator.IsAuthorizedCounterfactualSigner("foo", sig, wallet, walletAddr.Hex())
+		expectBool(err == ErrNoERC6492Validator, true, t)
+	})
+
+	t.Run("Deployed wallets should verify the inner signature of ERC-6492 wrapped signatures directly", func(t *testing.T) {
+		// calls to the validator error, so the deployment mustn't be simulated
+		mock := &mockContract{address: walletAddr, authorizedKey: &keyB.PublicKey, code: []byte{0x60}, errorAddresses: []common.Address{validator}}
+		authenticator := NewAuthenticator(nil, mock, WithERC6492Validator(validator))
+		sig := common.FromHex(signERC1654PersonalMessage("foo", keyB, walletAddr, t))
+		wrapped, err := WrapERC6492Signature(wallet.Factory, wallet.FactoryCalldata, sig)
+		checkError(err, t)
+
+		result, err := authenticator.Verify("foo", common.Bytes2Hex(wrapped), walletAddr.Hex())
+		checkError(err, t)
+		expectBool(result.Authorized, true, t)
+		expectBool(result.Path == PathContract, true, t)
+		expectBool(mock.calls == 1, true, t)
+	})
+}
@@ -0,0 +1,35 @@
+package dappauth
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// WithValidatorModule routes the verification of ERC-7579 modular smart accounts to the validator module, by prefixing
+// the signature passed to isValidSignature of the account with the address of the module (validator ‖ signature), as
+// accounts delegating to validator modules expect. The account remains the verified identity.
+func WithValidatorModule(validator common.Address) Option {
+	return func(a *Authenticator) {
+		a.validatorModule = validator
+	}
+}
+
+// IsAuthorizedSignerViaModule is like IsAuthorizedSigner but routes the verification of the smart account to the
+// validator module, as WithValidatorModule, for this verification only.
+func (a *Authenticator) IsAuthorizedSignerViaModule(challenge, signature, addrHex string, validator common.Address) (bool, error) {
+	routed := *a
+	routed.validatorModule = validator
+	routed.resultCache = nil
+
+	return routed.IsAuthorizedSigner(challenge, signature, addrHex)
+}
+
+// routeToValidatorModule prefixes the signature passed to the smart account with the validator module, if any.
+func (a *Authenticator) routeToValidatorModule(sig []byte) []byte {
+	if a.validatorModule == (common.Address{}) {
+		return sig
+	}
+
+	routed := make([]byte, 0, common.AddressLength+len(sig))
+	routed = append(routed, a.validatorModule.Bytes()...)
+	return append(routed, sig...)
+}
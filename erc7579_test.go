@@ -0,0 +1,50 @@
+package dappauth
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestValidatorModule(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	validator := common.HexToAddress("0x00000000000000000000000000000000000007a1")
+	otherValidator := common.HexToAddress("0x00000000000000000000000000000000000007a2")
+
+	// the smart account at A validates via the validator module, whose signer is B, and only implements ERC-1654
+	erc1654 := WithWalletInterfaces(InterfaceERC1654)
+	mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, code: []byte{0x60}, validatorModule: validator}
+	sig := signERC1654PersonalMessage("foo", keyB, addrA, t)
+
+	t.Run("Accounts should be authorized signers via their validator module", func(t *testing.T) {
+		isAuthorizedSigner, err := NewAuthenticator(nil, mock, erc1654, WithValidatorModule(validator)).IsAuthorizedSigner("foo", sig, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+		expectBool(bytes.Equal(mock.lastSignature, append(validator.Bytes(), common.FromHex(sig)...)), true, t)
+	})
+
+	t.Run("The validator module should be selectable per call", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, mock, erc1654, WithValidatorModule(otherValidator))
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSignerViaModule("foo", sig, addrA.Hex(), validator)
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+
+		_, err = authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+		expectBool(errors.Is(err, ErrUnsupportedWalletInterface), true, t)
+	})
+
+	t.Run("Accounts should NOT be authorized signers without routing to their validator module", func(t *testing.T) {
+		_, err := NewAuthenticator(nil, mock, erc1654).IsAuthorizedSigner("foo", sig, addrA.Hex())
+		expectBool(errors.Is(err, ErrUnsupportedWalletInterface), true, t)
+	})
+}
@@ -0,0 +1,99 @@
+package dappauth
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// ErrInvalidContentsType is returned when the ERC-7739 contents type isn't an EIP-712 encoded type, e.g. "Mail(address to,string contents)".
+var ErrInvalidContentsType = errors.New("dappauth: invalid ERC-7739 contents type")
+
+// NestedTypedData is application EIP-712 typed data signed by a smart account via ERC-7739 nested typed data,
+// which binds the signature to the account (i.e. it can't be replayed on another account of the same owner).
+type NestedTypedData struct {
+	AppDomain    TypedDataDomain // the domain of the application
+	ContentsType string          // the EIP-712 encoded type of the contents, e.g. "Mail(address to,string contents)"
+	ContentsHash [32]byte        // the EIP-712 struct hash of the contents
+}
+
+// Hash returns the EIP-712 digest of the application typed data, which is passed to isValidSignature of the account.
+func (n *NestedTypedData) Hash() [32]byte {
+	return TypedDataHash(n.AppDomain, n.ContentsHash)
+}
+
+// TypedDataSignHash returns the ERC-7739 TypedDataSign digest the owner of the account signs,
+// given the EIP-712 domain of the account (whose verifying contract is the account) and its salt.
+func (n *NestedTypedData) TypedDataSignHash(account TypedDataDomain, salt [32]byte) ([32]byte, error) {
+	return erc7739TypedDataSignHash(n.AppDomain.Separator(), n.ContentsType, n.ContentsHash, account, salt)
+}
+
+// WrapERC7739Signature wraps the owner's signature over the TypedDataSign digest as the account expects it
+// (signature ‖ appDomainSeparator ‖ contentsHash ‖ contentsType ‖ uint16(len(contentsType))).
+func WrapERC7739Signature(sig []byte, n NestedTypedData) []byte {
+	appDomainSeparator := n.AppDomain.Separator()
+
+	var contentsTypeLen [2]byte
+	binary.BigEndian.PutUint16(contentsTypeLen[:], uint16(len(n.ContentsType)))
+
+	wrapped := append([]byte{}, sig...)
+	wrapped = append(wrapped, appDomainSeparator[:]...)
+	wrapped = append(wrapped, n.ContentsHash[:]...)
+	wrapped = append(wrapped, n.ContentsType...)
+	return append(wrapped, contentsTypeLen[:]...)
+}
+
+// IsAuthorizedNestedTypedData checks if the smart account is an authorized signer for the ERC-7739 wrapped signature
+// of the nested typed data, via isValidSignature of the account (which rehashes the TypedDataSign digest).
+func (a *Authenticator) IsAuthorizedNestedTypedData(n NestedTypedData, signature, addrHex string) (bool, error) {
+
+	if _, err := erc7739ContentsName(n.ContentsType); err != nil {
+		return false, err
+	}
+
+	sigBytes, err := a.signatureBytes(signature)
+	if err != nil {
+		return false, err
+	}
+
+	addr, err := a.normalizeAddress(addrHex)
+	if err != nil {
+		return false, err
+	}
+
+	return authorized(a.verifyContractHash(n.Hash(), nil, sigBytes, addr))
+}
+
+func erc7739TypedDataSignHash(appDomainSeparator [32]byte, contentsType string, contentsHash [32]byte, account TypedDataDomain, salt [32]byte) ([32]byte, error) {
+	var hash [32]byte
+
+	contentsName, err := erc7739ContentsName(contentsType)
+	if err != nil {
+		return hash, err
+	}
+
+	typeHash := ethCrypto.Keccak256([]byte("TypedDataSign(" + contentsName + " contents,string name,string version,uint256 chainId,address verifyingContract,bytes32 salt)" + contentsType))
+	structHash := ethCrypto.Keccak256(
+		typeHash,
+		contentsHash[:],
+		ethCrypto.Keccak256([]byte(account.Name)),
+		ethCrypto.Keccak256([]byte(account.Version)),
+		encodeUint256(account.ChainID),
+		encodeAddress(account.VerifyingContract),
+		salt[:],
+	)
+
+	copy(hash[:], eip191Hash(EIP191VersionStructuredData, appDomainSeparator[:], structHash))
+	return hash, nil
+}
+
+// erc7739ContentsName returns the name of the contents type, i.e. the name of its first (primary) type.
+func erc7739ContentsName(contentsType string) (string, error) {
+	i := strings.IndexByte(contentsType, '(')
+	if i <= 0 || !strings.HasSuffix(contentsType, ")") || strings.ContainsAny(contentsType[:i], ", )\x00") {
+		return "", ErrInvalidContentsType
+	}
+	return contentsType[:i], nil
+}
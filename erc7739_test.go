@@ -0,0 +1,96 @@
+package dappauth
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestNestedTypedData(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyC, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrC := ethCrypto.PubkeyToAddress(keyC.PublicKey)
+
+	nested := NestedTypedData{
+		AppDomain:    TypedDataDomain{Name: "Ether Mail", Version: "1", ChainID: big.NewInt(1), VerifyingContract: common.HexToAddress("0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC")},
+		ContentsType: "Mail(address to,string contents)",
+		ContentsHash: [32]byte{1, 2, 3},
+	}
+	accountA := TypedDataDomain{Name: "Account", Version: "1", ChainID: big.NewInt(1), VerifyingContract: addrA}
+	accountC := TypedDataDomain{Name: "Account", Version: "1", ChainID: big.NewInt(1), VerifyingContract: addrC}
+
+	t.Run("Smart accounts should be authorized signers of nested typed data", func(t *testing.T) {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, hashScheme: HashSchemeRaw, erc7739Domain: &accountA}
+		authenticator := NewAuthenticator(nil, mock)
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedNestedTypedData(nested, signNestedTypedData(nested, accountA, keyB, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	t.Run("Smart accounts should NOT be authorized signers of nested typed data signed for another account", func(t *testing.T) {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, hashScheme: HashSchemeRaw, erc7739Domain: &accountA}
+		authenticator := NewAuthenticator(nil, mock)
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedNestedTypedData(nested, signNestedTypedData(nested, accountC, keyB, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, false, t)
+	})
+
+	t.Run("Smart accounts should NOT be authorized signers of other nested typed data", func(t *testing.T) {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, hashScheme: HashSchemeRaw, erc7739Domain: &accountA}
+		authenticator := NewAuthenticator(nil, mock)
+		other := nested
+		other.ContentsHash = [32]byte{4, 5, 6}
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedNestedTypedData(other, signNestedTypedData(nested, accountA, keyB, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, false, t)
+	})
+
+	t.Run("Invalid contents types should error", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{})
+		invalid := nested
+		invalid.ContentsType = "Mail"
+
+		_, err := authenticator.IsAuthorizedNestedTypedData(invalid, signNestedTypedData(nested, accountA, keyB, t), addrA.Hex())
+		expectBool(err == ErrInvalidContentsType, true, t)
+	})
+}
+
+// computes the ERC-7739 TypedDataSign digest independently of TypedDataSignHash
+func typedDataSignHash(nested NestedTypedData, account TypedDataDomain) []byte {
+	appDomainSeparator := nested.AppDomain.Separator()
+	structHash := ethCrypto.Keccak256(
+		ethCrypto.Keccak256([]byte("TypedDataSign(Mail contents,string name,string version,uint256 chainId,address verifyingContract,bytes32 salt)Mail(address to,string contents)")),
+		nested.ContentsHash[:],
+		ethCrypto.Keccak256([]byte(account.Name)),
+		ethCrypto.Keccak256([]byte(account.Version)),
+		common.LeftPadBytes(account.ChainID.Bytes(), 32),
+		common.LeftPadBytes(account.VerifyingContract.Bytes(), 32),
+		make([]byte, 32),
+	)
+	return ethCrypto.Keccak256([]byte{0x19, 0x01}, appDomainSeparator[:], structHash)
+}
+
+func signNestedTypedData(nested NestedTypedData, account TypedDataDomain, key *ecdsa.PrivateKey, t *testing.T) string {
+	digest, err := nested.TypedDataSignHash(account, [32]byte{})
+	checkError(err, t)
+	expectBool(bytes.Equal(digest[:], typedDataSignHash(nested, account)), true, t)
+
+	sig, err := hex.DecodeString(signRawHash(digest[:], key, t))
+	checkError(err, t)
+	return hex.EncodeToString(WrapERC7739Signature(sig, nested))
+}
@@ -0,0 +1,45 @@
+package dappauth
+
+import (
+	"crypto/rand"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// MinChallengeLength is the minimum length, in bytes, of generated challenges, for them to be unguessable.
+const MinChallengeLength = 16
+
+// ErrChallengeTooShort is returned when generating a challenge shorter than MinChallengeLength.
+var ErrChallengeTooShort = errors.New("dappauth: challenge too short")
+
+// GenerateChallenge generates a cryptographically random challenge of byteLen bytes, hex encoded with the 0x prefix.
+func GenerateChallenge(byteLen int) (string, error) {
+	if byteLen < MinChallengeLength {
+		return "", ErrChallengeTooShort
+	}
+
+	challenge := make([]byte, byteLen)
+	if _, err := rand.Read(challenge); err != nil {
+		return "", err
+	}
+	return hexutil.Encode(challenge), nil
+}
+
+// GenerateExpiringChallenge is like GenerateChallenge but prefixes the challenge with its expiry (RFC 3339, UTC),
+// e.g. "2020-01-01T12:05:00Z 0x8f3a…", which ExpiringChallengeExpiry extracts.
+func GenerateExpiringChallenge(byteLen int, expiry time.Time) (string, error) {
+	challenge, err := GenerateChallenge(byteLen)
+	if err != nil {
+		return "", err
+	}
+	return expiry.UTC().Format(time.RFC3339) + " " + challenge, nil
+}
+
+// ExpiringChallengeExpiry is the ChallengeExpiryExtractor of challenges generated by GenerateExpiringChallenge.
+func ExpiringChallengeExpiry(message []byte) (time.Time, error) {
+	expiry := strings.SplitN(string(message), " ", 2)[0]
+	return time.Parse(time.RFC3339, expiry)
+}
@@ -0,0 +1,59 @@
+package dappauth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestGenerateChallenge(t *testing.T) {
+
+	t.Run("Challenges should be hex encoded random bytes of the requested length", func(t *testing.T) {
+		seen := map[string]bool{}
+		for i := 0; i < 100; i++ {
+			challenge, err := GenerateChallenge(32)
+			checkError(err, t)
+
+			decoded, err := hexutil.Decode(challenge)
+			checkError(err, t)
+			expectBool(len(decoded) == 32, true, t)
+
+			expectBool(seen[challenge], false, t)
+			seen[challenge] = true
+		}
+	})
+
+	t.Run("Challenges shorter than MinChallengeLength should error", func(t *testing.T) {
+		_, err := GenerateChallenge(MinChallengeLength - 1)
+		expectBool(err == ErrChallengeTooShort, true, t)
+	})
+
+	t.Run("Expiring challenges should expire", func(t *testing.T) {
+		key, err := ethCrypto.GenerateKey()
+		checkError(err, t)
+		addr := ethCrypto.PubkeyToAddress(key.PublicKey)
+
+		now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+		challenge, err := GenerateExpiringChallenge(32, now.Add(5*time.Minute))
+		checkError(err, t)
+
+		sig := signEOAPersonalMessage(challenge, key, t)
+		for _, test := range []struct {
+			now         time.Time
+			expectedErr error
+		}{
+			{now, nil},
+			{now.Add(10 * time.Minute), ErrChallengeExpired},
+		} {
+			authenticator := NewAuthenticator(nil, &mockContract{},
+				WithChallengeExpiryExtractor(ExpiringChallengeExpiry),
+				WithClock(func() time.Time { return test.now }))
+
+			isAuthorizedSigner, err := authenticator.IsAuthorizedSigner(challenge, sig, addr.Hex())
+			expectBool(err == test.expectedErr, true, t)
+			expectBool(isAuthorizedSigner, test.expectedErr == nil, t)
+		}
+	})
+}
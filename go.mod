@@ -1,6 +1,6 @@
 module github.com/dapperlabs/dappauth
 
-go 1.12
+go 1.13
 
 require (
 	github.com/allegro/bigcache v1.2.0 // indirect
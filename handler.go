@@ -0,0 +1,126 @@
+package dappauth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// VerifyRequest is the JSON request body of VerifyJSON.
+type VerifyRequest struct {
+	Challenge string `json:"challenge"`
+	Signature string `json:"signature"`
+	Address   string `json:"address"`
+}
+
+// VerifyResponse is the JSON response body of VerifyJSON.
+type VerifyResponse struct {
+	Authorized       bool   `json:"authorized"`
+	RecoveredAddress string `json:"recoveredAddress,omitempty"`
+	Method           string `json:"method"`
+	Error            string `json:"error,omitempty"`
+}
+
+// VerifyJSON verifies the JSON encoded VerifyRequest, returning the JSON encoded VerifyResponse and its HTTP status:
+// 200 if authorized, 401 if not authorized (or the challenge is expired, not yet valid or not bound to the address, or
+// the address is neither the signer nor a smart-contract wallet implementing a supported interface),
+// 400 if the request is malformed and 500 if the verification failed (e.g. a contract call), whose error isn't disclosed.
+// It is handler-shaped, for wiring into net/http:
+//
+//	body, _ := ioutil.ReadAll(r.Body)
+//	response, status := authenticator.VerifyJSON(body)
+//	w.Header().Set("Content-Type", "application/json")
+//	w.WriteHeader(status)
+//	w.Write(response)
+func (a *Authenticator) VerifyJSON(requestBody []byte) (responseBody []byte, status int) {
+
+	var request VerifyRequest
+	if err := json.Unmarshal(requestBody, &request); err != nil {
+		return verifyResponse(VerifyResponse{Method: PathNone.String(), Error: "malformed request: " + err.Error()}, http.StatusBadRequest)
+	}
+	if request.Challenge == "" || request.Signature == "" || !a.isValidAddress(request.Address) {
+		return verifyResponse(VerifyResponse{Method: PathNone.String(), Error: "malformed request: challenge, signature and address are required"}, http.StatusBadRequest)
+	}
+
+	result, err := a.Verify(request.Challenge, request.Signature, request.Address)
+	if err != nil {
+		return verifyResponse(VerifyResponse{Method: PathNone.String(), Error: verifyErrorMessage(err)}, verifyErrorStatus(err))
+	}
+
+	response := VerifyResponse{Authorized: result.Authorized, Method: result.Path.String()}
+	if result.RecoveredAddress != (common.Address{}) {
+		response.RecoveredAddress = result.RecoveredAddress.Hex()
+	}
+	if !result.Authorized {
+		return verifyResponse(response, http.StatusUnauthorized)
+	}
+	return verifyResponse(response, http.StatusOK)
+}
+
+// the errors of malformed requests
+var _badRequestErrors = []error{
+	ErrInvalidSignatureHex,
+	ErrInvalidSignatureLength,
+	ErrInvalidRecoveryID,
+	ErrInvalidSignature,
+	ErrInvalidAddress,
+	ErrUnresolvedName,
+	ErrInvalidChallengeEncoding,
+	ErrInvalidChallengeDigest,
+	ErrChallengeTooShort,
+	ErrTooManySignatures,
+}
+
+// the errors of well-formed requests which aren't authorized, including by wallets which can't authorize
+var _unauthorizedErrors = []error{
+	ErrChallengeExpired,
+	ErrChallengeNotYetValid,
+	ErrAddressNotBound,
+	ErrAddressFiltered,
+	ErrHighS,
+	ErrNoContractCode,
+	ErrUnsupportedWalletInterface,
+	ErrWalletKindMismatch,
+}
+
+func verifyErrorStatus(err error) int {
+	for _, target := range _badRequestErrors {
+		if errors.Is(err, target) {
+			return http.StatusBadRequest
+		}
+	}
+	for _, target := range _unauthorizedErrors {
+		if errors.Is(err, target) {
+			return http.StatusUnauthorized
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// verifyErrorMessage returns the error of the request, or a generic message if the verification failed, as the error
+// of contract calls and backends may disclose internals (e.g. the RPC endpoint).
+func verifyErrorMessage(err error) string {
+	if verifyErrorStatus(err) == http.StatusInternalServerError {
+		return "verification failed"
+	}
+	return err.Error()
+}
+
+func verifyResponse(response VerifyResponse, status int) ([]byte, int) {
+	responseBody, err := json.Marshal(response)
+	if err != nil {
+		return []byte(`{"authorized":false,"method":"none","error":"encoding response"}`), http.StatusInternalServerError
+	}
+	return responseBody, status
+}
+
+// isValidAddress returns true if the address argument is a hex address or, if an address normalizer is set, normalizes.
+func (a *Authenticator) isValidAddress(addr string) bool {
+	if a.addressNormalizer == nil {
+		return common.IsHexAddress(addr)
+	}
+	_, err := a.normalizeAddress(addr)
+	return err == nil
+}
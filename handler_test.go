@@ -0,0 +1,153 @@
+package dappauth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestVerifyJSON(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrB := ethCrypto.PubkeyToAddress(keyB.PublicKey)
+	sig := signEOAPersonalMessage("foo", keyA, t)
+
+	jsonTests := []struct {
+		title            string
+		mock             *mockContract
+		requestBody      string
+		expectedStatus   int
+		expectedResponse VerifyResponse
+		expectedError    bool
+		bound            bool
+	}{
+		{
+			"Authorized signers should respond 200",
+			&mockContract{},
+			fmt.Sprintf(`{"challenge":"foo","signature":"%s","address":"%s"}`, sig, addrA.Hex()),
+			http.StatusOK,
+			VerifyResponse{Authorized: true, RecoveredAddress: addrA.Hex(), Method: "EOA"},
+			false,
+			false,
+		},
+		{
+			"Unauthorized signers should respond 401",
+			&mockContract{},
+			fmt.Sprintf(`{"challenge":"foo","signature":"%s","address":"%s"}`, sig, addrB.Hex()),
+			http.StatusUnauthorized,
+			VerifyResponse{RecoveredAddress: addrA.Hex(), Method: "contract"},
+			false,
+			false,
+		},
+		{
+			"Malformed JSON should respond 400",
+			&mockContract{},
+			`{"challenge":"foo",`,
+			http.StatusBadRequest,
+			VerifyResponse{Method: "none"},
+			true,
+			false,
+		},
+		{
+			"Requests without an address should respond 400",
+			&mockContract{},
+			fmt.Sprintf(`{"challenge":"foo","signature":"%s"}`, sig),
+			http.StatusBadRequest,
+			VerifyResponse{Method: "none"},
+			true,
+			false,
+		},
+		{
+			"Failed verifications should respond 500",
+			&mockContract{errorIsValidSignature: true},
+			fmt.Sprintf(`{"challenge":"foo","signature":"%s","address":"%s"}`, sig, addrB.Hex()),
+			http.StatusInternalServerError,
+			VerifyResponse{Method: "none"},
+			true,
+			false,
+		},
+		{
+			"Signatures exceeding the max multi-sig signatures should respond 400",
+			&mockContract{},
+			fmt.Sprintf(`{"challenge":"foo","signature":"%s","address":"%s"}`, strings.Repeat(sig, defaultMaxMultisigSignatures+1), addrA.Hex()),
+			http.StatusBadRequest,
+			VerifyResponse{Method: "none"},
+			true,
+			false,
+		},
+		{
+			"Challenges not bound to the address should respond 401",
+			&mockContract{},
+			fmt.Sprintf(`{"challenge":"foo","signature":"%s","address":"%s"}`, sig, addrA.Hex()),
+			http.StatusUnauthorized,
+			VerifyResponse{Method: "none"},
+			true,
+			true,
+		},
+		{
+			"Addresses neither signing nor having code should respond 401",
+			&mockContract{address: addrB, revertSelectors: []string{"1626ba7e", "20c13b0b"}},
+			fmt.Sprintf(`{"challenge":"foo","signature":"%s","address":"%s"}`, sig, addrB.Hex()),
+			http.StatusUnauthorized,
+			VerifyResponse{Method: "none"},
+			true,
+			false,
+		},
+		{
+			"Wallets implementing no supported interface should respond 401",
+			&mockContract{address: addrB, code: []byte{0x60}, revertSelectors: []string{"1626ba7e", "20c13b0b"}},
+			fmt.Sprintf(`{"challenge":"foo","signature":"%s","address":"%s"}`, sig, addrB.Hex()),
+			http.StatusUnauthorized,
+			VerifyResponse{Method: "none"},
+			true,
+			false,
+		},
+	}
+
+	for _, test := range jsonTests {
+		t.Run(test.title, func(t *testing.T) {
+			authenticator := NewAuthenticator(nil, test.mock, WithAddressBinding(test.bound))
+
+			responseBody, status := authenticator.VerifyJSON([]byte(test.requestBody))
+			expectBool(status == test.expectedStatus, true, t)
+
+			var response VerifyResponse
+			checkError(json.Unmarshal(responseBody, &response), t)
+			expectBool(response.Error != "", test.expectedError, t)
+			if status == http.StatusInternalServerError {
+				expectBool(response.Error == "verification failed", true, t)
+			}
+			response.Error = ""
+			expectBool(response == test.expectedResponse, true, t)
+		})
+	}
+}
+
+func TestVerifyErrorStatus(t *testing.T) {
+
+	statusTests := []struct {
+		title          string
+		err            error
+		expectedStatus int
+	}{
+		{"Addresses without code should be unauthorized", &contractError{kind: ErrNoContractCode, err: errDummy}, http.StatusUnauthorized},
+		{"Wallets implementing no supported interface should be unauthorized", fmt.Errorf("wrapped: %w", ErrUnsupportedWalletInterface), http.StatusUnauthorized},
+		{"Wallets of another kind than claimed should be unauthorized", ErrWalletKindMismatch, http.StatusUnauthorized},
+		{"Failed contract calls should be server errors", contractCallFailed(errDummy), http.StatusInternalServerError},
+	}
+
+	for _, test := range statusTests {
+		t.Run(test.title, func(t *testing.T) {
+			expectBool(verifyErrorStatus(test.err) == test.expectedStatus, true, t)
+		})
+	}
+}
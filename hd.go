@@ -0,0 +1,127 @@
+package dappauth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	// ErrInvalidExtendedKey is returned when the extended public key isn't a valid base58check encoded BIP-32 xpub/tpub.
+	ErrInvalidExtendedKey = errors.New("dappauth: invalid extended public key")
+	// ErrHardenedIndex is returned when deriving a hardened index, which requires the extended private key.
+	ErrHardenedIndex = errors.New("dappauth: hardened index can't be derived from an extended public key")
+)
+
+// the BIP-32 version bytes of mainnet (xpub) and testnet (tpub) extended public keys
+var _extendedPublicKeyVersions = [][4]byte{{0x04, 0x88, 0xb2, 0x1e}, {0x04, 0x35, 0x87, 0xcf}}
+
+const _base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// DeriveHDAddress derives the address at the (non-hardened) child index of the BIP-32 extended public key, e.g. the
+// account level xpub of a custody system tracking its addresses by index (m/44'/60'/0'/0 → m/44'/60'/0'/0/index).
+func DeriveHDAddress(xpub string, index uint32) (common.Address, error) {
+	if index >= 1<<31 {
+		return common.Address{}, ErrHardenedIndex
+	}
+
+	chainCode, parentKey, err := parseExtendedPublicKey(xpub)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	// CKDpub: I = HMAC-SHA512(chain code, serP(K) ‖ ser32(index)), K_child = parse256(I_L)·G + K
+	var serIndex [4]byte
+	binary.BigEndian.PutUint32(serIndex[:], index)
+	mac := hmac.New(sha512.New, chainCode)
+	mac.Write(parentKey)
+	mac.Write(serIndex[:])
+	il := mac.Sum(nil)[:32]
+
+	parent, err := ethCrypto.DecompressPubkey(parentKey)
+	if err != nil {
+		return common.Address{}, ErrInvalidExtendedKey
+	}
+
+	curve := ethCrypto.S256()
+	if new(big.Int).SetBytes(il).Cmp(curve.Params().N) >= 0 {
+		return common.Address{}, fmt.Errorf("dappauth: index %d derives an invalid key", index)
+	}
+	x, y := curve.ScalarBaseMult(il)
+	x, y = curve.Add(x, y, parent.X, parent.Y)
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return common.Address{}, fmt.Errorf("dappauth: index %d derives an invalid key", index)
+	}
+
+	pub := make([]byte, 65)
+	pub[0] = 0x04
+	copy(pub[1:33], common.LeftPadBytes(x.Bytes(), 32))
+	copy(pub[33:], common.LeftPadBytes(y.Bytes(), 32))
+	return common.BytesToAddress(ethCrypto.Keccak256(pub[1:])[12:]), nil
+}
+
+// IsAuthorizedSignerHD is like IsAuthorizedSigner but verifies against the address at the child index of the extended
+// public key (see DeriveHDAddress).
+func (a *Authenticator) IsAuthorizedSignerHD(challenge, signature, xpub string, index uint32) (bool, error) {
+	addr, err := DeriveHDAddress(xpub, index)
+	if err != nil {
+		return false, err
+	}
+
+	return a.IsAuthorizedSignerAddr(challenge, signature, addr)
+}
+
+// parseExtendedPublicKey returns the chain code and compressed public key of the extended public key.
+func parseExtendedPublicKey(xpub string) (chainCode, key []byte, err error) {
+	// version (4) ‖ depth (1) ‖ parent fingerprint (4) ‖ child number (4) ‖ chain code (32) ‖ key (33) ‖ checksum (4)
+	decoded, ok := base58Decode(xpub)
+	if !ok || len(decoded) != 82 {
+		return nil, nil, ErrInvalidExtendedKey
+	}
+
+	payload, checksum := decoded[:78], decoded[78:]
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	if !bytes.Equal(checksum, second[:4]) {
+		return nil, nil, ErrInvalidExtendedKey
+	}
+
+	var version [4]byte
+	copy(version[:], payload[:4])
+	knownVersion := false
+	for _, v := range _extendedPublicKeyVersions {
+		knownVersion = knownVersion || v == version
+	}
+	if !knownVersion || (payload[45] != 0x02 && payload[45] != 0x03) {
+		return nil, nil, ErrInvalidExtendedKey
+	}
+
+	return payload[13:45], payload[45:78], nil
+}
+
+func base58Decode(s string) ([]byte, bool) {
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for _, c := range []byte(s) {
+		digit := bytes.IndexByte([]byte(_base58Alphabet), c)
+		if digit < 0 {
+			return nil, false
+		}
+		n.Mul(n, radix).Add(n, big.NewInt(int64(digit)))
+	}
+
+	// each leading '1' encodes a leading zero byte
+	leadingZeros := 0
+	for leadingZeros < len(s) && s[leadingZeros] == _base58Alphabet[0] {
+		leadingZeros++
+	}
+	return append(make([]byte, leadingZeros), n.Bytes()...), true
+}
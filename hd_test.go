@@ -0,0 +1,121 @@
+package dappauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// BIP-32 test vector 2
+const (
+	_hdMasterXpub = "xpub661MyMwAqRbcFW31YEwpkMuc5THy2PSt5bDMsktWQcFF8syAmRUapSCGu8ED9W6oDMSgv6Zz8idoc4a6mr8BDzTJY47LJhkJ8UB7WEGuduB"
+	_hdMasterXprv = "xprv9s21ZrQH143K31xYSDQpPDxsXRTUcvj2iNHm5NUtrGiGG5e2DtALGdso3pGz6ssrdK4PFmM8NSpSBHNqPqm55Qn3LqFtT2emdEXVYsCzC2U"
+	_hdChild0Xpub = "xpub69H7F5d8KSRgmmdJg2KhpAK8SR3DjMwAdkxj3ZuxV27CprR9LgpeyGmXUbC6wb7ERfvrnKZjXoUmmDznezpbZb7ap6r1D3tgFxHmwMkQTPH"
+)
+
+func TestDeriveHDAddress(t *testing.T) {
+
+	t.Run("The derived address should be that of the reference child public key", func(t *testing.T) {
+		_, childKey, err := parseExtendedPublicKey(_hdChild0Xpub)
+		checkError(err, t)
+		childPub, err := ethCrypto.DecompressPubkey(childKey)
+		checkError(err, t)
+
+		addr, err := DeriveHDAddress(_hdMasterXpub, 0)
+		checkError(err, t)
+		expectBool(addr == ethCrypto.PubkeyToAddress(*childPub), true, t)
+	})
+
+	t.Run("Distinct indices should derive distinct addresses", func(t *testing.T) {
+		addr0, err := DeriveHDAddress(_hdMasterXpub, 0)
+		checkError(err, t)
+		addr1, err := DeriveHDAddress(_hdMasterXpub, 1)
+		checkError(err, t)
+		expectBool(addr0 == addr1, false, t)
+	})
+
+	t.Run("Hardened indices should error", func(t *testing.T) {
+		_, err := DeriveHDAddress(_hdMasterXpub, 1<<31)
+		expectBool(err == ErrHardenedIndex, true, t)
+	})
+
+	invalidKeys := []struct {
+		title string
+		xpub  string
+	}{
+		{"Extended private keys should error", _hdMasterXprv},
+		{"Keys with an invalid checksum should error", _hdMasterXpub[:len(_hdMasterXpub)-1] + "C"},
+		{"Keys which are not base58 should error", "0OIl"},
+		{"Truncated keys should error", _hdMasterXpub[:80]},
+	}
+
+	for _, test := range invalidKeys {
+		t.Run(test.title, func(t *testing.T) {
+			_, err := DeriveHDAddress(test.xpub, 0)
+			expectBool(err == ErrInvalidExtendedKey, true, t)
+		})
+	}
+}
+
+func TestIsAuthorizedSignerHD(t *testing.T) {
+
+	authenticator := NewAuthenticator(nil, &mockContract{})
+
+	key0 := deriveHDPrivateKey(_hdMasterXprv, 0, t)
+	key1 := deriveHDPrivateKey(_hdMasterXprv, 1, t)
+
+	hdTests := []struct {
+		title    string
+		key      *ecdsa.PrivateKey
+		index    uint32
+		expected bool
+	}{
+		{"The key at index 0 should be an authorized signer for index 0", key0, 0, true},
+		{"The key at index 1 should be an authorized signer for index 1", key1, 1, true},
+		{"The key at index 0 should NOT be an authorized signer for index 1", key0, 1, false},
+		{"The key at index 1 should NOT be an authorized signer for index 0", key1, 0, false},
+	}
+
+	for _, test := range hdTests {
+		t.Run(test.title, func(t *testing.T) {
+			isAuthorizedSigner, err := authenticator.IsAuthorizedSignerHD("foo", signEOAPersonalMessage("foo", test.key, t), _hdMasterXpub, test.index)
+			checkError(err, t)
+			expectBool(isAuthorizedSigner, test.expected, t)
+		})
+	}
+
+	t.Run("Invalid extended public keys should error", func(t *testing.T) {
+		_, err := authenticator.IsAuthorizedSignerHD("foo", signEOAPersonalMessage("foo", key0, t), _hdMasterXprv, 0)
+		expectBool(err == ErrInvalidExtendedKey, true, t)
+	})
+}
+
+// deriveHDPrivateKey derives the private key at the non-hardened child index of the extended private key (CKDpriv).
+func deriveHDPrivateKey(xprv string, index uint32, t *testing.T) *ecdsa.PrivateKey {
+	decoded, ok := base58Decode(xprv)
+	expectBool(ok && len(decoded) == 82, true, t)
+	chainCode, parentPriv := decoded[13:45], decoded[46:78]
+
+	parent, err := ethCrypto.ToECDSA(parentPriv)
+	checkError(err, t)
+
+	var serIndex [4]byte
+	binary.BigEndian.PutUint32(serIndex[:], index)
+	mac := hmac.New(sha512.New, chainCode)
+	mac.Write(ethCrypto.CompressPubkey(&parent.PublicKey))
+	mac.Write(serIndex[:])
+
+	n := ethCrypto.S256().Params().N
+	child := new(big.Int).SetBytes(mac.Sum(nil)[:32])
+	child.Add(child, parent.D).Mod(child, n)
+
+	key, err := ethCrypto.ToECDSA(common.LeftPadBytes(child.Bytes(), 32))
+	checkError(err, t)
+	return key
+}
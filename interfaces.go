@@ -0,0 +1,361 @@
+package dappauth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/dapperlabs/dappauth/ERCs"
+	"github.com/ethereum/go-ethereum"
+	ethAbi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	_ERC1271LegacyMagicValue = [4]byte{32, 193, 59, 11} // 0x20c13b0b
+
+	_ERC1271ABI       = mustABI(ERCs.ERC1271ABI)
+	_ERC1271LegacyABI = mustABI(ERCs.ERC1271LegacyABI)
+
+	// ErrUnsupportedWalletInterface is returned when the address has code but implements none of the configured wallet interfaces.
+	ErrUnsupportedWalletInterface = errors.New("dappauth: wallet implements no supported signature verification interface")
+
+	// ErrNoContractCode is returned when there is no code at the address verified as a smart-contract wallet, which usually
+	// means the client is on another network than the backend (or the wallet isn't deployed yet). Matches bind.ErrNoCode.
+	ErrNoContractCode = errors.New("dappauth: no contract code at address")
+
+	// ErrContractCallFailed is returned when a call to the backend failed (e.g. a transport error) rather than the contract
+	// responding. The error of the backend is wrapped.
+	ErrContractCallFailed = errors.New("dappauth: contract call failed")
+
+	// errReverted marks a contract call which reverted (or returned nothing), i.e. the interface isn't implemented.
+	errReverted = errors.New("dappauth: contract call reverted")
+)
+
+// WalletInterface is a signature verification interface of smart-contract wallets.
+type WalletInterface int
+
+const (
+	// InterfaceERC1654 is isValidSignature(bytes32 hash, bytes signature) returning 0x1626ba7e,
+	// as proposed by ERC-1654 and adopted by the final ERC-1271.
+	InterfaceERC1654 WalletInterface = iota
+	// InterfaceERC1271 is isValidSignature(bytes data, bytes signature) returning 0x20c13b0b,
+	// as proposed by the original (draft) ERC-1271. The data is the challenge message itself.
+	InterfaceERC1271
+	// InterfaceContractABI is the verification method of the wallet's ABI set by WithContractABI.
+	InterfaceContractABI
+)
+
+func (i WalletInterface) String() string {
+	switch i {
+	case InterfaceERC1654:
+		return "ERC-1654"
+	case InterfaceERC1271:
+		return "ERC-1271"
+	case InterfaceContractABI:
+		return "contract ABI"
+	default:
+		return "unknown"
+	}
+}
+
+var defaultWalletInterfaces = []WalletInterface{InterfaceERC1654, InterfaceERC1271}
+
+// WithConcurrentInterfaceProbe makes the wallet interfaces of smart-contract wallets be attempted concurrently rather
+// than in order, returning as soon as one authorizes the signature and cancelling the others. Trades RPC volume for
+// latency when the interface of wallets is unknown (see also WithInterfaceCache).
+func WithConcurrentInterfaceProbe(concurrent bool) Option {
+	return func(a *Authenticator) {
+		a.concurrentInterfaceProbe = concurrent
+	}
+}
+
+// WithWalletInterfaces sets the wallet interfaces attempted, in order, for smart-contract wallets
+// (default = InterfaceERC1654 then InterfaceERC1271). The next interface is only attempted if the call reverts.
+func WithWalletInterfaces(interfaces ...WalletInterface) Option {
+	return func(a *Authenticator) {
+		if len(interfaces) > 0 {
+			a.walletInterfaces = interfaces
+		}
+	}
+}
+
+// WithLenientMagicValue makes the magic value returned by isValidSignature of smart-contract wallets be checked as the
+// first 4 bytes of the return, tolerating wallets which return additional data after it. By default the return must
+// be exactly the ABI encoded bytes4.
+func WithLenientMagicValue(lenient bool) Option {
+	return func(a *Authenticator) {
+		a.lenientMagicValue = lenient
+	}
+}
+
+// WithRevertAsUnauthorized makes smart-contract wallets which revert on every wallet interface (e.g. wallets reverting
+// rather than returning a non-magic value for invalid signatures) be unauthorized rather than error with
+// ErrUnsupportedWalletInterface. Transport errors still error with ErrContractCallFailed.
+func WithRevertAsUnauthorized(revertAsUnauthorized bool) Option {
+	return func(a *Authenticator) {
+		a.revertAsUnauthorized = revertAsUnauthorized
+	}
+}
+
+// isValidSignature asks the smart-contract wallet if the signature is valid, attempting the configured interfaces in order.
+// message is the challenge message (for interfaces taking the data rather than its hash). gasUsed is the gas used by the
+// call which answered, if the backend reports it (see GasReporter).
+func (a *Authenticator) isValidSignature(addr common.Address, hash [32]byte, message, sig []byte) (isValid bool, gasUsed uint64, err error) {
+	if a.cc == nil {
+		return false, 0, ErrNoBackend
+	}
+
+	// share the result of identical calls within a batch
+	if a.callCoalescer != nil {
+		return a.callCoalescer.do(callKey(addr, hash, message, sig, a.callBlock), func() (bool, uint64, error) {
+			return a.probeIsValidSignature(addr, hash, message, sig)
+		})
+	}
+
+	return a.probeIsValidSignature(addr, hash, message, sig)
+}
+
+func (a *Authenticator) probeIsValidSignature(addr common.Address, hash [32]byte, message, sig []byte) (bool, uint64, error) {
+
+	// go straight to the interface the wallet is known to implement
+	if walletInterface, ok := a.interfaceCache.get(addr, a.now()); ok {
+		isValid, gasUsed, err := a.isValidSignatureVia(walletInterface, addr, hash, message, sig)
+		if err != errReverted {
+			return isValid, gasUsed, err
+		}
+		a.interfaceCache.delete(addr)
+	}
+
+	probe := a.probeInterfaces
+	if a.concurrentInterfaceProbe && len(a.walletInterfaces) > 1 {
+		probe = a.probeInterfacesConcurrently
+	}
+	if isValid, gasUsed, implemented, err := probe(addr, hash, message, sig); implemented {
+		return isValid, gasUsed, err
+	}
+
+	// none implemented, which is expected when the address isn't a contract
+	code, err := a.codeAt(addr)
+	if err != nil {
+		return false, 0, contractCallFailed(err)
+	}
+	if len(code) == 0 {
+		return false, 0, &contractError{kind: ErrNoContractCode, err: bind.ErrNoCode}
+	}
+	if isValid, gasUsed, implemented, err := a.retryRevertedProbe(probe, addr, hash, message, sig); implemented {
+		return isValid, gasUsed, err
+	}
+	if a.revertAsUnauthorized {
+		return false, 0, nil
+	}
+	return false, 0, ErrUnsupportedWalletInterface
+}
+
+// probeInterfaces attempts the interfaces in order, until one doesn't revert (implemented = false if all revert).
+func (a *Authenticator) probeInterfaces(addr common.Address, hash [32]byte, message, sig []byte) (isValid bool, gasUsed uint64, implemented bool, err error) {
+	for _, walletInterface := range a.walletInterfaces {
+		// don't probe further once cancelled
+		if err := a.context().Err(); err != nil {
+			return false, 0, true, err
+		}
+
+		isValid, gasUsed, err := a.isValidSignatureVia(walletInterface, addr, hash, message, sig)
+		if err == errReverted {
+			continue
+		}
+		if err == nil {
+			a.interfaceCache.put(addr, walletInterface, a.now())
+		}
+		return isValid, gasUsed, true, err
+	}
+	return false, 0, false, nil
+}
+
+type probeResult struct {
+	isValid bool
+	gasUsed uint64
+	err     error
+}
+
+// probeInterfacesConcurrently attempts the interfaces concurrently, returning as soon as one authorizes the signature
+// (cancelling the others). Otherwise the result is that of the first interface, in order, which doesn't revert.
+func (a *Authenticator) probeInterfacesConcurrently(addr common.Address, hash [32]byte, message, sig []byte) (isValid bool, gasUsed uint64, implemented bool, err error) {
+	ctx, cancel := context.WithCancel(a.context())
+	defer cancel()
+
+	probe := *a
+	probe.ctx = ctx
+
+	results := make([]probeResult, len(a.walletInterfaces))
+	done := make(chan int, len(a.walletInterfaces))
+	for i, walletInterface := range a.walletInterfaces {
+		go func(i int, walletInterface WalletInterface) {
+			r := &results[i]
+			r.isValid, r.gasUsed, r.err = probe.isValidSignatureVia(walletInterface, addr, hash, message, sig)
+			done <- i
+		}(i, walletInterface)
+	}
+
+	for range a.walletInterfaces {
+		i := <-done
+		if r := results[i]; r.err == nil && r.isValid {
+			a.interfaceCache.put(addr, a.walletInterfaces[i], a.now())
+			return true, r.gasUsed, true, nil
+		}
+	}
+
+	for i, r := range results {
+		if r.err == errReverted {
+			continue
+		}
+		if r.err == nil {
+			a.interfaceCache.put(addr, a.walletInterfaces[i], a.now())
+		}
+		return r.isValid, r.gasUsed, true, r.err
+	}
+	return false, 0, false, nil
+}
+
+func (a *Authenticator) isValidSignatureVia(walletInterface WalletInterface, addr common.Address, hash [32]byte, message, sig []byte) (bool, uint64, error) {
+	var (
+		abi        ethAbi.ABI
+		args       []interface{}
+		magicValue [4]byte
+	)
+	switch walletInterface {
+	case InterfaceERC1654:
+		abi, args, magicValue = _ERC1271ABI, []interface{}{hash, sig}, _ERC1271MagicValue
+	case InterfaceERC1271:
+		if message == nil {
+			message = hash[:]
+		}
+		abi, args, magicValue = _ERC1271LegacyABI, []interface{}{message, sig}, _ERC1271LegacyMagicValue
+	case InterfaceContractABI:
+		return a.isValidSignatureViaContractABI(addr, hash, message, sig)
+	default:
+		return false, 0, errReverted
+	}
+
+	input, err := abi.Pack("isValidSignature", args...)
+	if err != nil {
+		return false, 0, err
+	}
+
+	output, gasUsed, err := a.callContract(addr, input)
+	if err != nil {
+		if isRevertError(err) {
+			return false, 0, errReverted
+		}
+		return false, 0, contractCallFailed(err)
+	}
+	if len(output) == 0 {
+		return false, 0, errReverted
+	}
+
+	if a.lenientMagicValue && len(output) >= 4 {
+		return bytes.Equal(output[:4], magicValue[:]), gasUsed, nil
+	}
+	if len(output) != 32 {
+		return false, gasUsed, nil
+	}
+
+	var returnedMagicValue [4]byte
+	if err := abi.Unpack(&returnedMagicValue, "isValidSignature", output); err != nil {
+		return false, 0, err
+	}
+	return returnedMagicValue == magicValue, gasUsed, nil
+}
+
+// GasReporter is implemented by backends able to report the gas used by an eth_call (e.g. via a simulation or a
+// tracing call), which is then surfaced as the GasUsed of smart-contract wallet results.
+type GasReporter interface {
+	CallContractGasUsed(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) (output []byte, gasUsed uint64, err error)
+}
+
+// callContract makes an eth_call to the contract, returning its raw output and, if the backend reports it, the gas used.
+func (a *Authenticator) callContract(addr common.Address, input []byte) ([]byte, uint64, error) {
+	msg := ethereum.CallMsg{From: a.callFrom, To: &addr, Data: input}
+	if a.stateOverride != nil {
+		output, err := a.callContractWithStateOverride(msg)
+		return output, 0, err
+	}
+	if a.pendingState {
+		output, err := a.pendingCallContract(msg)
+		return output, 0, err
+	}
+	if gasReporter, ok := a.cc.(GasReporter); ok {
+		return gasReporter.CallContractGasUsed(a.context(), msg, a.callBlock)
+	}
+	output, err := a.cc.CallContract(a.context(), msg, a.callBlock)
+	return output, 0, err
+}
+
+// boundCaller routes the calls of contract bindings through callContract and codeAt, so that they honor the state
+// overrides, the pending state and the pinned block as the calls of the authenticator itself do.
+type boundCaller struct {
+	a *Authenticator
+}
+
+func (c boundCaller) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return c.a.codeAt(contract)
+}
+
+func (c boundCaller) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	output, _, err := c.a.callContract(*call.To, call.Data)
+	return output, err
+}
+
+// contractError is an error of a kind (ErrNoContractCode or ErrContractCallFailed) wrapping its cause.
+type contractError struct {
+	kind error
+	err  error
+}
+
+func (e *contractError) Error() string {
+	return e.kind.Error() + ": " + e.err.Error()
+}
+
+func (e *contractError) Unwrap() error {
+	return e.err
+}
+
+func (e *contractError) Is(target error) bool {
+	return target == e.kind
+}
+
+func contractCallFailed(err error) error {
+	return &contractError{kind: ErrContractCallFailed, err: err}
+}
+
+// the JSON-RPC error code of execution reverts carrying revert data
+const rpcRevertErrorCode = 3
+
+// isRevertError returns true if the error of an eth_call is an execution revert rather than e.g. a transport error.
+func isRevertError(err error) bool {
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == rpcRevertErrorCode {
+		return true
+	}
+	return strings.Contains(strings.ToLower(err.Error()), "execution reverted")
+}
+
+// context returns the network context of backend calls, never nil as transports (e.g. WebSocket) require one.
+func (a *Authenticator) context() context.Context {
+	if a.ctx == nil {
+		return context.Background()
+	}
+	return a.ctx
+}
+
+func mustABI(definition string) ethAbi.ABI {
+	abi, err := ethAbi.JSON(strings.NewReader(definition))
+	if err != nil {
+		panic(err)
+	}
+	return abi
+}
@@ -0,0 +1,348 @@
+package dappauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestWalletInterfaces(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	sig := signERC1654PersonalMessage("foo", keyB, addrA, t)
+
+	interfaceTests := []struct {
+		title           string
+		revertSelectors []string
+		options         []Option
+		expected        bool
+		expectedErr     error
+	}{
+		{"Wallets implementing ERC-1654 should be authorized signers", []string{"20c13b0b"}, nil, true, nil},
+		{"Wallets implementing the legacy ERC-1271 should be authorized signers", []string{"1626ba7e"}, nil, true, nil},
+		{"Wallets implementing only an interface which isn't configured should error", []string{"1626ba7e"}, []Option{WithWalletInterfaces(InterfaceERC1654)}, false, ErrUnsupportedWalletInterface},
+		{"Wallets implementing neither interface should error", []string{"1626ba7e", "20c13b0b"}, nil, false, ErrUnsupportedWalletInterface},
+	}
+
+	for _, test := range interfaceTests {
+		t.Run(test.title, func(t *testing.T) {
+			mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, code: []byte{0x60}, revertSelectors: test.revertSelectors}
+			authenticator := NewAuthenticator(nil, mock, test.options...)
+
+			isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+			if !errors.Is(err, test.expectedErr) {
+				t.Fatalf("expected error %v, got %v", test.expectedErr, err)
+			}
+			expectBool(isAuthorizedSigner, test.expected, t)
+		})
+	}
+
+	t.Run("Addresses without code implementing neither interface should NOT error with ErrUnsupportedWalletInterface", func(t *testing.T) {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, revertSelectors: []string{"1626ba7e", "20c13b0b"}}
+		authenticator := NewAuthenticator(nil, mock)
+
+		_, err := authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+		expectBool(err != nil, true, t)
+		expectBool(errors.Is(err, ErrUnsupportedWalletInterface), false, t)
+	})
+}
+
+func TestContractErrors(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyC, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	sigB := signERC1654PersonalMessage("foo", keyB, addrA, t)
+
+	t.Run("Addresses without code should error with ErrNoContractCode", func(t *testing.T) {
+		mock := &mockContract{revertSelectors: []string{"1626ba7e", "20c13b0b"}}
+		result, err := NewAuthenticator(nil, mock).Verify("foo", sigB, addrA.Hex())
+		expectBool(result == nil, true, t)
+		expectBool(errors.Is(err, ErrNoContractCode), true, t)
+		expectBool(errors.Is(err, bind.ErrNoCode), true, t)
+		expectBool(errors.Is(err, ErrContractCallFailed), false, t)
+	})
+
+	t.Run("Contracts rejecting the signature should NOT error", func(t *testing.T) {
+		mock := &mockContract{address: addrA, code: []byte{1}, authorizedKey: &keyC.PublicKey}
+		result, err := NewAuthenticator(nil, mock).Verify("foo", sigB, addrA.Hex())
+		checkError(err, t)
+		expectBool(result.Authorized, false, t)
+		expectBool(result.Path == PathContract, true, t)
+	})
+
+	t.Run("Failed calls should error with ErrContractCallFailed", func(t *testing.T) {
+		mock := &mockContract{errorAddresses: []common.Address{addrA}}
+		result, err := NewAuthenticator(nil, mock).Verify("foo", sigB, addrA.Hex())
+		expectBool(result == nil, true, t)
+		expectBool(errors.Is(err, ErrContractCallFailed), true, t)
+		expectBool(errors.Is(err, errDummy), true, t)
+		expectBool(errors.Is(err, ErrNoContractCode), false, t)
+	})
+}
+
+// delays the contract calls of the given methods, unless the call is cancelled
+type selectorDelayBackend struct {
+	*mockContract
+	delays map[string]time.Duration
+}
+
+func (b *selectorDelayBackend) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	select {
+	case <-time.After(b.delays[hex.EncodeToString(call.Data[:4])]):
+		return b.mockContract.CallContract(ctx, call, blockNumber)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestConcurrentInterfaceProbe(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyC, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	sigB := signERC1654PersonalMessage("foo", keyB, addrA, t)
+
+	// the wallet only implements the legacy interface, and is slow to revert the other
+	newBackend := func() *selectorDelayBackend {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, code: []byte{1}, revertSelectors: []string{"1626ba7e"}}
+		return &selectorDelayBackend{mockContract: mock, delays: map[string]time.Duration{"1626ba7e": 400 * time.Millisecond}}
+	}
+
+	t.Run("Probing should return as soon as an interface authorizes", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, newBackend(), WithConcurrentInterfaceProbe(true))
+
+		start := time.Now()
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sigB, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+		expectBool(time.Since(start) < 200*time.Millisecond, true, t)
+	})
+
+	t.Run("Probing in order should wait for each interface", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, newBackend())
+
+		start := time.Now()
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sigB, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+		expectBool(time.Since(start) >= 400*time.Millisecond, true, t)
+	})
+
+	t.Run("Rejections should be of the first implemented interface", func(t *testing.T) {
+		backend := newBackend()
+		backend.delays = nil
+		authenticator := NewAuthenticator(nil, backend, WithConcurrentInterfaceProbe(true))
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", signERC1654PersonalMessage("foo", keyC, addrA, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, false, t)
+	})
+
+	t.Run("Addresses without code should error with ErrNoContractCode", func(t *testing.T) {
+		mock := &mockContract{revertSelectors: []string{"1626ba7e", "20c13b0b"}}
+		_, err := NewAuthenticator(nil, mock, WithConcurrentInterfaceProbe(true)).IsAuthorizedSigner("foo", sigB, addrA.Hex())
+		expectBool(errors.Is(err, ErrNoContractCode), true, t)
+	})
+}
+
+func TestLenientMagicValue(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+
+	// the wallet returns the magic value followed by a word of additional data
+	mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, revertSelectors: []string{"20c13b0b"}, magicValueSuffix: make([]byte, 32)}
+
+	magicValueTests := []struct {
+		title    string
+		options  []Option
+		sigKey   *ecdsa.PrivateKey
+		expected bool
+	}{
+		{"Magic values with trailing data should be authorized when lenient", []Option{WithLenientMagicValue(true)}, keyB, true},
+		{"Magic values with trailing data should NOT be authorized by default", nil, keyB, false},
+		{"Non-magic values with trailing data should NOT be authorized when lenient", []Option{WithLenientMagicValue(true)}, keyA, false},
+	}
+
+	for _, test := range magicValueTests {
+		t.Run(test.title, func(t *testing.T) {
+			sig := signERC1654PersonalMessage("foo", test.sigKey, addrA, t)
+			isAuthorizedSigner, err := NewAuthenticator(nil, mock, test.options...).IsAuthorizedSigner("foo", sig, addrA.Hex())
+			checkError(err, t)
+			expectBool(isAuthorizedSigner, test.expected, t)
+		})
+	}
+}
+
+func TestRevertAsUnauthorized(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+
+	revertTests := []struct {
+		title       string
+		mock        *mockContract
+		sigKey      *ecdsa.PrivateKey
+		expected    bool
+		expectedErr error
+	}{
+		{
+			"Wallets reverting should NOT be authorized, without error",
+			&mockContract{address: addrA, authorizedKey: &keyB.PublicKey, code: []byte{0x60}, revertSelectors: []string{"1626ba7e", "20c13b0b"}},
+			keyB,
+			false,
+			nil,
+		},
+		{
+			"Wallets returning a non-magic value should NOT be authorized, without error",
+			&mockContract{address: addrA, authorizedKey: &keyB.PublicKey, code: []byte{0x60}},
+			keyA,
+			false,
+			nil,
+		},
+		{
+			"Transport errors should still error with ErrContractCallFailed",
+			&mockContract{address: addrA, authorizedKey: &keyB.PublicKey, code: []byte{0x60}, errorAddresses: []common.Address{addrA}},
+			keyB,
+			false,
+			ErrContractCallFailed,
+		},
+		{
+			"Addresses without code should still error with ErrNoContractCode",
+			&mockContract{address: addrA, authorizedKey: &keyB.PublicKey, revertSelectors: []string{"1626ba7e", "20c13b0b"}},
+			keyB,
+			false,
+			ErrNoContractCode,
+		},
+	}
+
+	for _, test := range revertTests {
+		t.Run(test.title, func(t *testing.T) {
+			sig := signERC1654PersonalMessage("foo", test.sigKey, addrA, t)
+			isAuthorizedSigner, err := NewAuthenticator(nil, test.mock, WithRevertAsUnauthorized(true)).IsAuthorizedSigner("foo", sig, addrA.Hex())
+			if !errors.Is(err, test.expectedErr) {
+				t.Fatalf("expected error %v, got %v", test.expectedErr, err)
+			}
+			expectBool(isAuthorizedSigner, test.expected, t)
+		})
+	}
+
+	t.Run("Transport errors mentioning a revert should still error with ErrContractCallFailed", func(t *testing.T) {
+		backend := &callErrorBackend{
+			mockContract: &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, code: []byte{0x60}},
+			err:          errors.New("dial tcp: connection reset, reverting to fallback endpoint"),
+		}
+		_, err := NewAuthenticator(nil, backend, WithRevertAsUnauthorized(true)).IsAuthorizedSigner("foo", signERC1654PersonalMessage("foo", keyB, addrA, t), addrA.Hex())
+		expectBool(errors.Is(err, ErrContractCallFailed), true, t)
+	})
+
+	t.Run("JSON-RPC errors with the revert code should be reverts", func(t *testing.T) {
+		backend := &callErrorBackend{
+			mockContract: &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, code: []byte{0x60}},
+			err:          &rpcCodeError{code: 3, message: "VM execution error"},
+		}
+		isAuthorizedSigner, err := NewAuthenticator(nil, backend, WithRevertAsUnauthorized(true)).IsAuthorizedSigner("foo", signERC1654PersonalMessage("foo", keyB, addrA, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, false, t)
+	})
+
+	t.Run("Wallets reverting should error with ErrUnsupportedWalletInterface by default", func(t *testing.T) {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, code: []byte{0x60}, revertSelectors: []string{"1626ba7e", "20c13b0b"}}
+		_, err := NewAuthenticator(nil, mock).IsAuthorizedSigner("foo", signERC1654PersonalMessage("foo", keyB, addrA, t), addrA.Hex())
+		expectBool(errors.Is(err, ErrUnsupportedWalletInterface), true, t)
+	})
+}
+
+// fails every contract call with err
+type callErrorBackend struct {
+	*mockContract
+	err error
+}
+
+func (b *callErrorBackend) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return nil, b.err
+}
+
+// a JSON-RPC error with its code, as returned by rpc clients
+type rpcCodeError struct {
+	code    int
+	message string
+}
+
+func (e *rpcCodeError) Error() string  { return e.message }
+func (e *rpcCodeError) ErrorCode() int { return e.code }
+
+func TestBytesOverloadOnly(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+
+	bytesTests := []struct {
+		title        string
+		challenge    string
+		options      []Option
+		expectedData []byte
+	}{
+		{"Short messages should be passed raw", "foo", nil, []byte("foo")},
+		{"Messages spanning several ABI words should be passed raw", strings.Repeat("sign in to example.com ", 5), nil, []byte(strings.Repeat("sign in to example.com ", 5))},
+		{"Hex challenges should be passed as the hex string signed", "0xcafe", []Option{WithChallengeEncoding(ChallengeEncodingHex)}, []byte("0xcafe")},
+	}
+
+	for _, test := range bytesTests {
+		t.Run(test.title, func(t *testing.T) {
+			// the wallet implements only isValidSignature(bytes, bytes)
+			mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, code: []byte{0x60}, revertSelectors: []string{"1626ba7e"}}
+			authenticator := NewAuthenticator(nil, mock, append(test.options, WithWalletInterfaces(InterfaceERC1271))...)
+
+			isAuthorizedSigner, err := authenticator.IsAuthorizedSigner(test.challenge, signERC1654PersonalMessage(string(test.expectedData), keyB, addrA, t), addrA.Hex())
+			checkError(err, t)
+			expectBool(isAuthorizedSigner, true, t)
+			expectBool(bytes.Equal(mock.lastData, test.expectedData), true, t)
+		})
+	}
+
+	t.Run("Wallets implementing only the bytes overload should NOT be verified via the bytes32 overload", func(t *testing.T) {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, code: []byte{0x60}, revertSelectors: []string{"1626ba7e"}}
+		_, err := NewAuthenticator(nil, mock, WithWalletInterfaces(InterfaceERC1654)).IsAuthorizedSigner("foo", signERC1654PersonalMessage("foo", keyB, addrA, t), addrA.Hex())
+		expectBool(errors.Is(err, ErrUnsupportedWalletInterface), true, t)
+	})
+}
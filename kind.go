@@ -0,0 +1,101 @@
+package dappauth
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	// ErrWalletKindMismatch is returned when the address claimed to be an external wallet has contract code
+	// and the signature doesn't recover to it.
+	ErrWalletKindMismatch = errors.New("dappauth: address has contract code but was claimed to be an external wallet")
+	// ErrUnknownWalletKind is returned for a wallet kind which isn't one of the WalletKind constants.
+	ErrUnknownWalletKind = errors.New("dappauth: unknown wallet kind")
+)
+
+// WalletKind is the kind of a wallet, when known ahead of the verification.
+type WalletKind int
+
+const (
+	// WalletKindEOA is an external wallet, verified by recovery only.
+	WalletKindEOA WalletKind = iota
+	// WalletKindERC1271 is a smart-contract wallet implementing the legacy isValidSignature(bytes, bytes) (see InterfaceERC1271).
+	WalletKindERC1271
+	// WalletKindERC1654 is a smart-contract wallet implementing isValidSignature(bytes32, bytes) (see InterfaceERC1654).
+	WalletKindERC1654
+)
+
+func (k WalletKind) String() string {
+	switch k {
+	case WalletKindEOA:
+		return "EOA"
+	case WalletKindERC1271:
+		return "ERC-1271"
+	case WalletKindERC1654:
+		return "ERC-1654"
+	default:
+		return "unknown"
+	}
+}
+
+// IsAuthorizedSignerTyped is like IsAuthorizedSigner but goes straight to the verification path of the kind of the
+// wallet (e.g. known from onboarding), without falling back to other paths or probing other wallet interfaces.
+// Results are not cached.
+func (a *Authenticator) IsAuthorizedSignerTyped(challenge, signature, addrHex string, kind WalletKind) (bool, error) {
+
+	addr, err := a.normalizeAddress(addrHex)
+	if err != nil {
+		return false, err
+	}
+
+	if err := a.checkChallengeValidity(challenge); err != nil {
+		return false, err
+	}
+	if err := a.checkAddressBinding(challenge, addr); err != nil {
+		return false, err
+	}
+
+	sigBytes, err := a.signatureBytes(signature)
+	if err != nil {
+		return false, err
+	}
+
+	switch kind {
+	case WalletKindEOA:
+		return a.isAuthorizedEOASigner(challenge, sigBytes, addr)
+	case WalletKindERC1271, WalletKindERC1654:
+		typed := *a
+		typed.walletInterfaces = []WalletInterface{InterfaceERC1271}
+		if kind == WalletKindERC1654 {
+			typed.walletInterfaces = []WalletInterface{InterfaceERC1654}
+		}
+		typed.interfaceCache = nil
+
+		return typed.isAuthorizedContractSigner(challenge, sigBytes, addr)
+	default:
+		return false, ErrUnknownWalletKind
+	}
+}
+
+func (a *Authenticator) isAuthorizedEOASigner(challenge string, sigBytes []byte, addr common.Address) (bool, error) {
+	result, err := a.verifyEOA(challenge, sigBytes, addr)
+	if err != nil {
+		return false, err
+	}
+	if result != nil {
+		return result.Authorized, nil
+	}
+
+	// the address not being an external wallet is more likely than a wrong signature
+	if a.cc != nil {
+		code, err := a.codeAt(addr)
+		if err != nil {
+			return false, wrapError("code of", addr, contractCallFailed(err))
+		}
+		if len(code) > 0 {
+			return false, ErrWalletKindMismatch
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,54 @@
+package dappauth
+
+import (
+	"errors"
+	"testing"
+
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestIsAuthorizedSignerTyped(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	eoaSig := signEOAPersonalMessage("foo", keyA, t)
+	contractSig := signERC1654PersonalMessage("foo", keyB, addrA, t)
+
+	kindTests := []struct {
+		title         string
+		mock          *mockContract
+		signature     string
+		kind          WalletKind
+		expected      bool
+		expectedErr   error
+		expectedCalls int
+	}{
+		{"External wallets should be authorized signers as EOA", &mockContract{}, eoaSig, WalletKindEOA, true, nil, 0},
+		{"External wallets should NOT be authorized signers as EOA with another signature", &mockContract{}, contractSig, WalletKindEOA, false, nil, 0},
+		{"Smart-contract wallets should error as EOA when the signature doesn't recover",
+			&mockContract{address: addrA, authorizedKey: &keyB.PublicKey, code: []byte{0x60}}, contractSig, WalletKindEOA, false, ErrWalletKindMismatch, 0},
+		{"Smart-contract wallets should be authorized signers as ERC1654 without probing",
+			&mockContract{address: addrA, authorizedKey: &keyB.PublicKey, code: []byte{0x60}}, contractSig, WalletKindERC1654, true, nil, 1},
+		{"Smart-contract wallets should be authorized signers as ERC1271 without probing",
+			&mockContract{address: addrA, authorizedKey: &keyB.PublicKey, code: []byte{0x60}}, contractSig, WalletKindERC1271, true, nil, 1},
+		{"Smart-contract wallets should error as a kind they don't implement",
+			&mockContract{address: addrA, authorizedKey: &keyB.PublicKey, code: []byte{0x60}, revertSelectors: []string{"20c13b0b"}}, contractSig, WalletKindERC1271, false, ErrUnsupportedWalletInterface, 1},
+	}
+
+	for _, test := range kindTests {
+		t.Run(test.title, func(t *testing.T) {
+			authenticator := NewAuthenticator(nil, test.mock)
+
+			isAuthorizedSigner, err := authenticator.IsAuthorizedSignerTyped("foo", test.signature, addrA.Hex(), test.kind)
+			if !errors.Is(err, test.expectedErr) {
+				t.Fatalf("expected error %v, got %v", test.expectedErr, err)
+			}
+			expectBool(isAuthorizedSigner, test.expected, t)
+			expectBool(test.mock.calls == test.expectedCalls, true, t)
+		})
+	}
+}
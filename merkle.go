@@ -0,0 +1,49 @@
+package dappauth
+
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// VerifyMerkleAuth checks if the address is an authorized signer (either as an external wallet or as a smart-contract
+// wallet) of the merkle root of a set of permissions, and that the leaf is a permission of the set per the proof.
+// The root is signed as a pre-hashed challenge, i.e. the 32 bytes of the root are personal_signed. Proofs are of
+// trees hashing sorted pairs, as OpenZeppelin's MerkleProof.
+func (a *Authenticator) VerifyMerkleAuth(root, leaf [32]byte, proof [][32]byte, signature, addrHex string) (bool, error) {
+
+	// no RPC is needed to reject a leaf which isn't in the tree
+	if !verifyMerkleProof(root, leaf, proof) {
+		return false, nil
+	}
+
+	rooted := *a
+	rooted.challengePreHashed = true
+	rooted.challengeEncoding = ChallengeEncodingRaw
+	rooted.challengeExpiryExtractor = nil // the root has no expiry, not-before time or nonce to extract
+	rooted.challengeNotBeforeExtractor = nil
+	rooted.challengeNonceExtractor = nil
+	rooted.resultCache = nil
+
+	return rooted.IsAuthorizedSigner(hexutil.Encode(root[:]), signature, addrHex)
+}
+
+// verifyMerkleProof returns true if the proof proves the leaf is in the tree of the root.
+func verifyMerkleProof(root, leaf [32]byte, proof [][32]byte) bool {
+	hash := leaf
+	for _, sibling := range proof {
+		hash = hashMerklePair(hash, sibling)
+	}
+	return hash == root
+}
+
+func hashMerklePair(a, b [32]byte) [32]byte {
+	if bytes.Compare(a[:], b[:]) > 0 {
+		a, b = b, a
+	}
+
+	var hash [32]byte
+	copy(hash[:], ethCrypto.Keccak256(a[:], b[:]))
+	return hash
+}
@@ -0,0 +1,73 @@
+package dappauth
+
+import (
+	"testing"
+
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestVerifyMerkleAuth(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyC, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrC := ethCrypto.PubkeyToAddress(keyC.PublicKey)
+
+	// a tree of 4 permissions
+	var leaves [4][32]byte
+	for i, permission := range []string{"read", "write", "transfer", "admin"} {
+		copy(leaves[i][:], ethCrypto.Keccak256([]byte(permission)))
+	}
+	left, right := hashMerklePair(leaves[0], leaves[1]), hashMerklePair(leaves[2], leaves[3])
+	root := hashMerklePair(left, right)
+	proofOfTransfer := [][32]byte{leaves[3], left}
+
+	var unknown [32]byte
+	copy(unknown[:], ethCrypto.Keccak256([]byte("mint")))
+
+	eoaSig := signEOAPersonalMessage(string(root[:]), keyC, t)
+	contractSig := signRawHash(erc191MessageHash(root[:], addrA), keyB, t)
+	authenticator := NewAuthenticator(nil, &mockContract{address: addrA, authorizedKey: &keyB.PublicKey})
+
+	merkleTests := []struct {
+		title     string
+		leaf      [32]byte
+		proof     [][32]byte
+		signature string
+		address   string
+		expected  bool
+	}{
+		{"External wallets should be authorized for permissions in the tree", leaves[2], proofOfTransfer, eoaSig, addrC.Hex(), true},
+		{"Smart-contract wallets should be authorized for permissions in the tree", leaves[2], proofOfTransfer, contractSig, addrA.Hex(), true},
+		{"Each permission should be proven by its own proof", leaves[0], [][32]byte{leaves[1], right}, eoaSig, addrC.Hex(), true},
+		{"Permissions should NOT be authorized with the proof of another permission", leaves[0], proofOfTransfer, eoaSig, addrC.Hex(), false},
+		{"Permissions NOT in the tree should NOT be authorized", unknown, proofOfTransfer, eoaSig, addrC.Hex(), false},
+		{"Truncated proofs should NOT be authorized", leaves[2], proofOfTransfer[:1], eoaSig, addrC.Hex(), false},
+	}
+
+	for _, test := range merkleTests {
+		t.Run(test.title, func(t *testing.T) {
+			isAuthorized, err := authenticator.VerifyMerkleAuth(root, test.leaf, test.proof, test.signature, test.address)
+			checkError(err, t)
+			expectBool(isAuthorized, test.expected, t)
+		})
+	}
+
+	t.Run("Signatures over another root should NOT be authorized", func(t *testing.T) {
+		otherSig := signEOAPersonalMessage(string(left[:]), keyC, t)
+		isAuthorized, _ := authenticator.VerifyMerkleAuth(root, leaves[2], proofOfTransfer, otherSig, addrC.Hex())
+		expectBool(isAuthorized, false, t)
+	})
+	t.Run("Challenge extractors should NOT apply to the root", func(t *testing.T) {
+		expiring := NewAuthenticator(nil, &mockContract{}, WithChallengeExpiryExtractor(ExpiringChallengeExpiry))
+
+		isAuthorized, err := expiring.VerifyMerkleAuth(root, leaves[2], proofOfTransfer, eoaSig, addrC.Hex())
+		checkError(err, t)
+		expectBool(isAuthorized, true, t)
+	})
+}
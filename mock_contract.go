@@ -4,41 +4,129 @@ import (
 	"bytes"
 	"context"
 	"crypto/ecdsa"
+	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"math/big"
 	"strings"
+	"sync"
 
+	"github.com/dapperlabs/dappauth/ERCs"
 	"github.com/ethereum/go-ethereum"
 	ethAbi "github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	ethCrypto "github.com/ethereum/go-ethereum/crypto"
 )
 
+var errDummy = errors.New("Dummy error")
+
 type mockContract struct {
 	address               common.Address
 	authorizedKey         *ecdsa.PublicKey
 	errorIsValidSignature bool
+	lastCallFrom          common.Address // the From of the last CallContract
+	code                  []byte         // the code deployed at address
+	errorCodeAt           bool
+	hashScheme            ContractHashScheme    // the hash the wallet's signers are expected to sign
+	lastHash              [32]byte              // the hash received by the last isValidSignature
+	lastData              []byte                // the data received by the last legacy isValidSignature
+	errorAddresses        []common.Address      // contract calls to these addresses error
+	implementation        common.Address        // the EIP-1967 implementation of the contract at address
+	blockNumber           uint64                // the latest block
+	calls                 int                   // the number of CallContract
+	revertSelectors       []string              // contract calls of these methods revert
+	erc7739Domain         *TypedDataDomain      // the EIP-712 domain of the account, if it verifies ERC-7739 nested typed data
+	expectRS64            bool                  // the wallet expects r ‖ s signatures, without the recovery byte
+	expectPubkey          bool                  // the wallet expects r ‖ s ‖ v ‖ compressed pubkey signatures
+	lastSignature         []byte                // the signature received by the last isValidSignature
+	eip5267Domain         *TypedDataDomain      // the EIP-712 domain reported by eip712Domain() (nil = not implemented)
+	sessionKeySelector    [4]byte               // the selector of the session key view (zero = not implemented)
+	sessionKeys           []common.Address      // the session keys authorized by the session key view
+	walletNonceSelector   [4]byte               // the selector of the nonce view (zero = not implemented)
+	walletNonce           *big.Int              // the nonce returned by the nonce view
+	magicValueSuffix      []byte                // trailing data returned after the result of isValidSignature
+	digestBuilder         ContractDigestBuilder // builds the digest the wallet's signers are expected to sign (overrides hashScheme)
+	validatorModule       common.Address        // the ERC-7579 validator module the account expects signatures to be prefixed with
+	revertingCalls        int                   // the number of upcoming contract calls which revert (e.g. during an upgrade)
+
+	mu sync.Mutex
 }
 
 func (m *mockContract) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
-	return nil, fmt.Errorf("CodeAt not supported")
+	if m.errorCodeAt {
+		return nil, fmt.Errorf("CodeAt not supported")
+	}
+	if contract == m.address {
+		return m.code, nil
+	}
+	return nil, nil
+}
+
+func (m *mockContract) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	if account == m.address && key == EIP1967ImplementationSlot {
+		return common.LeftPadBytes(m.implementation.Bytes(), 32), nil
+	}
+	return make([]byte, 32), nil
+}
+
+func (m *mockContract) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return &types.Header{Number: new(big.Int).SetUint64(m.blockNumber)}, nil
 }
 
 func (m *mockContract) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastCallFrom = call.From
+	m.calls++
+	for _, addr := range m.errorAddresses {
+		if *call.To == addr {
+			return nil, fmt.Errorf("%w at %v", errDummy, addr.Hex())
+		}
+	}
+
+	if m.revertingCalls > 0 {
+		m.revertingCalls--
+		return nil, errors.New("execution reverted")
+	}
+
 	methodCall := hex.EncodeToString(call.Data[:4])
 	methodParams := call.Data[4:]
+	for _, selector := range m.revertSelectors {
+		if methodCall == selector {
+			return nil, errors.New("execution reverted")
+		}
+	}
+
+	if m.sessionKeySelector != [4]byte{} && bytes.Equal(call.Data[:4], m.sessionKeySelector[:]) {
+		return m.isSessionKeyValid(methodParams)
+	}
+
+	if m.walletNonceSelector != [4]byte{} && bytes.Equal(call.Data[:4], m.walletNonceSelector[:]) {
+		return common.LeftPadBytes(m.walletNonce.Bytes(), 32), nil
+	}
+
 	switch methodCall {
 	case "1626ba7e":
-		return m._1626ba7e(methodParams)
+		return m._1626ba7e(*call.To, methodParams)
+	case "20c13b0b":
+		return m._20c13b0b(*call.To, methodParams)
+	case "98ef1ed8":
+		return m._98ef1ed8(methodParams)
+	case "84b0196e":
+		return m._84b0196e()
 	default:
 		return nil, fmt.Errorf("Unexpected method %v", methodCall)
 	}
 }
 
 // "IsValidSignature" method call
-func (m *mockContract) _1626ba7e(methodParams []byte) ([]byte, error) {
+func (m *mockContract) _1626ba7e(to common.Address, methodParams []byte) ([]byte, error) {
 	// TODO: refactor out of method
 	const definition = `[
 	{ "name" : "mixedBytes", "constant" : true, "outputs": [{ "name": "a", "type": "bytes32" }, { "name": "b", "type": "bytes" } ] }]`
@@ -57,17 +145,209 @@ func (m *mockContract) _1626ba7e(methodParams []byte) ([]byte, error) {
 		return nil, err
 	}
 
+	m.lastHash = data
+	m.lastSignature = sig
+
 	if m.errorIsValidSignature {
-		return nil, errors.New("Dummy error")
+		return nil, errDummy
+	}
+
+	if m.validatorModule != (common.Address{}) {
+		// the account routes to the module prefixing the signature, and only has this module installed
+		if len(sig) < common.AddressLength || common.BytesToAddress(sig[:common.AddressLength]) != m.validatorModule {
+			return nil, errors.New("execution reverted")
+		}
+		sig = sig[common.AddressLength:]
 	}
 
+	if m.expectRS64 {
+		return m.isAuthorizedRS64Signature(data, sig, to)
+	}
+
+	if m.expectPubkey {
+		return m.isAuthorizedPubkeySignature(data, sig, to)
+	}
+
+	if m.erc7739Domain != nil {
+		return m.isAuthorizedERC7739Signature(data, sig)
+	}
+
+	isValid, err := m.isAuthorizedSignature(data, sig, to)
+	if err != nil || m.magicValueSuffix == nil {
+		return isValid, err
+	}
+	return append(isValid, m.magicValueSuffix...), nil
+}
+
+// recovers the r ‖ s signature with either recovery byte
+func (m *mockContract) isAuthorizedRS64Signature(data [32]byte, sig []byte, address common.Address) ([]byte, error) {
+	if len(sig) != 64 {
+		return nil, fmt.Errorf("expected a 64 bytes signature, got %d bytes", len(sig))
+	}
+
+	for _, v := range []byte{27, 28} {
+		isValid, err := m.isAuthorizedSignature(data, append(append([]byte{}, sig...), v), address)
+		if err == nil && bytes.Equal(isValid[:4], _ERC1271MagicValue[:]) {
+			return isValid, nil
+		}
+	}
+	return _false()
+}
+
+// checks the compressed pubkey appended to the signature is that of the authorized key
+func (m *mockContract) isAuthorizedPubkeySignature(data [32]byte, sig []byte, address common.Address) ([]byte, error) {
+	if len(sig) != 65+33 {
+		return nil, fmt.Errorf("expected a 98 bytes signature, got %d bytes", len(sig))
+	}
+	if !bytes.Equal(sig[65:], ethCrypto.CompressPubkey(m.authorizedKey)) {
+		return _false()
+	}
+	return m.isAuthorizedSignature(data, sig[:65], address)
+}
+
+// rehashes the ERC-7739 wrapped signature to the TypedDataSign digest, as the account would
+func (m *mockContract) isAuthorizedERC7739Signature(data [32]byte, sig []byte) ([]byte, error) {
+	if len(sig) < 65+32+32+2 {
+		return _false()
+	}
+
+	contentsTypeLen := int(binary.BigEndian.Uint16(sig[len(sig)-2:]))
+	if len(sig) != 65+32+32+contentsTypeLen+2 {
+		return _false()
+	}
+
+	var appDomainSeparator, contentsHash [32]byte
+	copy(appDomainSeparator[:], sig[65:97])
+	copy(contentsHash[:], sig[97:129])
+	contentsType := string(sig[129 : 129+contentsTypeLen])
+
+	// the contents must be those of the hash being verified
+	if !bytes.Equal(ethCrypto.Keccak256([]byte{0x19, 0x01}, appDomainSeparator[:], contentsHash[:]), data[:]) {
+		return _false()
+	}
+
+	typedDataSignHash, err := erc7739TypedDataSignHash(appDomainSeparator, contentsType, contentsHash, *m.erc7739Domain, [32]byte{})
+	if err != nil {
+		return _false()
+	}
+
+	return m.isAuthorizedSignature(typedDataSignHash, sig[:65], m.address)
+}
+
+// legacy "IsValidSignature" method call, taking the data rather than its hash
+func (m *mockContract) _20c13b0b(to common.Address, methodParams []byte) ([]byte, error) {
+	const definition = `[
+	{ "name" : "mixedBytes", "constant" : true, "outputs": [{ "name": "a", "type": "bytes" }, { "name": "b", "type": "bytes" } ] }]`
+
+	abi, err := ethAbi.JSON(strings.NewReader(definition))
+	if err != nil {
+		return nil, err
+	}
+
+	data := []byte{}
+	sig := []byte{}
+
+	mixedBytes := []interface{}{&data, &sig}
+	err = abi.Unpack(&mixedBytes, "mixedBytes", methodParams)
+	if err != nil {
+		return nil, err
+	}
+
+	var hash [32]byte
+	copy(hash[:], ethCrypto.Keccak256(data))
+	m.lastHash = hash
+	m.lastData = data
+
+	isValid, err := m.isAuthorizedSignature(hash, sig, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// the legacy magic value is 0x20c13b0b
+	if bytes.Equal(isValid[:4], _ERC1271MagicValue[:]) {
+		return hex.DecodeString("20c13b0b00000000000000000000000000000000000000000000000000000000")
+	}
+	return _false()
+}
+
+// "IsValidSig" method call of an ERC-6492 UniversalSigValidator, simulating the deployment of a wallet
+// (owned by authorizedKey) at the signer's address
+func (m *mockContract) _98ef1ed8(methodParams []byte) ([]byte, error) {
+	const definition = `[
+	{ "name" : "mixedBytes", "constant" : true, "outputs": [{ "name": "a", "type": "address" }, { "name": "b", "type": "bytes32" }, { "name": "c", "type": "bytes" } ] }]`
+
+	abi, err := ethAbi.JSON(strings.NewReader(definition))
+	if err != nil {
+		return nil, err
+	}
+
+	signer := common.Address{}
+	data := [32]byte{}
+	sig := []byte{}
+
+	mixedBytes := []interface{}{&signer, &data, &sig}
+	err = abi.Unpack(&mixedBytes, "mixedBytes", methodParams)
+	if err != nil {
+		return nil, err
+	}
+
+	_, _, innerSig, err := unwrapERC6492Signature(sig)
+	if err != nil {
+		return nil, err
+	}
+
+	isValid, err := m.isAuthorizedSignature(data, innerSig, signer)
+	if err != nil {
+		return nil, err
+	}
+
+	// the validator returns a bool rather than the magic value
+	if bytes.Equal(isValid[:4], _ERC1271MagicValue[:]) {
+		return common.LeftPadBytes([]byte{1}, 32), nil
+	}
+	return common.LeftPadBytes([]byte{0}, 32), nil
+}
+
+// session key view, returning whether the session key is authorized
+func (m *mockContract) isSessionKeyValid(methodParams []byte) ([]byte, error) {
+	sessionKey := common.BytesToAddress(methodParams)
+	for _, key := range m.sessionKeys {
+		if key == sessionKey {
+			return common.LeftPadBytes([]byte{1}, 32), nil
+		}
+	}
+	return common.LeftPadBytes([]byte{0}, 32), nil
+}
+
+// EIP-5267 "eip712Domain" method call
+func (m *mockContract) _84b0196e() ([]byte, error) {
+	if m.eip5267Domain == nil {
+		return nil, errors.New("execution reverted")
+	}
+
+	d := m.eip5267Domain
+	chainID := d.ChainID
+	if chainID == nil {
+		chainID = new(big.Int)
+	}
+	return mustABI(ERCs.EIP5267ABI).Methods["eip712Domain"].Outputs.Pack(
+		[1]byte{byte(d.Fields)}, d.Name, d.Version, chainID, d.VerifyingContract, d.Salt, []*big.Int{},
+	)
+}
+
+func (m *mockContract) isAuthorizedSignature(data [32]byte, sig []byte, address common.Address) ([]byte, error) {
 	// split to 65 bytes (130 hex) chunks
 	multiSigs := chunk65Bytes(sig)
 	expectedAuthrorisedSig := multiSigs[0][:]
 	expectedAuthrorisedSig[64] -= 27 // Transform V from 27/28 to 0/1 according to the yellow paper
 
-	dataErc191Hash := erc191MessageHash(data[:], m.address)
-	recoveredKey, err := ethCrypto.SigToPub(dataErc191Hash, expectedAuthrorisedSig)
+	signedHash := data[:]
+	if m.digestBuilder != nil {
+		signedHash = m.digestBuilder(data[:], address)
+	} else if m.hashScheme == HashSchemeERC191 {
+		signedHash = erc191MessageHash(data[:], address)
+	}
+	recoveredKey, err := ethCrypto.SigToPub(signedHash, expectedAuthrorisedSig)
 	if err != nil {
 		return nil, err
 	}
@@ -94,32 +374,3 @@ func _true() ([]byte, error) {
 func _false() ([]byte, error) {
 	return hex.DecodeString("0000000000000000000000000000000000000000000000000000000000000000")
 }
-
-func erc191MessageHash(msg []byte, address common.Address) []byte {
-
-	b := append([]byte{}, 25, 0)
-	b = append(b, address.Bytes()...)
-	b = append(b, msg...)
-
-	return ethCrypto.Keccak256(b)
-}
-
-func chunk65Bytes(b []byte) [][65]byte {
-	chunkSize := 65
-	var chunks [][65]byte
-
-	for i := 0; i < len(b); i += chunkSize {
-		end := i + chunkSize
-
-		if end > len(b) {
-			end = len(b)
-		}
-
-		var chunk [65]byte
-		copy(chunk[:], b[i:end])
-
-		chunks = append(chunks, chunk)
-	}
-
-	return chunks
-}
@@ -0,0 +1,124 @@
+package dappauth
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	// ErrChallengeConsumed is returned when the challenge of the address was rotated concurrently, i.e. it was already used.
+	ErrChallengeConsumed = errors.New("dappauth: challenge already consumed")
+	// ErrNoChallengeIssued is returned when the store has no challenge issued to the address.
+	ErrNoChallengeIssued = errors.New("dappauth: no challenge issued to address")
+	// ErrNoChallengeNonce is returned when the challenge has no "Nonce: " line with a uint64 nonce.
+	ErrNoChallengeNonce = errors.New("dappauth: challenge has no nonce")
+	// ErrNonceTooLow is returned when the nonce of the challenge isn't greater than the last seen nonce, i.e. replayed.
+	ErrNonceTooLow = errors.New("dappauth: challenge nonce too low")
+)
+
+// ChallengeStore stores the challenge the server issued to each address.
+type ChallengeStore interface {
+	// Challenge returns the current challenge of the address (empty if none was issued).
+	Challenge(addr common.Address) (string, error)
+}
+
+// NonceStore stores the current challenge (nonce) expected from each address.
+type NonceStore interface {
+	ChallengeStore
+	// Rotate atomically replaces the challenge of the address with a fresh one, only if it is still the given challenge,
+	// returning the fresh challenge, or ok = false if the challenge has changed since.
+	Rotate(addr common.Address, challenge string) (next string, ok bool, err error)
+}
+
+// VerifyAndRotate checks if the address is an authorized signer for its current challenge in the store and,
+// if so, rotates the challenge, returning the fresh one (to be signed next). The rotation is conditional on the challenge being unchanged,
+// so that a signature authorizes at most once even under concurrent use (ErrChallengeConsumed).
+func (a *Authenticator) VerifyAndRotate(store NonceStore, addrHex, signature string) (authorized bool, next string, err error) {
+
+	addr, err := a.normalizeAddress(addrHex)
+	if err != nil {
+		return false, "", err
+	}
+
+	challenge, err := store.Challenge(addr)
+	if err != nil {
+		return false, "", err
+	}
+	if challenge == "" {
+		return false, "", ErrNoChallengeIssued
+	}
+
+	isAuthorizedSigner, err := a.IsAuthorizedSignerAddr(challenge, signature, addr)
+	if err != nil || !isAuthorizedSigner {
+		return false, "", err
+	}
+
+	next, ok, err := store.Rotate(addr, challenge)
+	if err != nil {
+		return false, "", err
+	}
+	if !ok {
+		return false, "", ErrChallengeConsumed
+	}
+	return true, next, nil
+}
+
+// VerifyIssued checks if the address is an authorized signer for the challenge the server issued to it, per the store,
+// so that the signature must be over that exact challenge rather than any message the client chose.
+func (a *Authenticator) VerifyIssued(store ChallengeStore, addrHex, signature string) (bool, error) {
+
+	addr, err := a.normalizeAddress(addrHex)
+	if err != nil {
+		return false, err
+	}
+
+	challenge, err := store.Challenge(addr)
+	if err != nil {
+		return false, err
+	}
+	if challenge == "" {
+		return false, ErrNoChallengeIssued
+	}
+
+	return a.IsAuthorizedSignerAddr(challenge, signature, addr)
+}
+
+// VerifyIncreasingNonce checks if the address is an authorized signer for the challenge and that the nonce of the
+// challenge is greater than lastSeen, for stateless replay protection by monotonic nonces. The nonce is the decimal
+// uint64 of the "Nonce: " line of the challenge message, e.g. "Sign in to example.com\nNonce: 42". It is returned for
+// the caller to store as the next lastSeen once authorized.
+func (a *Authenticator) VerifyIncreasingNonce(challenge, signature, addrHex string, lastSeen uint64) (authorized bool, nonce uint64, err error) {
+
+	msg, err := a.extractableMessage(challenge)
+	if err != nil {
+		return false, 0, err
+	}
+	nonce, err = challengeNonce(msg)
+	if err != nil {
+		return false, 0, err
+	}
+	if nonce <= lastSeen {
+		return false, nonce, ErrNonceTooLow
+	}
+
+	authorized, err = a.IsAuthorizedSigner(challenge, signature, addrHex)
+	return authorized, nonce, err
+}
+
+// challengeNonce returns the uint64 of the "Nonce: " line of the challenge message.
+func challengeNonce(msg []byte) (uint64, error) {
+	for _, line := range strings.Split(string(msg), "\n") {
+		if !strings.HasPrefix(line, "Nonce: ") {
+			continue
+		}
+		nonce, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "Nonce: ")), 10, 64)
+		if err != nil {
+			return 0, ErrNoChallengeNonce
+		}
+		return nonce, nil
+	}
+	return 0, ErrNoChallengeNonce
+}
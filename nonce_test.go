@@ -0,0 +1,188 @@
+package dappauth
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+type memoryNonceStore struct {
+	challenges map[common.Address]string
+	rotations  int
+	mu         sync.Mutex
+}
+
+func (s *memoryNonceStore) Challenge(addr common.Address) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.challenges[addr], nil
+}
+
+func (s *memoryNonceStore) Rotate(addr common.Address, challenge string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.challenges[addr] != challenge {
+		return "", false, nil
+	}
+	s.rotations++
+	s.challenges[addr] = fmt.Sprintf("nonce-%d", s.rotations)
+	return s.challenges[addr], true, nil
+}
+
+func TestVerifyAndRotate(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	authenticator := NewAuthenticator(nil, &mockContract{})
+
+	t.Run("Authorized signers should rotate the challenge", func(t *testing.T) {
+		store := &memoryNonceStore{challenges: map[common.Address]string{addrA: "foo"}}
+		sig := signEOAPersonalMessage("foo", keyA, t)
+
+		isAuthorizedSigner, next, err := authenticator.VerifyAndRotate(store, addrA.Hex(), sig)
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+		expectBool(next == "nonce-1", true, t)
+
+		t.Run("Replayed signatures should NOT be authorized after the rotation", func(t *testing.T) {
+			isAuthorizedSigner, next, err := authenticator.VerifyAndRotate(store, addrA.Hex(), sig)
+			checkError(err, t)
+			expectBool(isAuthorizedSigner, false, t)
+			expectBool(next == "", true, t)
+		})
+
+		t.Run("Signatures over the fresh challenge should be authorized", func(t *testing.T) {
+			isAuthorizedSigner, next, err := authenticator.VerifyAndRotate(store, addrA.Hex(), signEOAPersonalMessage("nonce-1", keyA, t))
+			checkError(err, t)
+			expectBool(isAuthorizedSigner, true, t)
+			expectBool(next == "nonce-2", true, t)
+		})
+	})
+
+	t.Run("Unauthorized signers should NOT rotate the challenge", func(t *testing.T) {
+		store := &memoryNonceStore{challenges: map[common.Address]string{addrA: "foo"}}
+
+		isAuthorizedSigner, _, err := authenticator.VerifyAndRotate(store, addrA.Hex(), signEOAPersonalMessage("foo", keyB, t))
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, false, t)
+		expectBool(store.challenges[addrA] == "foo", true, t)
+	})
+
+	t.Run("Concurrent uses of a signature should authorize once", func(t *testing.T) {
+		store := &memoryNonceStore{challenges: map[common.Address]string{addrA: "foo"}}
+		sig := signEOAPersonalMessage("foo", keyA, t)
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		authorizations := 0
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				isAuthorizedSigner, _, err := authenticator.VerifyAndRotate(store, addrA.Hex(), sig)
+				if err != nil && err != ErrChallengeConsumed {
+					t.Error(err)
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				if isAuthorizedSigner {
+					authorizations++
+				}
+			}()
+		}
+		wg.Wait()
+		expectBool(authorizations == 1, true, t)
+	})
+
+	t.Run("Addresses without an issued challenge should error with ErrNoChallengeIssued", func(t *testing.T) {
+		store := &memoryNonceStore{challenges: map[common.Address]string{}}
+
+		isAuthorizedSigner, next, err := authenticator.VerifyAndRotate(store, addrA.Hex(), signEOAPersonalMessage("", keyA, t))
+		expectBool(err == ErrNoChallengeIssued, true, t)
+		expectBool(isAuthorizedSigner, false, t)
+		expectBool(next == "", true, t)
+	})
+}
+
+func TestVerifyIssued(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrB := ethCrypto.PubkeyToAddress(keyB.PublicKey)
+
+	authenticator := NewAuthenticator(nil, &mockContract{})
+	store := &memoryNonceStore{challenges: map[common.Address]string{addrA: "foo"}}
+
+	issuedTests := []struct {
+		title       string
+		addr        common.Address
+		signature   string
+		expected    bool
+		expectedErr error
+	}{
+		{"Signatures over the issued challenge should be authorized", addrA, signEOAPersonalMessage("foo", keyA, t), true, nil},
+		{"Signatures over a self-chosen challenge should NOT be authorized", addrA, signEOAPersonalMessage("bar", keyA, t), false, nil},
+		{"Addresses without an issued challenge should error with ErrNoChallengeIssued", addrB, signEOAPersonalMessage("foo", keyB, t), false, ErrNoChallengeIssued},
+	}
+
+	for _, test := range issuedTests {
+		t.Run(test.title, func(t *testing.T) {
+			isAuthorizedSigner, err := authenticator.VerifyIssued(store, test.addr.Hex(), test.signature)
+			expectBool(err == test.expectedErr, true, t)
+			expectBool(isAuthorizedSigner, test.expected, t)
+		})
+	}
+}
+
+func TestVerifyIncreasingNonce(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	authenticator := NewAuthenticator(nil, &mockContract{})
+
+	nonceTests := []struct {
+		title         string
+		challenge     string
+		lastSeen      uint64
+		expected      bool
+		expectedNonce uint64
+		expectedErr   error
+	}{
+		{"Increasing nonces should be authorized", "Sign in to example.com\nNonce: 42", 41, true, 42, nil},
+		{"Nonces far above the last seen should be authorized", "Sign in to example.com\nNonce: 1000", 41, true, 1000, nil},
+		{"Replayed nonces should error with ErrNonceTooLow", "Sign in to example.com\nNonce: 42", 42, false, 42, ErrNonceTooLow},
+		{"Older nonces should error with ErrNonceTooLow", "Sign in to example.com\nNonce: 40", 42, false, 40, ErrNonceTooLow},
+		{"Challenges without a nonce should error with ErrNoChallengeNonce", "Sign in to example.com", 0, false, 0, ErrNoChallengeNonce},
+		{"Challenges with a non-numeric nonce should error with ErrNoChallengeNonce", "Sign in to example.com\nNonce: abc", 0, false, 0, ErrNoChallengeNonce},
+	}
+
+	for _, test := range nonceTests {
+		t.Run(test.title, func(t *testing.T) {
+			isAuthorizedSigner, nonce, err := authenticator.VerifyIncreasingNonce(test.challenge, signEOAPersonalMessage(test.challenge, keyA, t), addrA.Hex(), test.lastSeen)
+			expectBool(err == test.expectedErr, true, t)
+			expectBool(isAuthorizedSigner, test.expected, t)
+			expectBool(nonce == test.expectedNonce, true, t)
+		})
+	}
+
+	t.Run("Signatures over another nonce should NOT be authorized", func(t *testing.T) {
+		sig := signEOAPersonalMessage("Sign in to example.com\nNonce: 41", keyA, t)
+		isAuthorizedSigner, _, _ := authenticator.VerifyIncreasingNonce("Sign in to example.com\nNonce: 42", sig, addrA.Hex(), 41)
+		expectBool(isAuthorizedSigner, false, t)
+	})
+}
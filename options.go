@@ -0,0 +1,155 @@
+package dappauth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	// ErrInvalidAddress is returned under WithStrictAddresses when the address argument isn't a 20 bytes hex address.
+	ErrInvalidAddress = errors.New("dappauth: address is not a 20 bytes hex address")
+	// ErrUnresolvedName is returned under WithStrictAddresses when the address argument is a name (e.g. an ENS name),
+	// as names are only resolved by an address normalizer.
+	ErrUnresolvedName = errors.New("dappauth: address is a name, which is not resolved")
+)
+
+// Option configures optional behaviour of an Authenticator.
+type Option func(*Authenticator)
+
+// SignatureDecoder transforms a raw signature as received from the client into the canonical
+// signature bytes (65 bytes per signer) that the standard parsing expects.
+type SignatureDecoder func(raw []byte) (canonical []byte, err error)
+
+// WithSignatureDecoder registers a decoder that is applied to the signature before the standard parsing.
+// Useful for wallets that wrap the standard signature within a custom encoding (e.g. a header).
+func WithSignatureDecoder(decoder SignatureDecoder) Option {
+	return func(a *Authenticator) {
+		if decoder != nil {
+			a.decodeSignature = decoder
+		}
+	}
+}
+
+// WithCallFrom sets the address used as the caller (msg.sender) of the eth_call to the wallet contract.
+// By default the zero address is used, which some wallet contracts reject during isValidSignature.
+// Since the call is never mined any address can be used, e.g. a dummy EOA with no funds; note however that
+// a wallet contract may behave differently depending on msg.sender, so prefer an address with no special role.
+func WithCallFrom(from common.Address) Option {
+	return func(a *Authenticator) {
+		a.callFrom = from
+	}
+}
+
+// AddressFilter returns false for addresses which must never be authorized.
+type AddressFilter func(addr common.Address) bool
+
+// WithAddressFilter sets a filter applied after a signature was successfully verified, to the claimed address and,
+// for smart-contract wallets, to the inner signers recovered from the signature (if any). If the filter rejects any
+// of them the verification fails with ErrAddressFiltered. Useful for allowlists and denylists.
+func WithAddressFilter(filter AddressFilter) Option {
+	return func(a *Authenticator) {
+		a.addressFilter = filter
+	}
+}
+
+// AddressNormalizer maps the address argument to an EVM address, e.g. to support non-hex identifier formats.
+type AddressNormalizer func(addr string) (common.Address, error)
+
+// WithAddressNormalizer sets how address arguments are mapped to EVM addresses (default = parsed as hex).
+func WithAddressNormalizer(normalizer AddressNormalizer) Option {
+	return func(a *Authenticator) {
+		a.addressNormalizer = normalizer
+	}
+}
+
+// ChecksumMismatchHook is called with the address argument which isn't EIP-55 checksummed (e.g. all lowercase) and the
+// address it stands for.
+type ChecksumMismatchHook func(input string, addr common.Address)
+
+// WithChecksumMismatchHook sets a hook called, e.g. to log a warning, when a hex address argument isn't properly EIP-55
+// checksummed. Verification is unaffected: addresses are compared as their 20 bytes regardless of casing.
+func WithChecksumMismatchHook(hook ChecksumMismatchHook) Option {
+	return func(a *Authenticator) {
+		a.checksumMismatchHook = hook
+	}
+}
+
+// WithStrictAddresses rejects address arguments which aren't 20 bytes hex addresses with ErrInvalidAddress (or
+// ErrUnresolvedName for names such as ENS names), rather than leniently parsing them, e.g. to the zero address.
+// Address arguments mapped by an address normalizer (which may resolve names) are left to the normalizer.
+func WithStrictAddresses(strict bool) Option {
+	return func(a *Authenticator) {
+		a.strictAddresses = strict
+	}
+}
+
+// RecoverFunc recovers the address which signed the hash, the signature being r ‖ s ‖ v with v 0/1 (as ethCrypto.Ecrecover).
+type RecoverFunc func(hash, sig []byte) (common.Address, error)
+
+// WithRecoverFunc sets the recovery of addresses from signatures (default = go-ethereum's secp256k1), e.g. to use a
+// hardware accelerated or batched recovery backend. Recovery of full public keys (IsSignerPublicKey) is unaffected.
+func WithRecoverFunc(recoverFn RecoverFunc) Option {
+	return func(a *Authenticator) {
+		a.recoverFunc = recoverFn
+	}
+}
+
+// WithClock sets the clock deadlines and expiries are enforced against (default = time.Now).
+func WithClock(now func() time.Time) Option {
+	return func(a *Authenticator) {
+		if now != nil {
+			a.now = now
+		}
+	}
+}
+
+// WithOverallDeadline bounds the time of each verification as a whole, including all its contract calls, interface
+// probes and retries, failing it with ErrDeadlineExceeded once d elapsed (cancelling the remaining calls). Unlike the
+// network context given to NewAuthenticator, the budget starts over for each verification.
+func WithOverallDeadline(d time.Duration) Option {
+	return func(a *Authenticator) {
+		a.overallDeadline = d
+	}
+}
+
+// WithMaxMultisigSignatures bounds the number of 65 bytes signatures within a concatenated multi-sig signature (default = 32).
+// Signatures exceeding it are rejected with ErrTooManySignatures before any recovery, bounding the work on untrusted input.
+func WithMaxMultisigSignatures(n int) Option {
+	return func(a *Authenticator) {
+		if n > 0 {
+			a.maxMultisigSignatures = n
+		}
+	}
+}
+
+// WithTryBothParities makes EOA recovery be retried with the flipped V parity if recovery under the given V
+// doesn't match the address. Off by default as it weakens the binding of the signature; intended for debugging
+// misbehaving clients which send a wrong V.
+func WithTryBothParities(try bool) Option {
+	return func(a *Authenticator) {
+		a.tryBothParities = try
+	}
+}
+
+// WithRecoveryIDOffset sets a constant subtracted from V of external wallet signatures before recovery, for wallets
+// which offset V by a non-standard constant (e.g. 4 for V being 31/32). Default 0. Off-spec escape hatch: signatures
+// with the standard V no longer verify under a non-zero offset.
+func WithRecoveryIDOffset(offset int) Option {
+	return func(a *Authenticator) {
+		a.recoveryIDOffset = offset
+	}
+}
+
+// WithRawRecoveryID tells that V of external wallet signatures is already normalized to 0/1 by the caller, so that
+// the 27/28 adjustment is skipped. Signatures with V being 27/28 no longer verify under it.
+func WithRawRecoveryID(raw bool) Option {
+	return func(a *Authenticator) {
+		a.rawRecoveryID = raw
+	}
+}
+
+func identitySignatureDecoder(raw []byte) ([]byte, error) {
+	return raw, nil
+}
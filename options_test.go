@@ -0,0 +1,496 @@
+package dappauth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+var _walletHeader = []byte{0xde, 0xad, 0xbe, 0xef}
+
+func stripWalletHeader(raw []byte) ([]byte, error) {
+	if len(raw) < len(_walletHeader) || !bytes.Equal(raw[:len(_walletHeader)], _walletHeader) {
+		return nil, errors.New("missing wallet header")
+	}
+	return raw[len(_walletHeader):], nil
+}
+
+func TestSignatureDecoder(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	header := "deadbeef"
+
+	t.Run("External wallets should be authorized signers when the decoder strips the header", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{}, WithSignatureDecoder(stripWalletHeader))
+		sig := header + signEOAPersonalMessage("foo", keyA, t)
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	t.Run("Smart-contract wallets should receive the decoded signature", func(t *testing.T) {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey}
+		authenticator := NewAuthenticator(nil, mock, WithSignatureDecoder(stripWalletHeader))
+		sig := header + signERC1654PersonalMessage("foo", keyB, addrA, t)
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	t.Run("Decoder errors should be returned", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{}, WithSignatureDecoder(stripWalletHeader))
+		sig := signEOAPersonalMessage("foo", keyA, t)
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+		expectBool(err != nil, true, t)
+		expectBool(isAuthorizedSigner, false, t)
+	})
+}
+
+func TestCallFrom(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	from := common.HexToAddress("0x000000000000000000000000000000000000bEEF")
+	sig := signERC1654PersonalMessage("foo", keyB, addrA, t)
+
+	t.Run("Contract calls should be made from the zero address by default", func(t *testing.T) {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, lastCallFrom: from}
+		authenticator := NewAuthenticator(nil, mock)
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+		expectBool(mock.lastCallFrom == common.Address{}, true, t)
+	})
+
+	t.Run("Contract calls should be made from the configured address", func(t *testing.T) {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey}
+		authenticator := NewAuthenticator(nil, mock, WithCallFrom(from))
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+		expectBool(mock.lastCallFrom == from, true, t)
+	})
+}
+
+func TestAddressFilter(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrB := ethCrypto.PubkeyToAddress(keyB.PublicKey)
+
+	allowlist := func(allowed ...common.Address) AddressFilter {
+		return func(addr common.Address) bool {
+			for _, a := range allowed {
+				if a == addr {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	denylist := func(denied ...common.Address) AddressFilter {
+		return func(addr common.Address) bool {
+			return !allowlist(denied...)(addr)
+		}
+	}
+
+	filterTests := []struct {
+		title                         string
+		isEOA                         bool
+		filter                        AddressFilter
+		expectedAuthorizedSignerError error
+		expectedAuthorizedSigner      bool
+	}{
+		{"External wallets on the allowlist should be authorized", true, allowlist(addrA), nil, true},
+		{"External wallets NOT on the allowlist should be filtered", true, allowlist(addrB), ErrAddressFiltered, false},
+		{"External wallets on the denylist should be filtered", true, denylist(addrA), ErrAddressFiltered, false},
+		{"External wallets NOT on the denylist should be authorized", true, denylist(addrB), nil, true},
+		{"Smart-contract wallets with allowlisted wallet and inner signer should be authorized", false, allowlist(addrA, addrB), nil, true},
+		{"Smart-contract wallets with a non allowlisted inner signer should be filtered", false, allowlist(addrA), ErrAddressFiltered, false},
+		{"Smart-contract wallets on the denylist should be filtered", false, denylist(addrA), ErrAddressFiltered, false},
+		{"Smart-contract wallets with a denylisted inner signer should be filtered", false, denylist(addrB), ErrAddressFiltered, false},
+	}
+
+	for _, test := range filterTests {
+		t.Run(test.title, func(t *testing.T) {
+			mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey}
+			authenticator := NewAuthenticator(nil, mock, WithAddressFilter(test.filter))
+
+			var sig string
+			if test.isEOA {
+				sig = signEOAPersonalMessage("foo", keyA, t)
+			} else {
+				sig = signERC1654PersonalMessage("foo", keyB, addrA, t)
+			}
+
+			isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+			if !errors.Is(err, test.expectedAuthorizedSignerError) {
+				t.Errorf("expected %v to be %v", err, test.expectedAuthorizedSignerError)
+			}
+			expectBool(isAuthorizedSigner, test.expectedAuthorizedSigner, t)
+		})
+	}
+}
+
+func TestMaxMultisigSignatures(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey}
+	sig := signERC1654PersonalMessage("foo", keyB, addrA, t)
+
+	t.Run("A multi-sig signature within the cap should be verified", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, mock, WithMaxMultisigSignatures(2))
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sig+sig, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	t.Run("A multi-sig signature exceeding the cap should be rejected", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, mock, WithMaxMultisigSignatures(2))
+
+		_, err := authenticator.IsAuthorizedSigner("foo", sig+sig+sig, addrA.Hex())
+		expectBool(errors.Is(err, ErrTooManySignatures), true, t)
+	})
+
+	t.Run("A multi-sig signature exceeding the default cap should be rejected", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, mock)
+
+		_, err := authenticator.IsAuthorizedSigner("foo", strings.Repeat(sig, defaultMaxMultisigSignatures+1), addrA.Hex())
+		expectBool(errors.Is(err, ErrTooManySignatures), true, t)
+	})
+}
+
+func TestTryBothParities(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+
+	sigBytes := common.FromHex(signEOAPersonalMessage("foo", keyA, t))
+	sigBytes[64] = 55 - sigBytes[64] // send the wrong V
+	wrongV := common.Bytes2Hex(sigBytes)
+
+	isAuthorizedSigner, err := NewAuthenticator(nil, &mockContract{}).IsAuthorizedSigner("foo", wrongV, addrA.Hex())
+	checkError(err, t)
+	expectBool(isAuthorizedSigner, false, t)
+
+	isAuthorizedSigner, err = NewAuthenticator(nil, &mockContract{}, WithTryBothParities(true)).IsAuthorizedSigner("foo", wrongV, addrA.Hex())
+	checkError(err, t)
+	expectBool(isAuthorizedSigner, true, t)
+}
+
+func TestAddressNormalizer(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	errUnknownAccount := errors.New("unknown account")
+
+	// maps the account identifiers of another chain to the EVM addresses they are linked to
+	accounts := map[string]common.Address{"7EcDhSYGxXyscszYEp35KHN8vvw3svAuLKTzXwCFLtV": addrA}
+	authenticator := NewAuthenticator(nil, &mockContract{}, WithAddressNormalizer(func(addr string) (common.Address, error) {
+		if evmAddr, ok := accounts[addr]; ok {
+			return evmAddr, nil
+		}
+		return common.Address{}, errUnknownAccount
+	}))
+	sig := signEOAPersonalMessage("foo", keyA, t)
+
+	t.Run("Normalized addresses should be authorized signers", func(t *testing.T) {
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sig, "7EcDhSYGxXyscszYEp35KHN8vvw3svAuLKTzXwCFLtV")
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	t.Run("Addresses failing to normalize should error", func(t *testing.T) {
+		_, err := authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+		expectBool(errors.Is(err, errUnknownAccount), true, t)
+	})
+}
+
+func TestRecoverFunc(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrB := ethCrypto.PubkeyToAddress(keyB.PublicKey)
+	sig := signEOAPersonalMessage("foo", keyA, t)
+
+	t.Run("The recover func should be invoked with the 0/1 V signature", func(t *testing.T) {
+		calls := 0
+		authenticator := NewAuthenticator(nil, &mockContract{}, WithRecoverFunc(func(hash, sig []byte) (common.Address, error) {
+			calls++
+			expectBool(sig[64] == 0 || sig[64] == 1, true, t)
+			pub, err := ethCrypto.SigToPub(hash, sig)
+			if err != nil {
+				return common.Address{}, err
+			}
+			return ethCrypto.PubkeyToAddress(*pub), nil
+		}))
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+		expectBool(calls == 1, true, t)
+	})
+
+	t.Run("The result of the recover func should be used", func(t *testing.T) {
+		// the stub always recovers B
+		authenticator := NewAuthenticator(nil, &mockContract{}, WithRecoverFunc(func(hash, sig []byte) (common.Address, error) {
+			return addrB, nil
+		}))
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sig, addrB.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+}
+
+func TestRecoveryIDOffset(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	sig := signEOAPersonalMessage("foo", keyA, t)
+
+	sigBytes := common.FromHex(sig)
+	sigBytes[64] += 4 // the wallet sends V as 31/32
+	offsetV := common.Bytes2Hex(sigBytes)
+
+	offsetTests := []struct {
+		title     string
+		offset    int
+		signature string
+		expected  bool
+	}{
+		{"Offset signatures should NOT be authorized without offset", 0, offsetV, false},
+		{"Offset signatures should be authorized under the matching offset", 4, offsetV, true},
+		{"Offset signatures should NOT be authorized under another offset", 2, offsetV, false},
+		{"Standard signatures should NOT be authorized under an offset", 4, sig, false},
+	}
+
+	for _, test := range offsetTests {
+		t.Run(test.title, func(t *testing.T) {
+			// mismatches fall through to the contract path, where the mock errors on the off-spec V
+			authenticator := NewAuthenticator(nil, &mockContract{}, WithRecoveryIDOffset(test.offset))
+			isAuthorizedSigner, _ := authenticator.IsAuthorizedSigner("foo", test.signature, addrA.Hex())
+			expectBool(isAuthorizedSigner, test.expected, t)
+		})
+	}
+}
+
+func TestRawRecoveryID(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	sig := signEOAPersonalMessage("foo", keyA, t)
+
+	sigBytes := common.FromHex(sig)
+	sigBytes[64] -= 27 // the caller normalized V to 0/1
+	rawV := common.Bytes2Hex(sigBytes)
+
+	rawTests := []struct {
+		title     string
+		raw       bool
+		signature string
+		expected  bool
+	}{
+		{"Pre-normalized signatures should NOT be authorized by default", false, rawV, false},
+		{"Pre-normalized signatures should be authorized under WithRawRecoveryID", true, rawV, true},
+		{"Standard signatures should be authorized by default", false, sig, true},
+		{"Standard signatures should NOT be authorized under WithRawRecoveryID", true, sig, false},
+	}
+
+	for _, test := range rawTests {
+		t.Run(test.title, func(t *testing.T) {
+			// mismatches fall through to the contract path, where the mock errors on the off-spec V
+			authenticator := NewAuthenticator(nil, &mockContract{}, WithRawRecoveryID(test.raw))
+			isAuthorizedSigner, _ := authenticator.IsAuthorizedSigner("foo", test.signature, addrA.Hex())
+			expectBool(isAuthorizedSigner, test.expected, t)
+		})
+	}
+}
+
+func TestStrictAddresses(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	sig := signEOAPersonalMessage("foo", keyA, t)
+	authenticator := NewAuthenticator(nil, &mockContract{}, WithStrictAddresses(true))
+
+	addressTests := []struct {
+		title    string
+		address  string
+		expected error
+	}{
+		{"Hex addresses should be accepted", addrA.Hex(), nil},
+		{"Unprefixed hex addresses should be accepted", addrA.Hex()[2:], nil},
+		{"Bare ENS names should be rejected", "vitalik.eth", ErrUnresolvedName},
+		{"Hex strings of an invalid length should be rejected", addrA.Hex()[:40], ErrInvalidAddress},
+		{"Empty addresses should be rejected", "", ErrInvalidAddress},
+	}
+
+	for _, test := range addressTests {
+		t.Run(test.title, func(t *testing.T) {
+			isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sig, test.address)
+			if test.expected == nil {
+				checkError(err, t)
+				expectBool(isAuthorizedSigner, true, t)
+				return
+			}
+			expectBool(errors.Is(err, test.expected), true, t)
+		})
+	}
+
+	t.Run("Addresses should be parsed leniently by default", func(t *testing.T) {
+		_, err := NewAuthenticator(nil, &mockContract{}).IsAuthorizedSigner("foo", sig, "vitalik.eth")
+		expectBool(errors.Is(err, ErrUnresolvedName), false, t)
+	})
+}
+
+// takes delay per contract call, unless the call is cancelled
+type slowBackend struct {
+	*mockContract
+	delay time.Duration
+}
+
+func (b *slowBackend) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	select {
+	case <-time.After(b.delay):
+		return b.mockContract.CallContract(ctx, call, blockNumber)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestOverallDeadline(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyC, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrC := ethCrypto.PubkeyToAddress(keyC.PublicKey)
+	sigB := signERC1654PersonalMessage("foo", keyB, addrA, t)
+
+	// the wallet only implements the legacy interface, so verifying takes two calls
+	newBackend := func() *slowBackend {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, code: []byte{1}, revertSelectors: []string{"1626ba7e"}}
+		return &slowBackend{mockContract: mock, delay: 100 * time.Millisecond}
+	}
+
+	t.Run("Verifications within the deadline should complete", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, newBackend(), WithOverallDeadline(time.Second))
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sigB, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	t.Run("Verifications exceeding the deadline should terminate early", func(t *testing.T) {
+		backend := newBackend()
+		authenticator := NewAuthenticator(nil, backend, WithOverallDeadline(150*time.Millisecond))
+
+		start := time.Now()
+		_, err := authenticator.IsAuthorizedSigner("foo", sigB, addrA.Hex())
+		expectBool(err == ErrDeadlineExceeded, true, t)
+		expectBool(time.Since(start) < 200*time.Millisecond, true, t)
+		expectBool(backend.calls == 1, true, t) // the second call was cancelled
+	})
+
+	t.Run("The budget should start over for each verification", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, newBackend(), WithOverallDeadline(250*time.Millisecond))
+		for i := 0; i < 2; i++ {
+			isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sigB, addrA.Hex())
+			checkError(err, t)
+			expectBool(isAuthorizedSigner, true, t)
+		}
+	})
+
+	t.Run("External wallets should be verified without calls", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, newBackend(), WithOverallDeadline(time.Nanosecond))
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", signEOAPersonalMessage("foo", keyC, t), addrC.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+}
+
+func TestChecksumMismatchHook(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	sig := signEOAPersonalMessage("foo", keyA, t)
+
+	var mismatches []string
+	authenticator := NewAuthenticator(nil, &mockContract{}, WithChecksumMismatchHook(func(input string, addr common.Address) {
+		expectBool(addr == addrA, true, t)
+		mismatches = append(mismatches, input)
+	}))
+
+	checksumTests := []struct {
+		title    string
+		address  string
+		mismatch bool
+	}{
+		{"Checksummed addresses should verify without warning", addrA.Hex(), false},
+		{"Unprefixed checksummed addresses should verify without warning", addrA.Hex()[2:], false},
+		{"Lowercase addresses should verify with a warning", strings.ToLower(addrA.Hex()), true},
+		{"Uppercase addresses should verify with a warning", "0x" + strings.ToUpper(addrA.Hex()[2:]), true},
+	}
+
+	for _, test := range checksumTests {
+		t.Run(test.title, func(t *testing.T) {
+			mismatches = nil
+			isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sig, test.address)
+			checkError(err, t)
+			expectBool(isAuthorizedSigner, true, t)
+			expectBool(len(mismatches) == 1, test.mismatch, t)
+		})
+	}
+}
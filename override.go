@@ -0,0 +1,61 @@
+package dappauth
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrStateOverrideUnsupported is returned when state overrides are configured but the backend can't apply them.
+var ErrStateOverrideUnsupported = errors.New("dappauth: backend does not support eth_call state overrides")
+
+// OverrideAccount overrides the state of an account for the duration of an eth_call, as the eth_call state override
+// set of geth. Nil fields are left as is.
+type OverrideAccount struct {
+	Nonce     *uint64
+	Code      []byte
+	Balance   *big.Int
+	State     map[common.Hash]common.Hash // replaces the whole storage of the account
+	StateDiff map[common.Hash]common.Hash // replaces the given storage slots only
+}
+
+// StateOverride is the state override set of an eth_call, by account.
+type StateOverride map[common.Address]OverrideAccount
+
+// StateOverrideCaller is implemented by backends able to make eth_calls with state overrides
+// (e.g. an adapter of gethclient.Client).
+type StateOverrideCaller interface {
+	CallContractWithStateOverride(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int, override StateOverride) ([]byte, error)
+}
+
+// WithStateOverride verifies smart-contract wallets against the state with the overrides applied, e.g. to verify against
+// a wallet configuration which isn't on-chain yet. The backend must implement StateOverrideCaller, otherwise contract
+// calls fail with ErrStateOverrideUnsupported.
+func WithStateOverride(override StateOverride) Option {
+	return func(a *Authenticator) {
+		a.stateOverride = override
+	}
+}
+
+// callContractWithStateOverride makes the eth_call with the configured state overrides applied.
+func (a *Authenticator) callContractWithStateOverride(msg ethereum.CallMsg) ([]byte, error) {
+	caller, ok := a.cc.(StateOverrideCaller)
+	if !ok {
+		return nil, ErrStateOverrideUnsupported
+	}
+	return caller.CallContractWithStateOverride(a.context(), msg, a.callBlock, a.stateOverride)
+}
+
+// codeAt returns the code at the address, as overridden if it is, in the pending state if configured.
+func (a *Authenticator) codeAt(addr common.Address) ([]byte, error) {
+	if account, ok := a.stateOverride[addr]; ok && account.Code != nil {
+		return account.Code, nil
+	}
+	if a.pendingState {
+		return a.pendingCodeAt(addr)
+	}
+	return a.cc.CodeAt(a.context(), addr, a.callBlock)
+}
@@ -0,0 +1,77 @@
+package dappauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// honors state overrides of the owner of wallets, stored at slot 0
+type overrideBackend struct {
+	*mockContract
+	keys map[common.Address]*ecdsa.PublicKey // the keys of the possible owners
+}
+
+func (b *overrideBackend) CallContractWithStateOverride(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int, override StateOverride) ([]byte, error) {
+	account, ok := override[*call.To]
+	if !ok {
+		return b.CallContract(ctx, call, blockNumber)
+	}
+
+	owner := common.BytesToAddress(account.StateDiff[common.Hash{}].Bytes())
+	wallet := &mockContract{address: *call.To, authorizedKey: b.keys[owner], code: account.Code}
+	return wallet.CallContract(ctx, call, blockNumber)
+}
+
+func TestStateOverride(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrB := ethCrypto.PubkeyToAddress(keyB.PublicKey)
+	sigB := signERC1654PersonalMessage("foo", keyB, addrA, t)
+
+	// on-chain, the wallet at A has no authorized owner
+	backend := &overrideBackend{
+		mockContract: &mockContract{address: addrA},
+		keys:         map[common.Address]*ecdsa.PublicKey{addrB: &keyB.PublicKey},
+	}
+	ownedByB := StateOverride{addrA: OverrideAccount{
+		Code:      []byte{1},
+		StateDiff: map[common.Hash]common.Hash{{}: common.BytesToHash(addrB.Bytes())},
+	}}
+
+	t.Run("Signers should NOT be authorized per the on-chain state", func(t *testing.T) {
+		isAuthorizedSigner, err := NewAuthenticator(nil, backend).IsAuthorizedSigner("foo", sigB, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, false, t)
+	})
+
+	t.Run("Signers should be authorized per the overridden state", func(t *testing.T) {
+		isAuthorizedSigner, err := NewAuthenticator(nil, backend, WithStateOverride(ownedByB)).IsAuthorizedSigner("foo", sigB, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	t.Run("Overrides of other accounts should NOT affect the wallet", func(t *testing.T) {
+		override := StateOverride{addrB: ownedByB[addrA]}
+		isAuthorizedSigner, err := NewAuthenticator(nil, backend, WithStateOverride(override)).IsAuthorizedSigner("foo", sigB, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, false, t)
+	})
+
+	t.Run("Backends without state override support should error", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{address: addrA}, WithStateOverride(ownedByB))
+		_, err := authenticator.IsAuthorizedSigner("foo", sigB, addrA.Hex())
+		expectBool(errors.Is(err, ErrStateOverrideUnsupported), true, t)
+	})
+}
@@ -0,0 +1,128 @@
+package dappauth
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrNoOwnerResolver is returned when counting the owners who signed without an owner resolver (see WithOwnerResolver).
+var ErrNoOwnerResolver = errors.New("dappauth: no owner resolver")
+
+// OwnerResolver resolves the owners (signing keys) of a smart-contract wallet at a block, e.g. reconstructed
+// from the OwnerAdded/OwnerRemoved events of the wallet.
+type OwnerResolver interface {
+	// OwnersAt returns the owners of the wallet at the block (nil = latest).
+	OwnersAt(addr common.Address, blockNumber *big.Int) ([]common.Address, error)
+}
+
+// WithOwnerResolver verifies smart-contract wallets against the owners resolved by the resolver, instead of via
+// isValidSignature of the wallet. The EOAs recovered from each 65 bytes chunk of the signature (per the contract hash
+// scheme) must all be owners of the wallet, chunks which don't recover failing the verification.
+func WithOwnerResolver(resolver OwnerResolver) Option {
+	return func(a *Authenticator) {
+		a.ownerResolver = resolver
+	}
+}
+
+// IsAuthorizedSignerAt is like IsAuthorizedSigner but verifies smart-contract wallets against their owners at the block,
+// as resolved by the owner resolver (see WithOwnerResolver). Results are not cached.
+func (a *Authenticator) IsAuthorizedSignerAt(challenge, signature, addrHex string, blockNumber *big.Int) (bool, error) {
+	historical := *a
+	historical.ownersBlockNumber = blockNumber
+	historical.resultCache = nil
+
+	return historical.IsAuthorizedSigner(challenge, signature, addrHex)
+}
+
+// verifyOwners verifies the signature of the smart-contract wallet against its owners resolved by the owner resolver.
+func (a *Authenticator) verifyOwners(challengeHash [32]byte, origSigBytes []byte, addr common.Address) (*Result, error) {
+
+	blockNumber := a.ownersBlockNumber
+	if blockNumber == nil {
+		blockNumber = a.callBlockNumber()
+	}
+
+	// resolved at the block reported, if known, so that the result reflects the state of its block
+	owners, err := a.ownerResolver.OwnersAt(addr, blockNumber)
+	if err != nil {
+		return nil, wrapError("owners of", addr, err)
+	}
+
+	result := &Result{
+		Path:         PathContract,
+		InnerSigners: a.recoverInnerSigners(challengeHash[:], origSigBytes, addr),
+		Digest:       common.BytesToHash(a.signedContractHash(challengeHash[:], addr)),
+		BlockNumber:  blockNumber,
+	}
+
+	// every chunk must recover to an owner, so that a signature can't be padded with junk
+	if len(result.InnerSigners) == 0 || len(result.InnerSigners) != len(origSigBytes)/65 {
+		return result, nil
+	}
+
+	isOwner := make(map[common.Address]bool, len(owners))
+	for _, owner := range owners {
+		isOwner[owner] = true
+	}
+	for _, signer := range result.InnerSigners {
+		if !isOwner[signer] {
+			return result, nil
+		}
+	}
+
+	return a.authorize(result, append([]common.Address{addr}, result.InnerSigners...)...)
+}
+
+// VerifyMultisigCount returns how many distinct owners of the smart-contract wallet signed the challenge, and which
+// (in the order of the signature), per the owner resolver (see WithOwnerResolver). The signature is the concatenation of
+// the 65 bytes signatures, per the contract hash scheme, of which those of non-owners and duplicates aren't counted.
+func (a *Authenticator) VerifyMultisigCount(challenge, concatenatedSig, walletAddr string) (signerCount int, owners []common.Address, err error) {
+
+	if a.ownerResolver == nil {
+		return 0, nil, ErrNoOwnerResolver
+	}
+
+	addr, err := a.normalizeAddress(walletAddr)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if err := a.checkChallengeValidity(challenge); err != nil {
+		return 0, nil, err
+	}
+	if err := a.checkAddressBinding(challenge, addr); err != nil {
+		return 0, nil, err
+	}
+
+	sigBytes, err := a.signatureBytes(concatenatedSig)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(sigBytes) == 0 || len(sigBytes)%65 != 0 {
+		return 0, nil, ErrInvalidSignatureLength
+	}
+
+	challengeHash, err := a.contractHash(challenge)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	walletOwners, err := a.ownerResolver.OwnersAt(addr, a.ownersBlockNumber)
+	if err != nil {
+		return 0, nil, wrapError("owners of", addr, err)
+	}
+	isOwner := make(map[common.Address]bool, len(walletOwners))
+	for _, owner := range walletOwners {
+		isOwner[owner] = true
+	}
+
+	for _, signer := range a.recoverInnerSigners(challengeHash[:], sigBytes, addr) {
+		if isOwner[signer] {
+			owners = append(owners, signer)
+			isOwner[signer] = false // counted once
+		}
+	}
+	return len(owners), owners, nil
+}
@@ -0,0 +1,159 @@
+package dappauth
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// resolves the owners of a wallet from the block at which each owner set took effect
+type mockOwnerResolver struct {
+	changes []uint64           // ascending blocks at which the owner set changed
+	owners  [][]common.Address // the owner set taking effect at each change
+}
+
+func (r *mockOwnerResolver) OwnersAt(addr common.Address, blockNumber *big.Int) ([]common.Address, error) {
+	if r.owners == nil {
+		return nil, errDummy
+	}
+
+	var owners []common.Address
+	for i, change := range r.changes {
+		if blockNumber == nil || blockNumber.Uint64() >= change {
+			owners = r.owners[i]
+		}
+	}
+	return owners, nil
+}
+
+func TestOwnerResolver(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyC, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrB := ethCrypto.PubkeyToAddress(keyB.PublicKey)
+	addrC := ethCrypto.PubkeyToAddress(keyC.PublicKey)
+
+	// B owned the wallet from block 100, and was replaced by C at block 200
+	resolver := &mockOwnerResolver{
+		changes: []uint64{100, 200},
+		owners:  [][]common.Address{{addrB}, {addrC}},
+	}
+	// the wallet itself errors, so any result comes from the resolver, and the chain is past the changes
+	authenticator := NewAuthenticator(nil, &mockContract{errorIsValidSignature: true, blockNumber: 250}, WithOwnerResolver(resolver))
+	sigB := signERC1654PersonalMessage("foo", keyB, addrA, t)
+
+	ownerTests := []struct {
+		title       string
+		blockNumber *big.Int
+		expected    bool
+	}{
+		{"Signers should NOT be authorized before they were owners", big.NewInt(99), false},
+		{"Signers should be authorized while they were owners", big.NewInt(150), true},
+		{"Signers should NOT be authorized after they were removed as owners", big.NewInt(200), false},
+		{"Signers should NOT be authorized if they are not current owners", nil, false},
+	}
+
+	for _, test := range ownerTests {
+		t.Run(test.title, func(t *testing.T) {
+			isAuthorizedSigner, err := authenticator.IsAuthorizedSignerAt("foo", sigB, addrA.Hex(), test.blockNumber)
+			checkError(err, t)
+			expectBool(isAuthorizedSigner, test.expected, t)
+		})
+	}
+
+	t.Run("Current owners should be authorized", func(t *testing.T) {
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", signERC1654PersonalMessage("foo", keyC, addrA, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	t.Run("Resolver errors should error", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{}, WithOwnerResolver(&mockOwnerResolver{}))
+
+		_, err := authenticator.IsAuthorizedSignerAt("foo", sigB, addrA.Hex(), big.NewInt(150))
+		expectBool(err != nil, true, t)
+	})
+
+	t.Run("Signatures padded with a chunk which doesn't recover should NOT be authorized", func(t *testing.T) {
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSignerAt("foo", sigB+strings.Repeat("00", 65), addrA.Hex(), big.NewInt(150))
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, false, t)
+	})
+
+	t.Run("Owners should be resolved at the block reported", func(t *testing.T) {
+		// the backend lags behind the resolver, whose latest owner set took effect after the block of the backend
+		authenticator := NewAuthenticator(nil, &mockContract{errorIsValidSignature: true, blockNumber: 150}, WithOwnerResolver(resolver))
+
+		result, err := authenticator.Verify("foo", sigB, addrA.Hex())
+		checkError(err, t)
+		expectBool(result.BlockNumber != nil && result.BlockNumber.Uint64() == 150, true, t)
+		expectBool(result.Authorized, true, t)
+	})
+}
+
+func TestVerifyMultisigCount(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyC, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyD, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrB := ethCrypto.PubkeyToAddress(keyB.PublicKey)
+	addrC := ethCrypto.PubkeyToAddress(keyC.PublicKey)
+
+	// the wallet at A is owned by B and C, not D
+	resolver := &mockOwnerResolver{changes: []uint64{0}, owners: [][]common.Address{{addrB, addrC}}}
+	authenticator := NewAuthenticator(nil, &mockContract{}, WithOwnerResolver(resolver))
+
+	sigB := signERC1654PersonalMessage("foo", keyB, addrA, t)
+	sigC := signERC1654PersonalMessage("foo", keyC, addrA, t)
+	sigD := signERC1654PersonalMessage("foo", keyD, addrA, t)
+
+	countTests := []struct {
+		title          string
+		signature      string
+		expectedOwners []common.Address
+	}{
+		{"All owners signing should count all owners", sigB + sigC, []common.Address{addrB, addrC}},
+		{"Non-owners signing should NOT be counted", sigD + sigB + sigD, []common.Address{addrB}},
+		{"Owners signing twice should be counted once", sigC + sigB + sigC, []common.Address{addrC, addrB}},
+		{"Only non-owners signing should count none", sigD, nil},
+		{"Owners signing another challenge should NOT be counted", signERC1654PersonalMessage("bar", keyB, addrA, t) + sigC, []common.Address{addrC}},
+	}
+
+	for _, test := range countTests {
+		t.Run(test.title, func(t *testing.T) {
+			signerCount, owners, err := authenticator.VerifyMultisigCount("foo", test.signature, addrA.Hex())
+			checkError(err, t)
+			expectBool(signerCount == len(test.expectedOwners), true, t)
+			expectBool(len(owners) == len(test.expectedOwners), true, t)
+			for i, owner := range owners {
+				expectBool(owner == test.expectedOwners[i], true, t)
+			}
+		})
+	}
+
+	t.Run("Counting without an owner resolver should error with ErrNoOwnerResolver", func(t *testing.T) {
+		_, _, err := NewAuthenticator(nil, &mockContract{}).VerifyMultisigCount("foo", sigB, addrA.Hex())
+		expectBool(err == ErrNoOwnerResolver, true, t)
+	})
+
+	t.Run("Signatures which aren't 65 bytes chunks should error with ErrInvalidSignatureLength", func(t *testing.T) {
+		_, _, err := authenticator.VerifyMultisigCount("foo", sigB+"00", addrA.Hex())
+		expectBool(err == ErrInvalidSignatureLength, true, t)
+	})
+}
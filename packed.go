@@ -0,0 +1,80 @@
+package dappauth
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// ErrUnsupportedPackedValue is returned when a value can't be abi.encodePacked (see EncodePacked).
+var ErrUnsupportedPackedValue = errors.New("dappauth: unsupported abi.encodePacked value")
+
+// EncodePacked encodes the values as Solidity's abi.encodePacked, per their Go type: common.Address as an address
+// (20 bytes), *big.Int as a uint256 (32 bytes, within range), common.Hash and [32]byte as a bytes32, []byte and string
+// as is, and bool as a single byte.
+func EncodePacked(values ...interface{}) ([]byte, error) {
+	var packed []byte
+	for i, value := range values {
+		switch v := value.(type) {
+		case common.Address:
+			packed = append(packed, v.Bytes()...)
+		case *big.Int:
+			if v == nil || v.Sign() < 0 || v.BitLen() > 256 {
+				return nil, fmt.Errorf("%w: value %d is not a uint256", ErrUnsupportedPackedValue, i)
+			}
+			packed = append(packed, math.PaddedBigBytes(v, 32)...)
+		case common.Hash:
+			packed = append(packed, v.Bytes()...)
+		case [32]byte:
+			packed = append(packed, v[:]...)
+		case []byte:
+			packed = append(packed, v...)
+		case string:
+			packed = append(packed, v...)
+		case bool:
+			if v {
+				packed = append(packed, 1)
+			} else {
+				packed = append(packed, 0)
+			}
+		default:
+			return nil, fmt.Errorf("%w: value %d is of type %T", ErrUnsupportedPackedValue, i, value)
+		}
+	}
+	return packed, nil
+}
+
+// PackedHash returns keccak256(abi.encodePacked(values...)), per EncodePacked.
+func PackedHash(values ...interface{}) (common.Hash, error) {
+	packed, err := EncodePacked(values...)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return ethCrypto.Keccak256Hash(packed), nil
+}
+
+// IsAuthorizedSignerPacked checks if the address is an authorized signer (either as an external wallet or as a
+// smart-contract wallet) of the challenge keccak256(abi.encodePacked(values...)), per PackedHash. The hash is signed
+// as a pre-hashed challenge, i.e. its 32 bytes are personal_signed.
+func (a *Authenticator) IsAuthorizedSignerPacked(values []interface{}, signature, addrHex string) (bool, error) {
+
+	hash, err := PackedHash(values...)
+	if err != nil {
+		return false, err
+	}
+
+	packed := *a
+	packed.challengePreHashed = true
+	packed.challengeEncoding = ChallengeEncodingRaw
+	packed.challengeExpiryExtractor = nil // the hash has no expiry, not-before time or nonce to extract
+	packed.challengeNotBeforeExtractor = nil
+	packed.challengeNonceExtractor = nil
+	packed.resultCache = nil
+
+	return packed.IsAuthorizedSigner(hexutil.Encode(hash[:]), signature, addrHex)
+}
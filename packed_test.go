@@ -0,0 +1,108 @@
+package dappauth
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestEncodePacked(t *testing.T) {
+
+	addr := common.HexToAddress("0x5B38Da6a701c568545dCfcB03FcB875f56beddC4")
+	nonce := big.NewInt(42)
+	salt := common.HexToHash("0x0101010101010101010101010101010101010101010101010101010101010101")
+
+	packedTests := []struct {
+		title    string
+		values   []interface{}
+		expected string // keccak256(abi.encodePacked(...)) per solidity
+	}{
+		{
+			"address, uint256, bytes32 should pack as solidity",
+			[]interface{}{addr, nonce, salt},
+			"0x" + "5b38da6a701c568545dcfcb03fcb875f56beddc4" +
+				"000000000000000000000000000000000000000000000000000000000000002a" +
+				"0101010101010101010101010101010101010101010101010101010101010101",
+		},
+		{
+			"string, bool, bytes should pack as solidity",
+			[]interface{}{"foo", true, []byte{0xca, 0xfe}},
+			"0x" + "666f6f" + "01" + "cafe",
+		},
+	}
+
+	for _, test := range packedTests {
+		t.Run(test.title, func(t *testing.T) {
+			packed, err := EncodePacked(test.values...)
+			checkError(err, t)
+			expectBool(hexutil.Encode(packed) == test.expected, true, t)
+
+			hash, err := PackedHash(test.values...)
+			checkError(err, t)
+			expectBool(hash == ethCrypto.Keccak256Hash(hexutil.MustDecode(test.expected)), true, t)
+		})
+	}
+
+	unsupportedTests := []struct {
+		title string
+		value interface{}
+	}{
+		{"Negative integers should error with ErrUnsupportedPackedValue", big.NewInt(-1)},
+		{"Integers wider than 256 bits should error with ErrUnsupportedPackedValue", new(big.Int).Lsh(big.NewInt(1), 256)},
+		{"Unsupported types should error with ErrUnsupportedPackedValue", 42},
+	}
+
+	for _, test := range unsupportedTests {
+		t.Run(test.title, func(t *testing.T) {
+			_, err := EncodePacked(addr, test.value)
+			expectBool(errors.Is(err, ErrUnsupportedPackedValue), true, t)
+		})
+	}
+}
+
+func TestIsAuthorizedSignerPacked(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	values := []interface{}{addrA, big.NewInt(7), common.Hash{1}}
+
+	hash, err := PackedHash(values...)
+	checkError(err, t)
+
+	authenticator := NewAuthenticator(nil, &mockContract{})
+
+	t.Run("Signatures of the packed hash should be authorized", func(t *testing.T) {
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSignerPacked(values, signEOAPersonalMessage(string(hash[:]), keyA, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	t.Run("Signatures of another packed hash should NOT be authorized", func(t *testing.T) {
+		otherHash, err := PackedHash(addrA, big.NewInt(8), common.Hash{1})
+		checkError(err, t)
+
+		isAuthorizedSigner, _ := authenticator.IsAuthorizedSignerPacked(values, signEOAPersonalMessage(string(otherHash[:]), keyA, t), addrA.Hex())
+		expectBool(isAuthorizedSigner, false, t)
+	})
+
+	t.Run("Signatures of the packed hash by another key should NOT be authorized", func(t *testing.T) {
+		isAuthorizedSigner, _ := authenticator.IsAuthorizedSignerPacked(values, signEOAPersonalMessage(string(hash[:]), keyB, t), addrA.Hex())
+		expectBool(isAuthorizedSigner, false, t)
+	})
+
+	t.Run("Challenge extractors should NOT apply to the packed hash", func(t *testing.T) {
+		expiring := NewAuthenticator(nil, &mockContract{}, WithChallengeExpiryExtractor(ExpiringChallengeExpiry))
+
+		isAuthorizedSigner, err := expiring.IsAuthorizedSignerPacked(values, signEOAPersonalMessage(string(hash[:]), keyA, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+}
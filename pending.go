@@ -0,0 +1,35 @@
+package dappauth
+
+import (
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// WithPendingState makes contract calls resolve against the pending state rather than the latest block (default =
+// false), so that e.g. an owner just added by a pending transaction is authorized. The backend must implement
+// bind.PendingContractCaller, otherwise contract calls fail with bind.ErrNoPendingState. Results then don't report a
+// BlockNumber, as the pending block isn't mined. Calls with state overrides still resolve against the latest block.
+func WithPendingState(pending bool) Option {
+	return func(a *Authenticator) {
+		a.pendingState = pending
+	}
+}
+
+// pendingCallContract makes the eth_call against the pending state.
+func (a *Authenticator) pendingCallContract(msg ethereum.CallMsg) ([]byte, error) {
+	caller, ok := a.cc.(bind.PendingContractCaller)
+	if !ok {
+		return nil, bind.ErrNoPendingState
+	}
+	return caller.PendingCallContract(a.context(), msg)
+}
+
+// pendingCodeAt returns the code at the address in the pending state.
+func (a *Authenticator) pendingCodeAt(addr common.Address) ([]byte, error) {
+	caller, ok := a.cc.(bind.PendingContractCaller)
+	if !ok {
+		return nil, bind.ErrNoPendingState
+	}
+	return caller.PendingCodeAt(a.context(), addr)
+}
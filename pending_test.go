@@ -0,0 +1,113 @@
+package dappauth
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// pendingBackend serves the latest state from the embedded mock, and the pending state from pending.
+type pendingBackend struct {
+	*mockContract
+	pending *mockContract
+}
+
+func (b *pendingBackend) PendingCodeAt(ctx context.Context, contract common.Address) ([]byte, error) {
+	return b.pending.CodeAt(ctx, contract, nil)
+}
+
+func (b *pendingBackend) PendingCallContract(ctx context.Context, call ethereum.CallMsg) ([]byte, error) {
+	return b.pending.CallContract(ctx, call, nil)
+}
+
+func TestPendingState(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyC, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+
+	// the wallet at A is owned by B as of the latest block, and by C once a pending transaction is mined
+	backend := &pendingBackend{
+		mockContract: &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, blockNumber: 100},
+		pending:      &mockContract{address: addrA, authorizedKey: &keyC.PublicKey},
+	}
+
+	sigB := signERC1654PersonalMessage("foo", keyB, addrA, t)
+	sigC := signERC1654PersonalMessage("foo", keyC, addrA, t)
+
+	stateTests := []struct {
+		title     string
+		pending   bool
+		signature string
+		expected  bool
+	}{
+		{"Latest owners should be authorized signers against the latest block", false, sigB, true},
+		{"Pending owners should NOT be authorized signers against the latest block", false, sigC, false},
+		{"Pending owners should be authorized signers against the pending state", true, sigC, true},
+		{"Removed owners should NOT be authorized signers against the pending state", true, sigB, false},
+	}
+
+	for _, test := range stateTests {
+		t.Run(test.title, func(t *testing.T) {
+			authenticator := NewAuthenticator(nil, backend, WithPendingState(test.pending))
+
+			isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", test.signature, addrA.Hex())
+			checkError(err, t)
+			expectBool(isAuthorizedSigner, test.expected, t)
+		})
+	}
+
+	t.Run("Results against the pending state should NOT report a block", func(t *testing.T) {
+		result, err := NewAuthenticator(nil, backend, WithPendingState(true)).Verify("foo", sigC, addrA.Hex())
+		checkError(err, t)
+		expectBool(result.BlockNumber == nil, true, t)
+
+		result, err = NewAuthenticator(nil, backend).Verify("foo", sigB, addrA.Hex())
+		checkError(err, t)
+		expectBool(result.BlockNumber != nil && result.BlockNumber.Cmp(big.NewInt(100)) == 0, true, t)
+	})
+
+	t.Run("Backends without pending state should error", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, struct{ bind.ContractCaller }{backend.mockContract}, WithPendingState(true))
+
+		_, err := authenticator.IsAuthorizedSigner("foo", sigC, addrA.Hex())
+		expectBool(errors.Is(err, bind.ErrNoPendingState), true, t)
+		expectBool(errors.Is(err, ErrContractCallFailed), true, t)
+	})
+
+	// the wallet at A is only deployed once a pending transaction is mined
+	deploying := &pendingBackend{
+		mockContract: &mockContract{blockNumber: 100},
+		pending:      &mockContract{address: addrA, authorizedKey: &keyC.PublicKey, code: []byte{0x60}},
+	}
+	sigEOA := signEOAPersonalMessage("foo", keyB, t)
+
+	t.Run("Code checks should resolve against the pending state", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, deploying, WithPendingState(true))
+
+		crossCheck, err := authenticator.CrossCheck("foo", sigEOA, addrA.Hex())
+		checkError(err, t)
+		expectBool(crossCheck.HasCode, true, t)
+
+		_, err = authenticator.IsAuthorizedSignerTyped("foo", sigEOA, addrA.Hex(), WalletKindEOA)
+		expectBool(err == ErrWalletKindMismatch, true, t)
+
+		// deployed wallets verify the inner signature directly, without an ERC-6492 validator
+		wrapped, err := WrapERC6492Signature(common.HexToAddress("0x4e59b44847b379578588920ca78fbf26c0b4956c"), common.FromHex("0xdeadbeef"), common.FromHex(sigC))
+		checkError(err, t)
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", common.Bytes2Hex(wrapped), addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+}
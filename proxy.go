@@ -0,0 +1,113 @@
+package dappauth
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// EIP1967ImplementationSlot is the storage slot of the implementation address of EIP-1967 proxies,
+// bytes32(uint256(keccak256('eip1967.proxy.implementation')) - 1).
+var EIP1967ImplementationSlot = common.HexToHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc")
+
+// StorageReader is implemented by backends able to read contract storage (e.g. ethclient.Client).
+type StorageReader interface {
+	StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error)
+}
+
+// WithProxyResolution makes a smart-contract wallet which implements none of the interfaces (e.g. a proxy whose calls
+// revert) be retried with the code of its implementation, read from the EIP-1967 implementation slot of the wallet (for
+// transparent/UUPS proxies), in place of its own. The implementation's code runs against the proxy's storage via an
+// eth_call state override; the implementation is never called directly, as its own storage doesn't reflect the wallet.
+// Requires the backend to implement StorageReader and StateOverrideCaller. Costs an extra RPC (or three) on failures.
+func WithProxyResolution(resolve bool) Option {
+	return func(a *Authenticator) {
+		a.proxyResolution = resolve
+	}
+}
+
+// WithUpgradeRetry makes a smart-contract wallet which has code but reverted on every interface be probed again, up
+// to retries times, delay apart, to ride out the window of a proxy upgrade during which its calls revert transiently.
+// This is best-effort: a transient revert can't be told apart from a permanent one, so wallets which don't implement
+// any interface are also retried (delaying their failure), and longer upgrade windows still fail.
+func WithUpgradeRetry(retries int, delay time.Duration) Option {
+	return func(a *Authenticator) {
+		if retries >= 0 {
+			a.upgradeRetries = retries
+			a.upgradeRetryDelay = delay
+		}
+	}
+}
+
+// retryRevertedProbe probes the interfaces of a wallet which reverted on all of them again, up to the configured
+// upgrade retries, until one doesn't revert (implemented = false if all retries revert).
+func (a *Authenticator) retryRevertedProbe(probe func(common.Address, [32]byte, []byte, []byte) (bool, uint64, bool, error), addr common.Address, hash [32]byte, message, sig []byte) (isValid bool, gasUsed uint64, implemented bool, err error) {
+	for i := 0; i < a.upgradeRetries; i++ {
+		timer := time.NewTimer(a.upgradeRetryDelay)
+		select {
+		case <-a.context().Done():
+			timer.Stop()
+			return false, 0, true, a.context().Err()
+		case <-timer.C:
+		}
+
+		if isValid, gasUsed, implemented, err := probe(addr, hash, message, sig); implemented {
+			return isValid, gasUsed, true, err
+		}
+	}
+	return false, 0, false, nil
+}
+
+// isValidSignatureAtImplementation retries isValidSignature of the proxy with the code of its implementation in place
+// of its own, if the proxy implemented none of the interfaces. Returns callErr (the error of the call to the proxy) if
+// it failed otherwise, or if the implementation can't be resolved or the backend can't override the proxy's code.
+func (a *Authenticator) isValidSignatureAtImplementation(proxy common.Address, hash [32]byte, message, sig []byte, callErr error) (bool, uint64, error) {
+	if !errors.Is(callErr, ErrUnsupportedWalletInterface) && !errors.Is(callErr, errReverted) {
+		return false, 0, callErr
+	}
+	if _, ok := a.cc.(StateOverrideCaller); !ok {
+		return false, 0, callErr
+	}
+
+	implementation, ok := a.resolveImplementation(proxy)
+	if !ok {
+		return false, 0, callErr
+	}
+	code, err := a.codeAt(implementation)
+	if err != nil || len(code) == 0 {
+		return false, 0, callErr
+	}
+
+	override := make(StateOverride, len(a.stateOverride)+1)
+	for addr, account := range a.stateOverride {
+		override[addr] = account
+	}
+	account := override[proxy]
+	account.Code = code
+	override[proxy] = account
+
+	atImplementation := *a
+	atImplementation.stateOverride = override
+	atImplementation.callCoalescer = nil  // the calls differ from those to the proxy as is, under the same key
+	atImplementation.interfaceCache = nil // the interface is that of the implementation's code, not the proxy's
+
+	return atImplementation.isValidSignature(proxy, hash, message, sig)
+}
+
+func (a *Authenticator) resolveImplementation(proxy common.Address) (common.Address, bool) {
+	storageReader, ok := a.cc.(StorageReader)
+	if !ok {
+		return common.Address{}, false
+	}
+
+	slot, err := storageReader.StorageAt(a.context(), proxy, EIP1967ImplementationSlot, a.callBlock)
+	if err != nil {
+		return common.Address{}, false
+	}
+
+	implementation := common.BytesToAddress(slot)
+	return implementation, implementation != (common.Address{})
+}
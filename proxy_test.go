@@ -0,0 +1,199 @@
+package dappauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	_proxyCode          = []byte{0x60, 0x01} // reverts on every interface
+	_implementationCode = []byte{0x60, 0x02} // implements isValidSignature, checking the key stored as owner
+)
+
+// an EIP-1967 proxy and its implementation, each with their own storage (i.e. owner)
+type proxyBackend struct {
+	proxy, implementation           common.Address
+	proxyOwner, implementationOwner *ecdsa.PublicKey
+	proxyErr                        error // the error of calls to the proxy as is (nil = revert)
+}
+
+func (b *proxyBackend) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	switch contract {
+	case b.proxy:
+		return _proxyCode, nil
+	case b.implementation:
+		return _implementationCode, nil
+	}
+	return nil, nil
+}
+
+func (b *proxyBackend) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	if account == b.proxy && key == EIP1967ImplementationSlot {
+		return common.LeftPadBytes(b.implementation.Bytes(), 32), nil
+	}
+	return make([]byte, 32), nil
+}
+
+func (b *proxyBackend) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	switch *call.To {
+	case b.proxy:
+		if b.proxyErr != nil {
+			return nil, b.proxyErr
+		}
+		return nil, errors.New("execution reverted")
+	case b.implementation:
+		// the implementation's code against the implementation's storage
+		wallet := &mockContract{address: b.implementation, authorizedKey: b.implementationOwner, hashScheme: HashSchemeRaw}
+		return wallet.CallContract(ctx, call, blockNumber)
+	}
+	return nil, nil
+}
+
+func (b *proxyBackend) CallContractWithStateOverride(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int, override StateOverride) ([]byte, error) {
+	if account, ok := override[*call.To]; ok && *call.To == b.proxy && bytes.Equal(account.Code, _implementationCode) {
+		// the implementation's code against the proxy's storage
+		wallet := &mockContract{address: b.proxy, authorizedKey: b.proxyOwner, hashScheme: HashSchemeRaw}
+		return wallet.CallContract(ctx, call, blockNumber)
+	}
+	return b.CallContract(ctx, call, blockNumber)
+}
+
+func TestProxyResolution(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyC, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	proxy := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	implementation := common.HexToAddress("0x00000000000000000000000000000000000011Ee")
+
+	// the proxy is owned by B, while C took over the implementation's own storage (e.g. an uninitialized implementation)
+	newBackend := func(implementation common.Address) *proxyBackend {
+		return &proxyBackend{proxy: proxy, implementation: implementation, proxyOwner: &keyB.PublicKey, implementationOwner: &keyC.PublicKey}
+	}
+	challengeHash := ethCrypto.Keccak256([]byte("foo"))
+	sigB := signRawHash(challengeHash, keyB, t)
+	sigC := signRawHash(challengeHash, keyC, t)
+
+	t.Run("Proxy wallets should be authorized signers via the code of their implementation", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, newBackend(implementation), WithContractHashScheme(HashSchemeRaw), WithProxyResolution(true))
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sigB, proxy.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	t.Run("Proxy wallets should NOT be authorized signers per the storage of their implementation", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, newBackend(implementation), WithContractHashScheme(HashSchemeRaw), WithProxyResolution(true))
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sigC, proxy.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, false, t)
+	})
+
+	t.Run("Proxy wallets should error without proxy resolution", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, newBackend(implementation), WithContractHashScheme(HashSchemeRaw))
+
+		_, err := authenticator.IsAuthorizedSigner("foo", sigB, proxy.Hex())
+		expectBool(errors.Is(err, ErrUnsupportedWalletInterface), true, t)
+	})
+
+	t.Run("Proxy wallets without an implementation should return the original error", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, newBackend(common.Address{}), WithContractHashScheme(HashSchemeRaw), WithProxyResolution(true))
+
+		_, err := authenticator.IsAuthorizedSigner("foo", sigB, proxy.Hex())
+		expectBool(errors.Is(err, ErrUnsupportedWalletInterface), true, t)
+	})
+
+	t.Run("Proxy wallets should return the original error on backends without state overrides", func(t *testing.T) {
+		backend := newBackend(implementation)
+		authenticator := NewAuthenticator(nil, struct {
+			bind.ContractCaller
+			StorageReader
+		}{backend, backend}, WithContractHashScheme(HashSchemeRaw), WithProxyResolution(true))
+
+		_, err := authenticator.IsAuthorizedSigner("foo", sigB, proxy.Hex())
+		expectBool(errors.Is(err, ErrUnsupportedWalletInterface), true, t)
+	})
+
+	t.Run("Transport errors should NOT be retried via the implementation", func(t *testing.T) {
+		backend := newBackend(implementation)
+		backend.proxyErr = errDummy
+		authenticator := NewAuthenticator(nil, backend, WithContractHashScheme(HashSchemeRaw), WithProxyResolution(true))
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sigB, proxy.Hex())
+		expectBool(errors.Is(err, errDummy), true, t)
+		expectBool(isAuthorizedSigner, false, t)
+	})
+}
+
+func TestUpgradeRetry(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	sig := signERC1654PersonalMessage("foo", keyB, addrA, t)
+
+	t.Run("Wallets reverting transiently should be authorized signers once the retry succeeds", func(t *testing.T) {
+		// both interfaces revert on the first probe
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, code: []byte{0x60}, revertingCalls: 2}
+		authenticator := NewAuthenticator(nil, mock, WithUpgradeRetry(2, time.Millisecond))
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	t.Run("Wallets reverting transiently should error without retries", func(t *testing.T) {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, code: []byte{0x60}, revertingCalls: 2}
+		authenticator := NewAuthenticator(nil, mock)
+
+		_, err := authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+		expectBool(errors.Is(err, ErrUnsupportedWalletInterface), true, t)
+	})
+
+	t.Run("Wallets reverting past the retries should error", func(t *testing.T) {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, code: []byte{0x60}, revertingCalls: 6}
+		authenticator := NewAuthenticator(nil, mock, WithUpgradeRetry(2, time.Millisecond))
+
+		_, err := authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+		expectBool(errors.Is(err, ErrUnsupportedWalletInterface), true, t)
+		expectBool(mock.calls == 6, true, t)
+	})
+
+	t.Run("Addresses without code should NOT be retried", func(t *testing.T) {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, revertingCalls: 2}
+		authenticator := NewAuthenticator(nil, mock, WithUpgradeRetry(2, time.Millisecond))
+
+		_, err := authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+		expectBool(errors.Is(err, ErrNoContractCode), true, t)
+		expectBool(mock.calls == 2, true, t)
+	})
+
+	t.Run("Retries should stop once the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, code: []byte{0x60}, revertingCalls: 2}
+		authenticator := NewAuthenticator(ctx, mock, WithUpgradeRetry(1, time.Hour))
+
+		_, err := authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+		expectBool(errors.Is(err, context.DeadlineExceeded), true, t)
+	})
+}
@@ -0,0 +1,141 @@
+package dappauth
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Path is the verification path which determined the result of a verification.
+type Path int
+
+const (
+	// PathNone means no verification path was taken.
+	PathNone Path = iota
+	// PathEOA is the external wallet path (the signature recovers to the address).
+	PathEOA
+	// PathContract is the smart-contract wallet path (isValidSignature of the wallet).
+	PathContract
+	// PathERC6492 is the counterfactual smart-contract wallet path (ERC-6492 validator).
+	PathERC6492
+	// PathSessionKey is the session key path (an EOA authorized by the session key view of a smart account).
+	PathSessionKey
+)
+
+func (p Path) String() string {
+	switch p {
+	case PathEOA:
+		return "EOA"
+	case PathContract:
+		return "contract"
+	case PathERC6492:
+		return "ERC-6492"
+	case PathSessionKey:
+		return "session key"
+	default:
+		return "none"
+	}
+}
+
+// Reason is the reason a verification didn't authorize, when known.
+type Reason int
+
+const (
+	// ReasonNone means no specific reason is known, e.g. the signature is invalid.
+	ReasonNone Reason = iota
+	// ReasonWrongChallenge means the signature is valid, but over another recently issued challenge than the one
+	// verified, i.e. the user signed the wrong prompt.
+	ReasonWrongChallenge
+)
+
+func (r Reason) String() string {
+	switch r {
+	case ReasonWrongChallenge:
+		return "wrong challenge"
+	default:
+		return "none"
+	}
+}
+
+// Result is the detailed result of a verification.
+type Result struct {
+	Authorized       bool             // the address is an authorized signer
+	Path             Path             // the verification path which determined the result
+	RecoveredAddress common.Address   // the EOA recovered from the signature (EOA path), also set when unauthorized for debugging mismatches (zero if unrecoverable)
+	InnerSigners     []common.Address // best-effort recovery of the EOAs which signed on behalf of a smart-contract wallet (contract paths), which may or may not be owners of the wallet
+	GasUsed          uint64           // the gas used by isValidSignature of the smart-contract wallet, if the backend reports it (see GasReporter)
+	Digest           common.Hash      // the digest signed, i.e. recovered from (EOA paths) or signed by the signers of the smart-contract wallet per the contract hash scheme (contract paths)
+	Reason           Reason           // the reason the address isn't authorized, when known (see VerifyRecent)
+	BlockNumber      *big.Int         // the block the verification reflects the state of (contract paths), i.e. the latest block when the wallet was called or the block the owners were resolved at (nil if unknown)
+	IsContract       *bool            // whether the address has code, i.e. is a (deployed) smart-contract wallet, as learned by the path or per CodeAt (nil if unknown, e.g. without backend or if cached by IsAuthorizedSigner)
+}
+
+// Verify is like IsAuthorizedSigner but returns the detailed result of the verification.
+func (a *Authenticator) Verify(challenge, signature, addrHex string) (*Result, error) {
+
+	sigBytes, err := a.signatureBytes(signature)
+	if err != nil {
+		return nil, err
+	}
+
+	addr, err := a.normalizeAddress(addrHex)
+	if err != nil {
+		return nil, err
+	}
+
+	detailed := *a
+	detailed.resolveIsContract = true
+
+	return detailed.verify(challenge, sigBytes, addr)
+}
+
+// isContract reports whether the address has code, nil if unknown (no backend, or the call failed).
+func (a *Authenticator) isContract(addr common.Address) *bool {
+	if a.cc == nil {
+		return nil
+	}
+
+	code, err := a.codeAt(addr)
+	if err != nil {
+		return nil
+	}
+	isContract := len(code) > 0
+	return &isContract
+}
+
+func authorized(result *Result, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	return result.Authorized, nil
+}
+
+// VerifyRecent is like Verify but, if the address isn't authorized for the challenge, checks whether the signature is
+// that of the address (as an external wallet) over one of the recently issued challenges instead, in which case the
+// result is unauthorized with ReasonWrongChallenge, its Digest being that of the challenge which was signed. Errors of
+// the verification are returned as is, without checking the recent challenges.
+func (a *Authenticator) VerifyRecent(challenge, signature, addrHex string, recent []string) (*Result, error) {
+
+	// only a plain mismatch falls back, errors (e.g. an expired challenge or a failed contract call) are returned as is
+	result, err := a.Verify(challenge, signature, addrHex)
+	if (err != nil && !errors.Is(err, ErrNoContractCode)) || (err == nil && result.Authorized) {
+		return result, err
+	}
+
+	sigBytes, sigErr := a.signatureBytes(signature)
+	addr, addrErr := a.normalizeAddress(addrHex)
+	if sigErr != nil || addrErr != nil {
+		return result, err
+	}
+
+	for _, recentChallenge := range recent {
+		if recentChallenge == challenge {
+			continue
+		}
+		if signed, _ := a.verifyEOA(recentChallenge, sigBytes, addr); signed != nil {
+			return &Result{Path: PathNone, RecoveredAddress: signed.RecoveredAddress, Digest: signed.Digest, Reason: ReasonWrongChallenge}, nil
+		}
+	}
+	return result, err
+}
@@ -0,0 +1,439 @@
+package dappauth
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestVerify(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyC, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrB := ethCrypto.PubkeyToAddress(keyB.PublicKey)
+	addrC := ethCrypto.PubkeyToAddress(keyC.PublicKey)
+
+	t.Run("External wallets should report the EOA path and the recovered address", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{})
+
+		result, err := authenticator.Verify("foo", signEOAPersonalMessage("foo", keyA, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(result.Authorized, true, t)
+		expectBool(result.Path == PathEOA, true, t)
+		expectBool(result.RecoveredAddress == addrA, true, t)
+	})
+
+	t.Run("Smart-contract wallets should report the inner recovered address alongside a successful verification", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{address: addrA, authorizedKey: &keyB.PublicKey})
+
+		result, err := authenticator.Verify("foo", signERC1654PersonalMessage("foo", keyB, addrA, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(result.Authorized, true, t)
+		expectBool(result.Path == PathContract, true, t)
+		expectAddresses(result.InnerSigners, []common.Address{addrB}, t)
+	})
+
+	t.Run("Smart-contract wallets should report all inner recovered addresses of a multi-sig", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{address: addrA, authorizedKey: &keyB.PublicKey})
+		sig := signERC1654PersonalMessage("foo", keyB, addrA, t) + signERC1654PersonalMessage("foo", keyC, addrA, t)
+
+		result, err := authenticator.Verify("foo", sig, addrA.Hex())
+		checkError(err, t)
+		expectBool(result.Authorized, true, t)
+		expectAddresses(result.InnerSigners, []common.Address{addrB, addrC}, t)
+	})
+
+	t.Run("Smart-contract wallets should report the inner recovered address of an unauthorized key", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{address: addrA, authorizedKey: &keyB.PublicKey})
+
+		result, err := authenticator.Verify("foo", signERC1654PersonalMessage("foo", keyC, addrA, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(result.Authorized, false, t)
+		expectAddresses(result.InnerSigners, []common.Address{addrC}, t)
+	})
+}
+
+func expectAddresses(actual, expected []common.Address, t *testing.T) {
+	if len(actual) != len(expected) {
+		t.Errorf("expected %v to be %v", actual, expected)
+		return
+	}
+	for i := range actual {
+		if actual[i] != expected[i] {
+			t.Errorf("expected %v to be %v", actual, expected)
+			return
+		}
+	}
+}
+
+// reports a fixed gas figure for each call
+type gasBackend struct {
+	*mockContract
+	gasUsed uint64
+}
+
+func (b *gasBackend) CallContractGasUsed(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, uint64, error) {
+	output, err := b.CallContract(ctx, call, blockNumber)
+	return output, b.gasUsed, err
+}
+
+func TestGasUsed(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyC, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrC := ethCrypto.PubkeyToAddress(keyC.PublicKey)
+	mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey}
+	sigB := signERC1654PersonalMessage("foo", keyB, addrA, t)
+
+	t.Run("The gas reported by the backend should be in the result", func(t *testing.T) {
+		result, err := NewAuthenticator(nil, &gasBackend{mockContract: mock, gasUsed: 12345}).Verify("foo", sigB, addrA.Hex())
+		checkError(err, t)
+		expectBool(result.Authorized, true, t)
+		expectBool(result.GasUsed == 12345, true, t)
+	})
+
+	t.Run("The gas should be zero if the backend doesn't report it", func(t *testing.T) {
+		result, err := NewAuthenticator(nil, mock).Verify("foo", sigB, addrA.Hex())
+		checkError(err, t)
+		expectBool(result.GasUsed == 0, true, t)
+	})
+
+	t.Run("The gas should be zero for external wallets", func(t *testing.T) {
+		result, err := NewAuthenticator(nil, &gasBackend{mockContract: mock, gasUsed: 12345}).Verify("foo", signEOAPersonalMessage("foo", keyC, t), addrC.Hex())
+		checkError(err, t)
+		expectBool(result.GasUsed == 0, true, t)
+	})
+}
+
+func TestResultDigest(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyC, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrC := ethCrypto.PubkeyToAddress(keyC.PublicKey)
+	authenticator := NewAuthenticator(nil, &mockContract{address: addrA, authorizedKey: &keyB.PublicKey})
+
+	// computed independently of the authenticator
+	personalDigest := ethCrypto.Keccak256Hash([]byte("\x19Ethereum Signed Message:\n3foo"))
+	erc191Digest := ethCrypto.Keccak256Hash([]byte{0x19, 0x00}, addrA.Bytes(), ethCrypto.Keccak256([]byte("foo")))
+
+	t.Run("The digest of external wallets should be the personal message hash", func(t *testing.T) {
+		sig := signEOAPersonalMessage("foo", keyC, t)
+		result, err := authenticator.Verify("foo", sig, addrC.Hex())
+		checkError(err, t)
+		expectBool(result.Digest == personalDigest, true, t)
+
+		// re-verifiable offline from the digest
+		recovered, err := ethCrypto.SigToPub(result.Digest[:], append(common.FromHex(sig)[:64], common.FromHex(sig)[64]-27))
+		checkError(err, t)
+		expectBool(ethCrypto.PubkeyToAddress(*recovered) == addrC, true, t)
+	})
+
+	t.Run("The digest of smart-contract wallets should be the erc191 hash signed by their signers", func(t *testing.T) {
+		result, err := authenticator.Verify("foo", signERC1654PersonalMessage("foo", keyB, addrA, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(result.Authorized, true, t)
+		expectBool(result.Digest == erc191Digest, true, t)
+	})
+}
+
+func TestVerifyRecent(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	authenticator := NewAuthenticator(nil, &mockContract{})
+	recent := []string{"foo", "bar", "baz"}
+
+	recentTests := []struct {
+		title          string
+		signature      string
+		expected       bool
+		expectedReason Reason
+	}{
+		{"Signatures over the challenge should be authorized", signEOAPersonalMessage("foo", keyA, t), true, ReasonNone},
+		{"Signatures over a stale challenge should report ReasonWrongChallenge", signEOAPersonalMessage("bar", keyA, t), false, ReasonWrongChallenge},
+		{"Signatures over an unknown challenge should NOT report ReasonWrongChallenge", signEOAPersonalMessage("qux", keyA, t), false, ReasonNone},
+		{"Signatures of another key over a stale challenge should NOT report ReasonWrongChallenge", signEOAPersonalMessage("bar", keyB, t), false, ReasonNone},
+	}
+
+	for _, test := range recentTests {
+		t.Run(test.title, func(t *testing.T) {
+			result, err := authenticator.VerifyRecent("foo", test.signature, addrA.Hex(), recent)
+			if test.expected || test.expectedReason != ReasonNone {
+				checkError(err, t)
+			}
+			if result != nil {
+				expectBool(result.Authorized, test.expected, t)
+				expectBool(result.Reason == test.expectedReason, true, t)
+			} else {
+				expectBool(test.expected || test.expectedReason != ReasonNone, false, t)
+			}
+		})
+	}
+
+	t.Run("The digest of results with ReasonWrongChallenge should be that of the challenge signed", func(t *testing.T) {
+		result, err := authenticator.VerifyRecent("foo", signEOAPersonalMessage("baz", keyA, t), addrA.Hex(), recent)
+		checkError(err, t)
+		expectBool(result.RecoveredAddress == addrA, true, t)
+
+		personalHash, err := authenticator.personalChallengeHash("baz")
+		checkError(err, t)
+		expectBool(result.Digest == common.BytesToHash(personalHash), true, t)
+	})
+
+	t.Run("Errors of the verification should be returned rather than ReasonWrongChallenge", func(t *testing.T) {
+		failing := NewAuthenticator(nil, &mockContract{errorIsValidSignature: true})
+
+		result, err := failing.VerifyRecent("foo", signEOAPersonalMessage("bar", keyA, t), addrA.Hex(), recent)
+		expectBool(errors.Is(err, ErrContractCallFailed), true, t)
+		expectBool(result == nil, true, t)
+	})
+
+	t.Run("Expired challenges should error rather than report ReasonWrongChallenge", func(t *testing.T) {
+		expiring := NewAuthenticator(nil, &mockContract{}, WithChallengeExpiryExtractor(func([]byte) (time.Time, error) { return time.Unix(1, 0), nil }))
+
+		_, err := expiring.VerifyRecent("foo", signEOAPersonalMessage("bar", keyA, t), addrA.Hex(), recent)
+		expectBool(err == ErrChallengeExpired, true, t)
+	})
+}
+
+func TestResultBlockNumber(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrB := ethCrypto.PubkeyToAddress(keyB.PublicKey)
+	sig := signERC1654PersonalMessage("foo", keyB, addrA, t)
+
+	// the latest block is 100
+	mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, blockNumber: 100}
+
+	t.Run("Contract results should report the latest block at call time", func(t *testing.T) {
+		result, err := NewAuthenticator(nil, mock).Verify("foo", sig, addrA.Hex())
+		checkError(err, t)
+		expectBool(result.Authorized, true, t)
+		expectBool(result.BlockNumber != nil && result.BlockNumber.Uint64() == 100, true, t)
+	})
+
+	t.Run("Owner results should report the latest block the owners were resolved at", func(t *testing.T) {
+		resolver := &mockOwnerResolver{changes: []uint64{0}, owners: [][]common.Address{{addrB}}}
+
+		result, err := NewAuthenticator(nil, mock, WithOwnerResolver(resolver)).Verify("foo", sig, addrA.Hex())
+		checkError(err, t)
+		expectBool(result.Authorized, true, t)
+		expectBool(result.BlockNumber != nil && result.BlockNumber.Uint64() == 100, true, t)
+	})
+
+	t.Run("EOA results should NOT report a block", func(t *testing.T) {
+		result, err := NewAuthenticator(nil, mock).Verify("foo", signEOAPersonalMessage("foo", keyB, t), addrB.Hex())
+		checkError(err, t)
+		expectBool(result.Authorized, true, t)
+		expectBool(result.BlockNumber == nil, true, t)
+	})
+
+	t.Run("Contract results should NOT report a block if the backend can't read it", func(t *testing.T) {
+		callerOnly := struct{ bind.ContractCaller }{mock}
+		result, err := NewAuthenticator(nil, callerOnly).Verify("foo", sig, addrA.Hex())
+		checkError(err, t)
+		expectBool(result.BlockNumber == nil, true, t)
+	})
+
+	t.Run("Contract calls should be pinned to the block reported, even if a block lands meanwhile", func(t *testing.T) {
+		backend := &blockLandingBackend{mockContract: &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, blockNumber: 100}}
+
+		result, err := NewAuthenticator(nil, backend).Verify("foo", sig, addrA.Hex())
+		checkError(err, t)
+		expectBool(result.Authorized, true, t)
+		expectBool(result.BlockNumber != nil && result.BlockNumber.Uint64() == 100, true, t)
+		expectBool(len(backend.callBlocks) > 0, true, t)
+		for _, callBlock := range backend.callBlocks {
+			expectBool(callBlock != nil && callBlock.Uint64() == 100, true, t)
+		}
+	})
+}
+
+// blockLandingBackend lands a block after each read of the latest block, recording the blocks of contract calls.
+type blockLandingBackend struct {
+	*mockContract
+	callBlocks []*big.Int
+}
+
+func (b *blockLandingBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	header, err := b.mockContract.HeaderByNumber(ctx, number)
+	b.mockContract.blockNumber++
+	return header, err
+}
+
+func (b *blockLandingBackend) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	b.callBlocks = append(b.callBlocks, blockNumber)
+	return b.mockContract.CallContract(ctx, call, blockNumber)
+}
+
+func TestRecoveredAddressOnMismatch(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrB := ethCrypto.PubkeyToAddress(keyB.PublicKey)
+	authenticator := NewAuthenticator(nil, &mockContract{})
+
+	t.Run("Mismatching results should report the recovered address", func(t *testing.T) {
+		result, err := authenticator.Verify("foo", signEOAPersonalMessage("foo", keyA, t), addrB.Hex())
+		checkError(err, t)
+		expectBool(result.Authorized, false, t)
+		expectBool(result.RecoveredAddress == addrA, true, t)
+	})
+
+	t.Run("Matching results should report the recovered address", func(t *testing.T) {
+		result, err := authenticator.Verify("foo", signEOAPersonalMessage("foo", keyA, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(result.Authorized, true, t)
+		expectBool(result.RecoveredAddress == addrA, true, t)
+	})
+}
+
+func TestResultIsContract(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrB := ethCrypto.PubkeyToAddress(keyB.PublicKey)
+
+	// addrA is a smart-contract wallet, authorizing keyB
+	mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, code: []byte{0x60}}
+
+	isContractTests := []struct {
+		title         string
+		authenticator *Authenticator
+		signature     string
+		addr          common.Address
+		expected      *bool
+	}{
+		{"EOA logins should NOT be contracts", NewAuthenticator(nil, mock), signEOAPersonalMessage("foo", keyB, t), addrB, boolPtr(false)},
+		{"Contract logins should be contracts", NewAuthenticator(nil, mock), signERC1654PersonalMessage("foo", keyB, addrA, t), addrA, boolPtr(true)},
+		{"EOA logins without backend should be unknown", NewAuthenticator(nil, nil), signEOAPersonalMessage("foo", keyB, t), addrB, nil},
+		{"EOA logins should be unknown if CodeAt fails", NewAuthenticator(nil, &mockContract{errorCodeAt: true}), signEOAPersonalMessage("foo", keyB, t), addrB, nil},
+	}
+
+	for _, test := range isContractTests {
+		t.Run(test.title, func(t *testing.T) {
+			result, err := test.authenticator.Verify("foo", test.signature, test.addr.Hex())
+			checkError(err, t)
+			expectBool(result.Authorized, true, t)
+			expectBool(result.IsContract == nil, test.expected == nil, t)
+			if test.expected != nil && result.IsContract != nil {
+				expectBool(*result.IsContract, *test.expected, t)
+			}
+		})
+	}
+}
+
+// codeAtCountingBackend counts the CodeAt calls to the mock, and whether they were bounded by a deadline.
+type codeAtCountingBackend struct {
+	*mockContract
+	codeAts   int
+	unbounded bool
+}
+
+func (b *codeAtCountingBackend) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	b.codeAts++
+	if _, ok := ctx.Deadline(); !ok {
+		b.unbounded = true
+	}
+	return b.mockContract.CodeAt(ctx, contract, blockNumber)
+}
+
+func TestResultIsContractCalls(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrB := ethCrypto.PubkeyToAddress(keyB.PublicKey)
+
+	t.Run("Contract logins should NOT call CodeAt", func(t *testing.T) {
+		backend := &codeAtCountingBackend{mockContract: &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, code: []byte{0x60}}}
+		authenticator := NewAuthenticator(nil, backend)
+
+		result, err := authenticator.Verify("foo", signERC1654PersonalMessage("foo", keyB, addrA, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(result.IsContract != nil && *result.IsContract, true, t)
+		expectBool(backend.codeAts == 0, true, t)
+	})
+
+	t.Run("Cached results should NOT call CodeAt again", func(t *testing.T) {
+		backend := &codeAtCountingBackend{mockContract: &mockContract{}}
+		authenticator := NewAuthenticator(nil, backend, WithResultCache(0))
+
+		for i := 0; i < 2; i++ {
+			result, err := authenticator.Verify("foo", signEOAPersonalMessage("foo", keyB, t), addrB.Hex())
+			checkError(err, t)
+			expectBool(result.IsContract != nil && !*result.IsContract, true, t)
+		}
+		expectBool(backend.codeAts == 1, true, t)
+	})
+
+	t.Run("IsAuthorizedSigner should NOT call CodeAt for EOA logins", func(t *testing.T) {
+		backend := &codeAtCountingBackend{mockContract: &mockContract{}}
+		authenticator := NewAuthenticator(nil, backend)
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", signEOAPersonalMessage("foo", keyB, t), addrB.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+		expectBool(backend.codeAts == 0, true, t)
+	})
+
+	t.Run("CodeAt should be bounded by the overall deadline", func(t *testing.T) {
+		backend := &codeAtCountingBackend{mockContract: &mockContract{}}
+		authenticator := NewAuthenticator(nil, backend, WithOverallDeadline(time.Minute))
+
+		_, err := authenticator.Verify("foo", signEOAPersonalMessage("foo", keyB, t), addrB.Hex())
+		checkError(err, t)
+		expectBool(backend.codeAts == 1, true, t)
+		expectBool(backend.unbounded, false, t)
+	})
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
@@ -0,0 +1,72 @@
+package dappauth
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DefaultSessionKeySelector is the selector of isSessionKeyValid(address) returns (bool).
+var DefaultSessionKeySelector = [4]byte{0xee, 0xd6, 0x3d, 0xfb}
+
+// ErrInvalidSessionKeyResponse is returned when the session key view of the smart account doesn't return a bool.
+var ErrInvalidSessionKeyResponse = errors.New("dappauth: session key view did not return a bool")
+
+// WithSessionKeySelector sets the selector of the view of smart accounts which tells whether a session key is authorized
+// to sign on behalf of the account (default = DefaultSessionKeySelector). The view must take the address of the
+// session key and return a bool, e.g. isSessionKeyValid(address) returns (bool).
+func WithSessionKeySelector(selector [4]byte) Option {
+	return func(a *Authenticator) {
+		a.sessionKeySelector = selector
+	}
+}
+
+// IsAuthorizedSessionKey checks if the challenge was signed via personal_sign by a session key (an EOA) which the smart
+// account authorizes to sign on its behalf, per the session key view of the account (see WithSessionKeySelector).
+func (a *Authenticator) IsAuthorizedSessionKey(challenge, signature, accountHex string) (bool, error) {
+	return authorized(a.VerifySessionKey(challenge, signature, accountHex))
+}
+
+// VerifySessionKey is like IsAuthorizedSessionKey but returns the detailed result of the verification, whose
+// RecoveredAddress is the session key.
+func (a *Authenticator) VerifySessionKey(challenge, signature, accountHex string) (*Result, error) {
+
+	account, err := a.normalizeAddress(accountHex)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.checkAddressBinding(challenge, account); err != nil {
+		return nil, err
+	}
+
+	sessionKey, err := a.DeriveAddress(challenge, signature)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := a.personalChallengeHash(challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.cc == nil {
+		return nil, ErrNoBackend
+	}
+
+	input := make([]byte, 0, 4+32)
+	input = append(input, a.sessionKeySelector[:]...)
+	input = append(input, encodeAddress(sessionKey)...)
+
+	output, gasUsed, err := a.callContract(account, input)
+	if err != nil {
+		return nil, wrapError("session key view of", account, contractCallFailed(err))
+	}
+	if len(output) != 32 || !isZero(output[:31]) || output[31] > 1 {
+		return nil, wrapError("session key view of", account, ErrInvalidSessionKeyResponse)
+	}
+
+	result := &Result{Path: PathSessionKey, RecoveredAddress: sessionKey, GasUsed: gasUsed, Digest: common.BytesToHash(digest)}
+	if output[31] == 0 {
+		return result, nil
+	}
+	return a.authorize(result, account, sessionKey)
+}
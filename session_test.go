@@ -0,0 +1,59 @@
+package dappauth
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestSessionKey(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyS, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyT, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrS := ethCrypto.PubkeyToAddress(keyS.PublicKey)
+
+	// the smart account at A authorizes the session key S
+	mock := &mockContract{address: addrA, sessionKeySelector: DefaultSessionKeySelector, sessionKeys: []common.Address{addrS}}
+	authenticator := NewAuthenticator(nil, mock)
+
+	t.Run("Authorized session keys should sign on behalf of the account", func(t *testing.T) {
+		result, err := authenticator.VerifySessionKey("foo", signEOAPersonalMessage("foo", keyS, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(result.Authorized, true, t)
+		expectBool(result.Path == PathSessionKey, true, t)
+		expectBool(result.RecoveredAddress == addrS, true, t)
+	})
+
+	t.Run("Unauthorized session keys should NOT sign on behalf of the account", func(t *testing.T) {
+		isAuthorized, err := authenticator.IsAuthorizedSessionKey("foo", signEOAPersonalMessage("foo", keyT, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorized, false, t)
+	})
+
+	t.Run("Session keys should NOT sign on behalf of the account over another challenge", func(t *testing.T) {
+		isAuthorized, err := authenticator.IsAuthorizedSessionKey("foo", signEOAPersonalMessage("bar", keyS, t), addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorized, false, t)
+	})
+
+	t.Run("The session key view should be configurable", func(t *testing.T) {
+		selector := [4]byte{0x12, 0x34, 0x56, 0x78}
+		mock := &mockContract{address: addrA, sessionKeySelector: selector, sessionKeys: []common.Address{addrS}}
+		sig := signEOAPersonalMessage("foo", keyS, t)
+
+		isAuthorized, err := NewAuthenticator(nil, mock, WithSessionKeySelector(selector)).IsAuthorizedSessionKey("foo", sig, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorized, true, t)
+
+		_, err = NewAuthenticator(nil, mock).IsAuthorizedSessionKey("foo", sig, addrA.Hex())
+		expectBool(errors.Is(err, ErrContractCallFailed), true, t)
+	})
+}
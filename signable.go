@@ -0,0 +1,31 @@
+package dappauth
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// SignableMessage returns the exact preimage an external wallet signs for the challenge via personal_sign,
+// i.e. the personal message prefix (or the message prefix, see WithMessagePrefix) followed by the challenge message
+// (per the challenge options).
+// Returns nil if the challenge is invalid per the challenge options.
+func (a *Authenticator) SignableMessage(challenge string) []byte {
+	msg, err := a.challengeMessage(challenge)
+	if err != nil {
+		return nil
+	}
+	return a.signedMessage(msg)
+}
+
+// SignableContractDigest returns the exact digest the signers of the smart-contract wallet at addr sign for the challenge,
+// per the contract hash scheme. Returns the zero digest if the challenge is invalid per the challenge options.
+func (a *Authenticator) SignableContractDigest(challenge string, addr common.Address) [32]byte {
+	var digest [32]byte
+
+	hash, err := a.contractHash(challenge)
+	if err != nil {
+		return digest
+	}
+
+	copy(digest[:], a.signedContractHash(hash[:], addr))
+	return digest
+}
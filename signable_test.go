@@ -0,0 +1,77 @@
+package dappauth
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestSignableMessage(t *testing.T) {
+
+	t.Run("The signable message should be the personal message preimage of the challenge", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{})
+		expectBool(string(authenticator.SignableMessage("foo")) == "\x19Ethereum Signed Message:\n3foo", true, t)
+	})
+
+	t.Run("The signable message should be the personal message preimage of the decoded challenge", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{}, WithChallengeEncoding(ChallengeEncodingBase64))
+		expectBool(string(authenticator.SignableMessage("Zm9v")) == "\x19Ethereum Signed Message:\n3foo", true, t)
+	})
+
+	t.Run("The signable message of an invalid challenge should be nil", func(t *testing.T) {
+		authenticator := NewAuthenticator(nil, &mockContract{}, WithChallengePreHashed(true))
+		expectBool(authenticator.SignableMessage("foo") == nil, true, t)
+	})
+
+	t.Run("External wallets signing the signable message should be authorized signers", func(t *testing.T) {
+		key, err := ethCrypto.GenerateKey()
+		checkError(err, t)
+
+		authenticator := NewAuthenticator(nil, &mockContract{})
+		sig := signRawHash(ethCrypto.Keccak256(authenticator.SignableMessage("foo")), key, t)
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sig, ethCrypto.PubkeyToAddress(key.PublicKey).Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+}
+
+func TestSignableContractDigest(t *testing.T) {
+
+	addr := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+
+	digestTests := []struct {
+		title    string
+		scheme   ContractHashScheme
+		expected string
+	}{
+		{"The signable contract digest should be pinned under the ERC191 hash scheme", HashSchemeERC191, "0xedf73f760d0ed6e558effa07325b59c6bb097f418f462a544881f3165cc3874c"},
+		{"The signable contract digest should be pinned under the raw hash scheme", HashSchemeRaw, "0x41b1a0649752af1b28b3dc29a1556eee781e4a4c3a1f7f53f90fa834de098c4d"},
+		{"The signable contract digest should be pinned under the personal-prefixed hash scheme", HashSchemePersonalPrefixed, "0x76b2e96714d3b5e6eb1d1c509265430b907b44f72b2a22b06fcd4d96372b8565"},
+	}
+
+	for _, test := range digestTests {
+		t.Run(test.title, func(t *testing.T) {
+			authenticator := NewAuthenticator(nil, &mockContract{}, WithContractHashScheme(test.scheme))
+			digest := authenticator.SignableContractDigest("foo", addr)
+			expectBool(common.ToHex(digest[:]) == test.expected, true, t)
+		})
+	}
+
+	t.Run("Smart-contract wallets whose signers sign the signable contract digest should be authorized signers", func(t *testing.T) {
+		keyA, err := ethCrypto.GenerateKey()
+		checkError(err, t)
+		keyB, err := ethCrypto.GenerateKey()
+		checkError(err, t)
+
+		addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+		authenticator := NewAuthenticator(nil, &mockContract{address: addrA, authorizedKey: &keyB.PublicKey})
+		digest := authenticator.SignableContractDigest("foo", addrA)
+		sig := signRawHash(digest[:], keyB, t)
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", sig, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+}
@@ -0,0 +1,192 @@
+package dappauth
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	// ErrInvalidSignatureHex is returned when the signature is not valid hex.
+	ErrInvalidSignatureHex = errors.New("dappauth: signature is not valid hex")
+
+	// ErrInvalidSignatureLength is returned when the signature is neither 65 bytes nor 64 bytes (EIP-2098 compact).
+	ErrInvalidSignatureLength = errors.New("dappauth: invalid signature length")
+
+	// ErrInvalidRecoveryID is returned when the signature's V is not 27/28.
+	ErrInvalidRecoveryID = errors.New("dappauth: invalid signature recovery id")
+
+	// ErrInvalidSignature is returned when the signature's R or S is not in [1, n-1] (n being the curve order),
+	// i.e. the signature can't be recovered from.
+	ErrInvalidSignature = errors.New("dappauth: signature r or s value out of range")
+
+	// ErrHighS is returned when the signature's S is in the upper half of the curve order (malleable signature).
+	ErrHighS = errors.New("dappauth: signature s value is not in the lower half of the curve order")
+)
+
+var (
+	secp256k1N      = ethCrypto.S256().Params().N
+	secp256k1HalfN  = new(big.Int).Rsh(secp256k1N, 1)
+	secp256k1NBytes = common.LeftPadBytes(secp256k1N.Bytes(), 32)
+)
+
+// ValidateSignatureFormat checks that the signature is well-formed, without any key recovery or network calls.
+// A well-formed signature is hex encoded (optionally 0x prefixed), 65 bytes long with V being 27/28 or 64 bytes
+// long (EIP-2098 compact), and has a low S value.
+func ValidateSignatureFormat(signature string) error {
+	signature = strings.TrimPrefix(strings.TrimPrefix(signature, "0x"), "0X")
+
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidSignatureHex
+	}
+
+	var s []byte
+	switch len(sig) {
+	case 65:
+		if v := sig[64]; v != 27 && v != 28 {
+			return ErrInvalidRecoveryID
+		}
+		s = sig[32:64]
+	case 64:
+		// EIP-2098: the top bit of s holds the y parity
+		s = make([]byte, 32)
+		copy(s, sig[32:64])
+		s[0] &= 0x7f
+	default:
+		return ErrInvalidSignatureLength
+	}
+
+	if err := validateSignatureValues(sig[:32], s); err != nil {
+		return err
+	}
+	if new(big.Int).SetBytes(s).Cmp(secp256k1HalfN) > 0 {
+		return ErrHighS
+	}
+
+	return nil
+}
+
+// WithLowSEnforcement rejects signatures with a high S value (malleable signatures) on the given paths, failing their
+// verification with ErrHighS, e.g. to be strict with smart-contract wallets while accepting legacy high-S signatures of
+// external wallets. On PathEOA, a high-S signature recovering to the address fails rather than authorizes. On
+// PathContract, a signature of which any 65 bytes signer signature has a high S value fails before the wallet is called.
+// Other paths are unaffected.
+func WithLowSEnforcement(paths ...Path) Option {
+	return func(a *Authenticator) {
+		a.lowSPaths = paths
+	}
+}
+
+// enforcesLowS reports whether high-S signatures are rejected on the path.
+func (a *Authenticator) enforcesLowS(path Path) bool {
+	for _, p := range a.lowSPaths {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// hasHighS reports whether any 65 bytes signature of the (concatenated) signature has a high S value.
+func hasHighS(sig []byte) bool {
+	if len(sig)%65 != 0 {
+		return false
+	}
+	for _, chunk := range chunk65Bytes(sig) {
+		if new(big.Int).SetBytes(chunk[32:64]).Cmp(secp256k1HalfN) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// validateSignatureValues checks that the 32 bytes R and S are in [1, n-1], as required to recover from the signature.
+// Compares the big-endian bytes directly, as it is on the hot path.
+func validateSignatureValues(r, s []byte) error {
+	if isZero(r) || isZero(s) || bytes.Compare(r, secp256k1NBytes) >= 0 || bytes.Compare(s, secp256k1NBytes) >= 0 {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Canonicalize normalizes the signature into its canonical form: 65 bytes, low S and V being 27/28, 0x prefixed hex encoded.
+// It accepts V being 0/1 or 27/28, high S values (flipping V accordingly) and EIP-2098 compact signatures, so that
+// equivalent signatures canonicalize to the same output.
+func Canonicalize(signature string) (string, error) {
+	sig, err := canonicalSignature(signature)
+	if err != nil {
+		return "", err
+	}
+	return "0x" + hex.EncodeToString(sig), nil
+}
+
+// SignatureFingerprint returns a short stable hash of the signature, the 0x prefixed hex of keccak256 over its
+// canonical form (see Canonicalize), so that equivalent signatures share a fingerprint, e.g. for detecting replayed
+// signatures without storing them.
+func SignatureFingerprint(signature string) (string, error) {
+	sig, err := canonicalSignature(signature)
+	if err != nil {
+		return "", err
+	}
+	return ethCrypto.Keccak256Hash(sig).Hex(), nil
+}
+
+func canonicalSignature(signature string) ([]byte, error) {
+	signature = strings.TrimPrefix(strings.TrimPrefix(signature, "0x"), "0X")
+
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return nil, ErrInvalidSignatureHex
+	}
+
+	canonical := make([]byte, 65)
+	var parity byte
+	switch len(sig) {
+	case 65:
+		copy(canonical, sig)
+		switch v := sig[64]; v {
+		case 0, 1:
+			parity = v
+		case 27, 28:
+			parity = v - 27
+		default:
+			return nil, ErrInvalidRecoveryID
+		}
+	case 64:
+		// EIP-2098: the top bit of s holds the y parity
+		copy(canonical, sig)
+		parity = canonical[32] >> 7
+		canonical[32] &= 0x7f
+	default:
+		return nil, ErrInvalidSignatureLength
+	}
+
+	if err := validateSignatureValues(canonical[:32], canonical[32:64]); err != nil {
+		return nil, err
+	}
+
+	// s and n - s are both valid, for opposite y parities
+	s := new(big.Int).SetBytes(canonical[32:64])
+	if s.Cmp(secp256k1HalfN) > 0 {
+		copy(canonical[32:64], common.LeftPadBytes(s.Sub(secp256k1N, s).Bytes(), 32))
+		parity ^= 1
+	}
+
+	canonical[64] = parity + 27
+	return canonical, nil
+}
@@ -0,0 +1,248 @@
+package dappauth
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestValidateSignatureFormat(t *testing.T) {
+
+	key, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	sig := signEOAPersonalMessage("foo", key, t)
+	sigBytes, err := hex.DecodeString(sig)
+	checkError(err, t)
+
+	// EIP-2098 compact form: r || (yParity << 255 | s)
+	compact := make([]byte, 64)
+	copy(compact, sigBytes[:64])
+	compact[32] |= (sigBytes[64] - 27) << 7
+
+	badV := append([]byte{}, sigBytes...)
+	badV[64] = 29
+
+	// s' = n - s is the malleable (high-S) twin of s
+	highS := append([]byte{}, sigBytes...)
+	s := new(big.Int).SetBytes(sigBytes[32:64])
+	copy(highS[32:64], common.LeftPadBytes(new(big.Int).Sub(secp256k1N, s).Bytes(), 32))
+
+	formatTests := []struct {
+		title     string
+		signature string
+		expected  error
+	}{
+		{"A 65 bytes signature should be valid", sig, nil},
+		{"A 0x prefixed 65 bytes signature should be valid", "0x" + sig, nil},
+		{"An EIP-2098 compact signature should be valid", hex.EncodeToString(compact), nil},
+		{"A non hex signature should be invalid", "0xzz" + sig[2:], ErrInvalidSignatureHex},
+		{"An odd length hex signature should be invalid", sig[1:], ErrInvalidSignatureHex},
+		{"A truncated signature should be invalid", sig[:128-2], ErrInvalidSignatureLength},
+		{"A concatenated signature should be invalid", sig + sig, ErrInvalidSignatureLength},
+		{"A signature with V out of range should be invalid", hex.EncodeToString(badV), ErrInvalidRecoveryID},
+		{"A high-S signature should be invalid", hex.EncodeToString(highS), ErrHighS},
+	}
+
+	for _, test := range formatTests {
+		t.Run(test.title, func(t *testing.T) {
+			err := ValidateSignatureFormat(test.signature)
+			if err != test.expected {
+				t.Errorf("expected %v to be %v", err, test.expected)
+			}
+		})
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+
+	key, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	sig := signEOAPersonalMessage("foo", key, t)
+	canonical := "0x" + sig
+
+	for _, test := range equivalentSignatureForms(sig, t) {
+		t.Run(test.form+" should canonicalize", func(t *testing.T) {
+			actual, err := Canonicalize(test.signature)
+			checkError(err, t)
+			expectBool(actual == canonical, true, t)
+		})
+	}
+
+	t.Run("Invalid signatures should NOT canonicalize", func(t *testing.T) {
+		_, err := Canonicalize(sig[:126])
+		expectBool(err == ErrInvalidSignatureLength, true, t)
+	})
+}
+
+func TestSignatureFingerprint(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	sig := signEOAPersonalMessage("foo", keyA, t)
+	fingerprint, err := SignatureFingerprint(sig)
+	checkError(err, t)
+
+	t.Run("Fingerprints should be the keccak256 of the canonical signature", func(t *testing.T) {
+		sigBytes, err := hex.DecodeString(sig)
+		checkError(err, t)
+		expectBool(fingerprint == ethCrypto.Keccak256Hash(sigBytes).Hex(), true, t)
+	})
+
+	for _, test := range equivalentSignatureForms(sig, t) {
+		t.Run(test.form+" should share the fingerprint", func(t *testing.T) {
+			actual, err := SignatureFingerprint(test.signature)
+			checkError(err, t)
+			expectBool(actual == fingerprint, true, t)
+		})
+	}
+
+	t.Run("Other signatures should NOT share the fingerprint", func(t *testing.T) {
+		for _, other := range []string{signEOAPersonalMessage("bar", keyA, t), signEOAPersonalMessage("foo", keyB, t)} {
+			actual, err := SignatureFingerprint(other)
+			checkError(err, t)
+			expectBool(actual == fingerprint, false, t)
+		}
+	})
+
+	t.Run("Invalid signatures should NOT have a fingerprint", func(t *testing.T) {
+		_, err := SignatureFingerprint(sig[:126])
+		expectBool(err == ErrInvalidSignatureLength, true, t)
+	})
+}
+
+// equivalentSignatureForms returns the forms equivalent to the (hex encoded, 65 bytes) signature.
+func equivalentSignatureForms(sig string, t *testing.T) []struct{ form, signature string } {
+	sigBytes, err := hex.DecodeString(sig)
+	checkError(err, t)
+
+	// V as 0/1
+	rawV := append([]byte{}, sigBytes...)
+	rawV[64] -= 27
+
+	// EIP-2098 compact form
+	compact := make([]byte, 64)
+	copy(compact, sigBytes[:64])
+	compact[32] |= (sigBytes[64] - 27) << 7
+
+	return []struct{ form, signature string }{
+		{"The canonical form", "0x" + sig},
+		{"Non 0x prefixed signatures", sig},
+		{"Upper case signatures", "0x" + strings.ToUpper(sig)},
+		{"Signatures with V as 0/1", hex.EncodeToString(rawV)},
+		{"High-S signatures", highSTwin(sig, t)},
+		{"EIP-2098 compact signatures", hex.EncodeToString(compact)},
+	}
+}
+
+// highSTwin returns the malleable (high-S) twin of the (hex encoded, 65 bytes, low-S) signature, with the opposite y parity.
+func highSTwin(sig string, t *testing.T) string {
+	sigBytes, err := hex.DecodeString(sig)
+	checkError(err, t)
+
+	highS := append([]byte{}, sigBytes...)
+	s := new(big.Int).SetBytes(sigBytes[32:64])
+	copy(highS[32:64], common.LeftPadBytes(new(big.Int).Sub(secp256k1N, s).Bytes(), 32))
+	highS[64] = 27 + (28 - sigBytes[64])
+	return hex.EncodeToString(highS)
+}
+
+func TestLowSEnforcement(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrB := ethCrypto.PubkeyToAddress(keyB.PublicKey)
+
+	// addrA is a smart-contract wallet, authorizing keyB
+	mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey}
+
+	eoaSig := signEOAPersonalMessage("foo", keyB, t)
+	contractSig := signERC1654PersonalMessage("foo", keyB, addrA, t)
+
+	enforcementTests := []struct {
+		title       string
+		paths       []Path
+		signature   string
+		addr        common.Address
+		expected    bool
+		expectedErr error
+	}{
+		{"High-S EOA signatures should be accepted without enforcement", nil, highSTwin(eoaSig, t), addrB, true, nil},
+		{"High-S EOA signatures should be rejected when enforced for EOAs", []Path{PathEOA}, highSTwin(eoaSig, t), addrB, false, ErrHighS},
+		{"High-S EOA signatures should be accepted when enforced for contracts only", []Path{PathContract}, highSTwin(eoaSig, t), addrB, true, nil},
+		{"High-S contract signatures should be accepted without enforcement", nil, highSTwin(contractSig, t), addrA, true, nil},
+		{"High-S contract signatures should be rejected when enforced for contracts", []Path{PathContract}, highSTwin(contractSig, t), addrA, false, ErrHighS},
+		{"High-S contract signatures should be accepted when enforced for EOAs only", []Path{PathEOA}, highSTwin(contractSig, t), addrA, true, nil},
+		{"Low-S EOA signatures should be accepted when enforced for all paths", []Path{PathEOA, PathContract}, eoaSig, addrB, true, nil},
+		{"Low-S contract signatures should be accepted when enforced for all paths", []Path{PathEOA, PathContract}, contractSig, addrA, true, nil},
+	}
+
+	for _, test := range enforcementTests {
+		t.Run(test.title, func(t *testing.T) {
+			authenticator := NewAuthenticator(nil, mock, WithLowSEnforcement(test.paths...))
+
+			isAuthorizedSigner, err := authenticator.IsAuthorizedSigner("foo", test.signature, test.addr.Hex())
+			if !errors.Is(err, test.expectedErr) {
+				t.Errorf("expected %v to be %v", err, test.expectedErr)
+			}
+			expectBool(isAuthorizedSigner, test.expected, t)
+		})
+	}
+}
+
+func TestSignatureValueRange(t *testing.T) {
+
+	key, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	sig, err := hex.DecodeString(signEOAPersonalMessage("foo", key, t))
+	checkError(err, t)
+
+	withValue := func(offset int, value []byte) string {
+		malformed := append([]byte{}, sig...)
+		copy(malformed[offset:offset+32], common.LeftPadBytes(value, 32))
+		return hex.EncodeToString(malformed)
+	}
+	nPlusOne := new(big.Int).Add(secp256k1N, big.NewInt(1)).Bytes()
+
+	rangeTests := []struct {
+		title     string
+		signature string
+	}{
+		{"Signatures with a zero r should be invalid", withValue(0, nil)},
+		{"Signatures with r = n should be invalid", withValue(0, secp256k1N.Bytes())},
+		{"Signatures with r > n should be invalid", withValue(0, nPlusOne)},
+		{"Signatures with a zero s should be invalid", withValue(32, nil)},
+		{"Signatures with s = n should be invalid", withValue(32, secp256k1N.Bytes())},
+	}
+
+	authenticator := NewAuthenticator(nil, &mockContract{})
+	for _, test := range rangeTests {
+		t.Run(test.title, func(t *testing.T) {
+			_, err := authenticator.DeriveAddress("foo", test.signature)
+			expectBool(errors.Is(err, ErrInvalidSignature), true, t)
+			expectBool(ValidateSignatureFormat(test.signature) == ErrInvalidSignature, true, t)
+
+			_, err = Canonicalize(test.signature)
+			expectBool(err == ErrInvalidSignature, true, t)
+		})
+	}
+
+	t.Run("Signatures with r and s = n - 1 should be in range", func(t *testing.T) {
+		nMinusOne := new(big.Int).Sub(secp256k1N, big.NewInt(1)).Bytes()
+		expectBool(validateSignatureValues(common.LeftPadBytes(nMinusOne, 32), common.LeftPadBytes(nMinusOne, 32)) == nil, true, t)
+	})
+}
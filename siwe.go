@@ -0,0 +1,202 @@
+package dappauth
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const _SIWEHeaderSuffix = " wants you to sign in with your Ethereum account:"
+
+var (
+	// ErrInvalidSIWEMessage is returned when a message isn't a well-formed EIP-4361 (Sign-In with Ethereum) message.
+	ErrInvalidSIWEMessage = errors.New("dappauth: invalid SIWE message")
+	// ErrSIWEDomainMismatch is returned when the domain of a SIWE message isn't the bound domain (see WithDomainBinding).
+	ErrSIWEDomainMismatch = errors.New("dappauth: SIWE message domain is not the bound domain")
+)
+
+// SIWEMessage is an EIP-4361 (Sign-In with Ethereum) message.
+type SIWEMessage struct {
+	Domain         string // the domain (RFC 3986 authority, optionally with a scheme) requesting the sign-in
+	Address        common.Address
+	Statement      string // optional
+	URI            string
+	Version        string
+	ChainID        *big.Int
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime time.Time // optional (zero = doesn't expire)
+	NotBefore      time.Time // optional (zero = valid once issued)
+	RequestID      string    // optional
+	Resources      []string  // optional, URIs
+}
+
+// ParseSIWEMessage parses the EIP-4361 message, including its optional fields.
+func ParseSIWEMessage(message string) (*SIWEMessage, error) {
+	lines := strings.Split(message, "\n")
+	p := &siweParser{lines: lines}
+
+	var m SIWEMessage
+
+	header := p.next()
+	if !strings.HasSuffix(header, _SIWEHeaderSuffix) || len(header) == len(_SIWEHeaderSuffix) {
+		return nil, fmt.Errorf("%w: invalid header", ErrInvalidSIWEMessage)
+	}
+	m.Domain = strings.TrimSuffix(header, _SIWEHeaderSuffix)
+
+	address := p.next()
+	if !common.IsHexAddress(address) || !strings.HasPrefix(address, "0x") {
+		return nil, fmt.Errorf("%w: invalid address %q", ErrInvalidSIWEMessage, address)
+	}
+	m.Address = common.HexToAddress(address)
+
+	// the statement is optional, within blank lines
+	for p.peek() == "" && p.more() {
+		p.next()
+	}
+	if !strings.HasPrefix(p.peek(), "URI: ") {
+		m.Statement = p.next()
+		for p.peek() == "" && p.more() {
+			p.next()
+		}
+	}
+
+	var (
+		chainID, issuedAt string
+		ok                bool
+	)
+	if m.URI, ok = p.field("URI"); !ok {
+		return nil, fmt.Errorf("%w: missing URI", ErrInvalidSIWEMessage)
+	}
+	if m.Version, ok = p.field("Version"); !ok || m.Version != "1" {
+		return nil, fmt.Errorf("%w: missing or unsupported version", ErrInvalidSIWEMessage)
+	}
+	if chainID, ok = p.field("Chain ID"); !ok {
+		return nil, fmt.Errorf("%w: missing chain ID", ErrInvalidSIWEMessage)
+	}
+	if m.ChainID, ok = new(big.Int).SetString(chainID, 10); !ok {
+		return nil, fmt.Errorf("%w: invalid chain ID %q", ErrInvalidSIWEMessage, chainID)
+	}
+	if m.Nonce, ok = p.field("Nonce"); !ok || len(m.Nonce) < 8 {
+		return nil, fmt.Errorf("%w: missing or too short nonce", ErrInvalidSIWEMessage)
+	}
+	if issuedAt, ok = p.field("Issued At"); !ok {
+		return nil, fmt.Errorf("%w: missing issued at", ErrInvalidSIWEMessage)
+	}
+
+	var err error
+	if m.IssuedAt, err = parseSIWETime("issued at", issuedAt); err != nil {
+		return nil, err
+	}
+	if expirationTime, ok := p.field("Expiration Time"); ok {
+		if m.ExpirationTime, err = parseSIWETime("expiration time", expirationTime); err != nil {
+			return nil, err
+		}
+	}
+	if notBefore, ok := p.field("Not Before"); ok {
+		if m.NotBefore, err = parseSIWETime("not before", notBefore); err != nil {
+			return nil, err
+		}
+	}
+	m.RequestID, _ = p.field("Request ID")
+
+	if p.peek() == "Resources:" {
+		p.next()
+		for strings.HasPrefix(p.peek(), "- ") {
+			resource := strings.TrimPrefix(p.next(), "- ")
+			if u, err := url.Parse(resource); err != nil || !u.IsAbs() {
+				return nil, fmt.Errorf("%w: resource %q is not a URI", ErrInvalidSIWEMessage, resource)
+			}
+			m.Resources = append(m.Resources, resource)
+		}
+	}
+
+	if p.more() {
+		return nil, fmt.Errorf("%w: unexpected line %q", ErrInvalidSIWEMessage, p.peek())
+	}
+	return &m, nil
+}
+
+// IsAuthorizedSIWE checks if the address of the EIP-4361 message is an authorized signer for the signature of the
+// message, either as an external wallet or as a smart-contract wallet, and that the message is valid at the time
+// per its Not Before and Expiration Time (failing with ErrChallengeNotYetValid and ErrChallengeExpired respectively).
+// If a domain is bound (see WithDomainBinding), the domain of the message must be it (ErrSIWEDomainMismatch otherwise).
+// The message is signed as is via personal_sign, so the challenge and message prefix options don't apply.
+func (a *Authenticator) IsAuthorizedSIWE(message, signature string) (bool, error) {
+
+	m, err := ParseSIWEMessage(message)
+	if err != nil {
+		return false, err
+	}
+
+	if a.domainBinding != "" && strings.ToLower(m.Domain) != a.domainBinding {
+		return false, ErrSIWEDomainMismatch
+	}
+
+	now := a.now()
+	if !m.NotBefore.IsZero() && now.Before(m.NotBefore) {
+		return false, ErrChallengeNotYetValid
+	}
+	if !m.ExpirationTime.IsZero() && now.After(m.ExpirationTime) {
+		return false, ErrChallengeExpired
+	}
+
+	siwe := *a
+	siwe.challengeEncoding = ChallengeEncodingRaw
+	siwe.challengePreHashed = false
+	siwe.domainBinding = ""
+	siwe.challengeExpiryExtractor = nil
+	siwe.challengeNotBeforeExtractor = nil
+	siwe.challengeNonceExtractor = nil
+	siwe.addressBinding = false // the message binds its own address
+	siwe.messagePrefix = ""
+	siwe.eip191Version = EIP191VersionPersonalSign
+	siwe.eip191VersionData = nil
+	siwe.resultCache = nil
+
+	return siwe.IsAuthorizedSignerAddr(message, signature, m.Address)
+}
+
+func parseSIWETime(name, value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid %s %q", ErrInvalidSIWEMessage, name, value)
+	}
+	return t, nil
+}
+
+type siweParser struct {
+	lines []string
+	i     int
+}
+
+func (p *siweParser) more() bool {
+	return p.i < len(p.lines)
+}
+
+func (p *siweParser) peek() string {
+	if !p.more() {
+		return ""
+	}
+	return p.lines[p.i]
+}
+
+func (p *siweParser) next() string {
+	line := p.peek()
+	p.i++
+	return line
+}
+
+// field consumes the next line if it is the field of the name, returning its value.
+func (p *siweParser) field(name string) (string, bool) {
+	prefix := name + ": "
+	if !strings.HasPrefix(p.peek(), prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(p.next(), prefix), true
+}
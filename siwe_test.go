@@ -0,0 +1,163 @@
+package dappauth
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestSIWE(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	siweMessage := func(optionalFields ...string) string {
+		lines := []string{
+			"example.com wants you to sign in with your Ethereum account:",
+			addrA.Hex(),
+			"",
+			"Sign in to Example.",
+			"",
+			"URI: https://example.com/login",
+			"Version: 1",
+			"Chain ID: 1",
+			"Nonce: 32891756",
+			"Issued At: 2020-01-01T11:55:00Z",
+		}
+		return strings.Join(append(lines, optionalFields...), "\n")
+	}
+
+	authenticator := NewAuthenticator(nil, &mockContract{}, WithClock(func() time.Time { return now }))
+
+	t.Run("Messages with optional fields should parse", func(t *testing.T) {
+		m, err := ParseSIWEMessage(siweMessage(
+			"Expiration Time: 2020-01-01T12:05:00Z",
+			"Not Before: 2020-01-01T11:55:00Z",
+			"Request ID: request-1",
+			"Resources:",
+			"- ipfs://bafybeiemxf5abjwjbikoz4mc3a3dla6ual3jsgpdr4cjr3oz3evfyavhwq/",
+			"- https://example.com/my-web2-claim.json",
+		))
+		checkError(err, t)
+		expectBool(m.Domain == "example.com" && m.Address == addrA && m.Statement == "Sign in to Example.", true, t)
+		expectBool(m.URI == "https://example.com/login" && m.ChainID.Int64() == 1 && m.Nonce == "32891756", true, t)
+		expectBool(m.ExpirationTime.Equal(time.Date(2020, 1, 1, 12, 5, 0, 0, time.UTC)), true, t)
+		expectBool(m.NotBefore.Equal(time.Date(2020, 1, 1, 11, 55, 0, 0, time.UTC)), true, t)
+		expectBool(m.RequestID == "request-1", true, t)
+		expectBool(len(m.Resources) == 2 && m.Resources[1] == "https://example.com/my-web2-claim.json", true, t)
+	})
+
+	t.Run("Messages without a statement should parse", func(t *testing.T) {
+		m, err := ParseSIWEMessage(strings.Replace(siweMessage(), "Sign in to Example.\n\n", "", 1))
+		checkError(err, t)
+		expectBool(m.Statement == "" && m.URI == "https://example.com/login", true, t)
+	})
+
+	malformedTests := []struct {
+		title   string
+		message string
+	}{
+		{"Resources which aren't URIs should error", siweMessage("Resources:", "- not a uri")},
+		{"Invalid not-before times should error", siweMessage("Not Before: tomorrow")},
+		{"Fields out of order should error", siweMessage("Request ID: request-1", "Not Before: 2020-01-01T11:55:00Z")},
+		{"Messages without a nonce should error", strings.Replace(siweMessage(), "Nonce: 32891756\n", "", 1)},
+	}
+
+	for _, test := range malformedTests {
+		t.Run(test.title, func(t *testing.T) {
+			_, err := ParseSIWEMessage(test.message)
+			expectBool(errors.Is(err, ErrInvalidSIWEMessage), true, t)
+		})
+	}
+
+	verifyTests := []struct {
+		title       string
+		message     string
+		key         *ecdsa.PrivateKey
+		expected    bool
+		expectedErr error
+	}{
+		{
+			"Messages with a past not-before and resources should be authorized",
+			siweMessage("Not Before: 2020-01-01T11:59:00Z", "Request ID: request-1", "Resources:", "- https://example.com/data"),
+			keyA,
+			true,
+			nil,
+		},
+		{
+			"Messages with a future not-before should error with ErrChallengeNotYetValid",
+			siweMessage("Not Before: 2020-01-01T12:01:00Z", "Resources:", "- https://example.com/data"),
+			keyA,
+			false,
+			ErrChallengeNotYetValid,
+		},
+		{
+			"Messages past their expiration time should error with ErrChallengeExpired",
+			siweMessage("Expiration Time: 2020-01-01T11:59:00Z"),
+			keyA,
+			false,
+			ErrChallengeExpired,
+		},
+		{
+			"Messages signed by another key should not be authorized",
+			siweMessage(),
+			keyB,
+			false,
+			nil,
+		},
+	}
+
+	for _, test := range verifyTests {
+		t.Run(test.title, func(t *testing.T) {
+			isAuthorizedSigner, err := authenticator.IsAuthorizedSIWE(test.message, signEOAPersonalMessage(test.message, test.key, t))
+			if test.expected || test.expectedErr != nil {
+				expectBool(err == test.expectedErr, true, t)
+			}
+			expectBool(isAuthorizedSigner, test.expected, t)
+		})
+	}
+
+	optionTests := []struct {
+		title       string
+		options     []Option
+		expected    bool
+		expectedErr error
+	}{
+		{"Messages of the bound domain should be authorized", []Option{WithDomainBinding("Example.com")}, true, nil},
+		{"Messages of another domain should error with ErrSIWEDomainMismatch", []Option{WithDomainBinding("example.org")}, false, ErrSIWEDomainMismatch},
+		{"Messages should be authorized regardless of the address binding", []Option{WithAddressBinding(true)}, true, nil},
+		{"Messages should be authorized regardless of the message prefix", []Option{WithMessagePrefix("\x19{chainName} Signed Message:\n", nil, "Ronin")}, true, nil},
+		{"Messages should be authorized regardless of the EIP-191 version", []Option{WithEIP191Version(EIP191VersionDataWithValidator, addrA.Bytes())}, true, nil},
+	}
+
+	for _, test := range optionTests {
+		t.Run(test.title, func(t *testing.T) {
+			authenticator := NewAuthenticator(nil, &mockContract{}, append(test.options, WithClock(func() time.Time { return now }))...)
+
+			isAuthorizedSigner, err := authenticator.IsAuthorizedSIWE(siweMessage(), signEOAPersonalMessage(siweMessage(), keyA, t))
+			expectBool(err == test.expectedErr, true, t)
+			expectBool(isAuthorizedSigner, test.expected, t)
+		})
+	}
+
+	t.Run("Smart-contract wallets should be authorized regardless of the wallet nonce check", func(t *testing.T) {
+		// the wallet at A is owned by B, and the challenge nonce extractor would fail on SIWE messages
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, code: []byte{0x60}}
+		authenticator := NewAuthenticator(nil, mock, WithClock(func() time.Time { return now }), WithWalletNonce([4]byte{1}, func([]byte) (*big.Int, error) { return nil, ErrNoChallengeNonce }))
+
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSIWE(siweMessage(), signERC1654PersonalMessage(siweMessage(), keyB, addrA, t))
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+}
@@ -0,0 +1,55 @@
+package dappauth
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidToken is returned when a token isn't framed as documented by EncodeToken.
+var ErrInvalidToken = errors.New("dappauth: invalid token")
+
+// EncodeToken encodes the challenge and its signature into a single opaque token, for clients to pass both as one
+// string. The token is the base64url (unpadded) encoding of the frame
+//
+//	challengeLen (4 bytes, big endian) ‖ challenge ‖ signature
+//
+// i.e. the challenge argument as is (per the configured ChallengeEncoding), followed by the raw signature bytes.
+func EncodeToken(challenge string, sig []byte) string {
+	frame := make([]byte, 4, 4+len(challenge)+len(sig))
+	binary.BigEndian.PutUint32(frame, uint32(len(challenge)))
+	frame = append(append(frame, challenge...), sig...)
+	return base64.RawURLEncoding.EncodeToString(frame)
+}
+
+// DecodeToken splits the token into its challenge and signature, failing with ErrInvalidToken if it isn't framed as
+// documented by EncodeToken. Padded tokens are accepted.
+func DecodeToken(token string) (challenge string, sig []byte, err error) {
+	frame, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(token, "="))
+	if err != nil || len(frame) < 4 {
+		return "", nil, ErrInvalidToken
+	}
+
+	challengeLen := binary.BigEndian.Uint32(frame)
+	if uint64(challengeLen) > uint64(len(frame)-4) {
+		return "", nil, ErrInvalidToken
+	}
+
+	sig = frame[4+challengeLen:]
+	if len(sig) == 0 {
+		return "", nil, ErrInvalidToken
+	}
+	return string(frame[4 : 4+challengeLen]), sig, nil
+}
+
+// VerifyToken is like IsAuthorizedSigner but takes the challenge and signature as a single token (see EncodeToken).
+func (a *Authenticator) VerifyToken(token, addrHex string) (bool, error) {
+	challenge, sig, err := DecodeToken(token)
+	if err != nil {
+		return false, err
+	}
+
+	return a.IsAuthorizedSigner(challenge, hex.EncodeToString(sig), addrHex)
+}
@@ -0,0 +1,82 @@
+package dappauth
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestVerifyToken(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrB := ethCrypto.PubkeyToAddress(keyB.PublicKey)
+
+	eoaSig, err := hex.DecodeString(signEOAPersonalMessage("foo", keyA, t))
+	checkError(err, t)
+	contractSig, err := hex.DecodeString(signERC1654PersonalMessage("foo", keyB, addrA, t))
+	checkError(err, t)
+
+	authenticator := NewAuthenticator(nil, &mockContract{})
+
+	t.Run("Tokens should round trip", func(t *testing.T) {
+		challenge, sig, err := DecodeToken(EncodeToken("foo", eoaSig))
+		checkError(err, t)
+		expectBool(challenge == "foo", true, t)
+		expectBool(hex.EncodeToString(sig) == hex.EncodeToString(eoaSig), true, t)
+	})
+
+	t.Run("External wallets should be authorized signers of their token", func(t *testing.T) {
+		isAuthorizedSigner, err := authenticator.VerifyToken(EncodeToken("foo", eoaSig), addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	t.Run("External wallets should NOT be authorized signers of another wallet's token", func(t *testing.T) {
+		isAuthorizedSigner, err := authenticator.VerifyToken(EncodeToken("foo", eoaSig), addrB.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, false, t)
+	})
+
+	t.Run("Smart-contract wallets should be authorized signers of their token", func(t *testing.T) {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey}
+		authenticator := NewAuthenticator(nil, mock)
+
+		isAuthorizedSigner, err := authenticator.VerifyToken(EncodeToken("foo", contractSig), addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	t.Run("Padded tokens should be accepted", func(t *testing.T) {
+		frame, err := base64.RawURLEncoding.DecodeString(EncodeToken("foo", eoaSig))
+		checkError(err, t)
+
+		isAuthorizedSigner, err := authenticator.VerifyToken(base64.URLEncoding.EncodeToString(frame), addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	malformedTests := []struct {
+		title string
+		token string
+	}{
+		{"Empty tokens should error", ""},
+		{"Tokens which are not base64url should error", "not/base64+"},
+		{"Tokens shorter than the length should error", base64.RawURLEncoding.EncodeToString([]byte{0, 0})},
+		{"Tokens whose challenge overruns the frame should error", base64.RawURLEncoding.EncodeToString([]byte{0, 0, 0, 9, 'f', 'o', 'o'})},
+		{"Tokens without a signature should error", EncodeToken("foo", nil)},
+	}
+
+	for _, test := range malformedTests {
+		t.Run(test.title, func(t *testing.T) {
+			_, err := authenticator.VerifyToken(test.token, addrA.Hex())
+			expectBool(err == ErrInvalidToken, true, t)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package dappauth
+
+// Vector is a reference test vector of an EOA (external wallet) personal_sign signature over a challenge,
+// for conformance testing of other implementations.
+type Vector struct {
+	Challenge         string // the challenge signed via personal_sign
+	PrivateKey        string // hex encoded private key of the signer
+	ExpectedSignature string // 0x prefixed hex encoded 65 bytes signature, V being 27/28 (deterministic per RFC 6979)
+	ExpectedAddress   string // EIP-55 checksummed address of the signer
+}
+
+// TestVectors returns the reference test vectors. Each vector's signature is authorized for its address.
+func TestVectors() []Vector {
+	return []Vector{
+		{
+			Challenge:         "foo",
+			PrivateKey:        "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318",
+			ExpectedSignature: "0x83746042d7b395bc5104bbd9c25863599b635b4347d0fddcdf377d23d20c99f8670bec917e5b53426ea12e4574ed18177417c375698275f501a82750e2cf6f581c",
+			ExpectedAddress:   "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23",
+		},
+		{
+			Challenge:         "",
+			PrivateKey:        "0000000000000000000000000000000000000000000000000000000000000001",
+			ExpectedSignature: "0x0ac02a3eb3039b7a3ebb6a35f1e0dd31a4ed51781205a2c193354752a25edad50593868baf38c519b78bdc61a23c3f55e058c29f8b83d79ae48cc47d931afaed1b",
+			ExpectedAddress:   "0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf",
+		},
+		{
+			Challenge:         "Sign in to dappauth: 0123456789abcdef",
+			PrivateKey:        "fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364140",
+			ExpectedSignature: "0x6122ca27ea2e24998dcce057dfeca8bf3dfb4afa191407c77820e2f566b7227e71e09c123c45c1147489168c29916d393a4b31dd34710524fc393cf18766fd061b",
+			ExpectedAddress:   "0x80C0dbf239224071c59dD8970ab9d542E3414aB2",
+		},
+		{
+			Challenge:         "ünïcödé ✓",
+			PrivateKey:        "8da4ef21b864d2cc526dbdb2a120bd2874c36c9d0a1fb7f8c63d7f7a8b41de8f",
+			ExpectedSignature: "0xf1bef39a204ef0b71898130decd55c2d9d51799df4acf00cf981866ce5d677e74325d5003cc2a5872284a104e4791e422d9533026b708366468c3c72b1f76f451c",
+			ExpectedAddress:   "0x63FaC9201494f0bd17B9892B9fae4d52fe3BD377",
+		},
+	}
+}
@@ -0,0 +1,28 @@
+package dappauth
+
+import (
+	"testing"
+
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestTestVectors(t *testing.T) {
+
+	authenticator := NewAuthenticator(nil, &mockContract{})
+
+	for _, vector := range TestVectors() {
+		t.Run(vector.ExpectedAddress, func(t *testing.T) {
+			key, err := ethCrypto.HexToECDSA(vector.PrivateKey)
+			checkError(err, t)
+
+			expectBool(ethCrypto.PubkeyToAddress(key.PublicKey).Hex() == vector.ExpectedAddress, true, t)
+			expectBool("0x"+signEOAPersonalMessage(vector.Challenge, key, t) == vector.ExpectedSignature, true, t)
+
+			isAuthorizedSigner, err := authenticator.IsAuthorizedSigner(vector.Challenge, vector.ExpectedSignature, vector.ExpectedAddress)
+			checkError(err, t)
+			expectBool(isAuthorizedSigner, true, t)
+
+			checkError(ValidateSignatureFormat(vector.ExpectedSignature), t)
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package dappauth
+
+import (
+	"context"
+)
+
+// Verifier checks if addresses are authorized signers of challenges. *Authenticator implements it, so consumers can
+// depend on a Verifier and substitute a fake in their tests.
+type Verifier interface {
+	IsAuthorizedSigner(challenge, signature, addrHex string) (bool, error)
+	IsAuthorizedSignerContext(ctx context.Context, challenge, signature, addrHex string) (bool, error)
+}
+
+var _ Verifier = (*Authenticator)(nil)
+
+// IsAuthorizedSignerContext is like IsAuthorizedSigner but makes the backend calls within ctx rather than the context
+// of the authenticator.
+func (a *Authenticator) IsAuthorizedSignerContext(ctx context.Context, challenge, signature, addrHex string) (bool, error) {
+	withCtx := *a
+	withCtx.ctx = ctx
+	return withCtx.IsAuthorizedSigner(challenge, signature, addrHex)
+}
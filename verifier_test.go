@@ -0,0 +1,66 @@
+package dappauth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// fakeVerifier is a consumer-side fake, authorizing a fixed address whatever the signature.
+type fakeVerifier struct {
+	authorized string
+}
+
+func (f fakeVerifier) IsAuthorizedSigner(challenge, signature, addrHex string) (bool, error) {
+	return addrHex == f.authorized, nil
+}
+
+func (f fakeVerifier) IsAuthorizedSignerContext(ctx context.Context, challenge, signature, addrHex string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return f.IsAuthorizedSigner(challenge, signature, addrHex)
+}
+
+// login is consumer code depending on a Verifier.
+func login(v Verifier, challenge, signature, addrHex string) bool {
+	isAuthorizedSigner, err := v.IsAuthorizedSigner(challenge, signature, addrHex)
+	return err == nil && isAuthorizedSigner
+}
+
+func TestVerifier(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+
+	t.Run("Consumers should accept the Authenticator as a Verifier", func(t *testing.T) {
+		expectBool(login(NewAuthenticator(nil, &mockContract{}), "foo", signEOAPersonalMessage("foo", keyA, t), addrA.Hex()), true, t)
+	})
+
+	t.Run("Consumers should accept a fake Verifier", func(t *testing.T) {
+		expectBool(login(fakeVerifier{authorized: addrA.Hex()}, "foo", "0x", addrA.Hex()), true, t)
+		expectBool(login(fakeVerifier{}, "foo", "0x", addrA.Hex()), false, t)
+	})
+
+	t.Run("The context variant should make the backend calls within the context", func(t *testing.T) {
+		keyB, err := ethCrypto.GenerateKey()
+		checkError(err, t)
+
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey}
+		sig := signERC1654PersonalMessage("foo", keyB, addrA, t)
+
+		var verifier Verifier = NewAuthenticator(nil, &slowBackend{mockContract: mock, delay: 200 * time.Millisecond})
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err = verifier.IsAuthorizedSignerContext(ctx, "foo", sig, addrA.Hex())
+		expectBool(err != nil, true, t)
+
+		isAuthorizedSigner, err := verifier.IsAuthorizedSignerContext(context.Background(), "foo", sig, addrA.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+}
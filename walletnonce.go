@@ -0,0 +1,64 @@
+package dappauth
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	// ErrStaleNonce is returned when the nonce embedded in the challenge isn't the current nonce of the smart-contract
+	// wallet, i.e. the challenge was already used (or not yet issued) per the wallet.
+	ErrStaleNonce = errors.New("dappauth: challenge nonce is not the current nonce of the wallet")
+	// ErrInvalidWalletNonceResponse is returned when the nonce view of the wallet doesn't return a uint256.
+	ErrInvalidWalletNonceResponse = errors.New("dappauth: wallet nonce view did not return a uint256")
+)
+
+// ChallengeNonceExtractor extracts the wallet nonce from the challenge message, e.g. a nonce field of a JSON challenge.
+type ChallengeNonceExtractor func(message []byte) (*big.Int, error)
+
+// WithWalletNonce enables replay protection by smart-contract wallets which manage their own nonce: the nonce the
+// extractor extracts from the challenge message must be the current nonce of the wallet, per its nonce view, otherwise
+// verifications fail with ErrStaleNonce. The view must take no argument and return a uint256, e.g. nonce() returns
+// (uint256). External wallets aren't checked.
+func WithWalletNonce(selector [4]byte, extractor ChallengeNonceExtractor) Option {
+	return func(a *Authenticator) {
+		a.walletNonceSelector = selector
+		a.challengeNonceExtractor = extractor
+	}
+}
+
+// checkWalletNonce enforces that the challenge embeds the current nonce of the wallet, if configured.
+func (a *Authenticator) checkWalletNonce(challenge string, addr common.Address) error {
+	if a.challengeNonceExtractor == nil {
+		return nil
+	}
+
+	msg, err := a.extractableMessage(challenge)
+	if err != nil {
+		return err
+	}
+	nonce, err := a.challengeNonceExtractor(msg)
+	if err != nil {
+		return fmt.Errorf("dappauth: extracting challenge nonce: %w", err)
+	}
+
+	if a.cc == nil {
+		return ErrNoBackend
+	}
+
+	output, _, err := a.callContract(addr, a.walletNonceSelector[:])
+	if err != nil {
+		return wrapError("nonce view of", addr, contractCallFailed(err))
+	}
+	if len(output) != 32 {
+		return wrapError("nonce view of", addr, ErrInvalidWalletNonceResponse)
+	}
+
+	if nonce == nil || new(big.Int).SetBytes(output).Cmp(nonce) != 0 {
+		return ErrStaleNonce
+	}
+	return nil
+}
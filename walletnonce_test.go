@@ -0,0 +1,72 @@
+package dappauth
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestWalletNonce(t *testing.T) {
+
+	keyA, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+	keyB, err := ethCrypto.GenerateKey()
+	checkError(err, t)
+
+	addrA := ethCrypto.PubkeyToAddress(keyA.PublicKey)
+	addrB := ethCrypto.PubkeyToAddress(keyB.PublicKey)
+
+	// nonce() returns (uint256)
+	nonceSelector := [4]byte{0xaf, 0xfe, 0xd0, 0xe0}
+
+	// extracts the nonce field of a JSON challenge
+	extractNonce := func(message []byte) (*big.Int, error) {
+		var challenge struct {
+			Nonce *big.Int `json:"nonce"`
+		}
+		err := json.Unmarshal(message, &challenge)
+		return challenge.Nonce, err
+	}
+
+	// the smart-contract wallet at A, whose key is B, is at nonce 7
+	mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey, walletNonceSelector: nonceSelector, walletNonce: big.NewInt(7)}
+	authenticator := NewAuthenticator(nil, mock, WithWalletNonce(nonceSelector, extractNonce))
+
+	nonceTests := []struct {
+		title       string
+		challenge   string
+		expectedErr error
+	}{
+		{"Challenges embedding the current nonce of the wallet should be authorized", `{"nonce":7}`, nil},
+		{"Challenges embedding a past nonce of the wallet should error with ErrStaleNonce", `{"nonce":6}`, ErrStaleNonce},
+		{"Challenges embedding no nonce should error with ErrStaleNonce", `{}`, ErrStaleNonce},
+	}
+
+	for _, test := range nonceTests {
+		t.Run(test.title, func(t *testing.T) {
+			sig := signERC1654PersonalMessage(test.challenge, keyB, addrA, t)
+			isAuthorizedSigner, err := authenticator.IsAuthorizedSigner(test.challenge, sig, addrA.Hex())
+			expectBool(errors.Is(err, test.expectedErr), true, t)
+			expectBool(isAuthorizedSigner, test.expectedErr == nil, t)
+		})
+	}
+
+	t.Run("External wallets should NOT be checked", func(t *testing.T) {
+		challenge := `{"nonce":1}`
+		isAuthorizedSigner, err := authenticator.IsAuthorizedSigner(challenge, signEOAPersonalMessage(challenge, keyB, t), addrB.Hex())
+		checkError(err, t)
+		expectBool(isAuthorizedSigner, true, t)
+	})
+
+	t.Run("Wallets without a nonce view should error", func(t *testing.T) {
+		mock := &mockContract{address: addrA, authorizedKey: &keyB.PublicKey}
+		challenge := `{"nonce":7}`
+		sig := signERC1654PersonalMessage(challenge, keyB, addrA, t)
+
+		_, err := NewAuthenticator(nil, mock, WithWalletNonce(nonceSelector, extractNonce)).IsAuthorizedSigner(challenge, sig, addrA.Hex())
+		expectBool(errors.Is(err, ErrContractCallFailed), true, t)
+	})
+}